@@ -29,7 +29,6 @@ import (
 	"github.com/terramate-io/terramate/hcl/ast"
 	"github.com/terramate-io/terramate/hcl/eval"
 	"github.com/terramate-io/terramate/hcl/fmt"
-	"github.com/terramate-io/terramate/hcl/info"
 	"github.com/terramate-io/terramate/modvendor/download"
 	"github.com/terramate-io/terramate/versions"
 
@@ -91,6 +90,9 @@ type cliSpec struct {
 	Changed        bool     `short:"c" optional:"true" help:"Filter by changed infrastructure"`
 	Tags           []string `optional:"true" sep:"none" help:"Filter stacks by tags. Use \":\" for logical AND and \",\" for logical OR. Example: --tags app:prod filters stacks containing tag \"app\" AND \"prod\". If multiple --tags are provided, an OR expression is created. Example: \"--tags A --tags B\" is the same as \"--tags A,B\""`
 	NoTags         []string `optional:"true" sep:"," help:"Filter stacks that do not have the given tags"`
+	StackPath      []string `optional:"true" sep:"none" help:"Filter stacks by path glob. Relative globs are resolved from the working dir. Use \"*\" to match a path element and \"**\" to match any number of directories. Example: --stack-path \"/stacks/**/prod\". If multiple --stack-path are provided, stacks matching any of them are selected"`
+	StackID        []string `optional:"true" sep:"," help:"Filter stacks by ID"`
+	Where          string   `optional:"true" help:"Filter stacks by a boolean expression evaluated on each stack using the terramate and global namespaces. Example: --where 'global.env == \"prod\"'"`
 	LogLevel       string   `optional:"true" default:"warn" enum:"disabled,trace,debug,info,warn,error,fatal" help:"Log level to use: 'disabled', 'trace', 'debug', 'info', 'warn', 'error', or 'fatal'"`
 	LogFmt         string   `optional:"true" default:"console" enum:"console,text,json" help:"Log format to use: 'console', 'text', or 'json'"`
 	LogDestination string   `optional:"true" default:"stderr" enum:"stderr,stdout" help:"Destination of log messages"`
@@ -103,6 +105,8 @@ type cliSpec struct {
 	DisableCheckpoint          bool `optional:"true" default:"false" help:"Disable checkpoint checks for updates"`
 	DisableCheckpointSignature bool `optional:"true" default:"false" help:"Disable checkpoint signature"`
 
+	DisableGenerateCache bool `optional:"true" default:"false" help:"Disable the cache of generated code"`
+
 	Create struct {
 		Path           string   `arg:"" name:"path" predictor:"file" help:"Path of the new stack relative to the working dir"`
 		ID             string   `help:"ID of the stack, defaults to UUID"`
@@ -120,21 +124,34 @@ type cliSpec struct {
 	} `cmd:"" help:"Format all files inside dir recursively"`
 
 	List struct {
-		Why bool `help:"Shows the reason why the stack has changed"`
+		Why    bool     `help:"Shows the reason why the stack has changed"`
+		Format string   `default:"text" enum:"text,json,csv,table" help:"Output format: 'text', 'json', 'csv' or 'table'"`
+		Fields []string `sep:"," help:"Comma separated list of fields to output when --format is not 'text'. Supported fields: id, name, description, path, tags, after, before, wants, wanted_by, watch, state, changed, reason. Defaults to all fields. The changed and reason fields are null (empty on csv and table) unless --changed is given"`
 	} `cmd:"" help:"List stacks"`
 
 	Run struct {
-		CloudSyncDeployment   bool     `default:"false" help:"Enable synchronization of stack execution with the Terramate Cloud"`
-		DisableCheckGenCode   bool     `default:"false" help:"Disable outdated generated code check"`
-		DisableCheckGitRemote bool     `default:"false" help:"Disable checking if local default branch is updated with remote"`
-		ContinueOnError       bool     `default:"false" help:"Continue executing in other stacks in case of error"`
-		NoRecursive           bool     `default:"false" help:"Do not recurse into child stacks"`
-		DryRun                bool     `default:"false" help:"Plan the execution but do not execute it"`
-		Reverse               bool     `default:"false" help:"Reverse the order of execution"`
-		Command               []string `arg:"" name:"cmd" predictor:"file" passthrough:"" help:"Command to execute"`
+		CloudSyncDeployment   bool              `default:"false" help:"Enable synchronization of stack execution with the Terramate Cloud"`
+		DisableCheckGenCode   bool              `default:"false" help:"Disable outdated generated code check"`
+		DisableCheckGitRemote bool              `default:"false" help:"Disable checking if local default branch is updated with remote"`
+		ContinueOnError       bool              `default:"false" help:"Continue executing in other stacks in case of error"`
+		NoRecursive           bool              `default:"false" help:"Do not recurse into child stacks"`
+		DryRun                bool              `default:"false" help:"Plan the execution but do not execute it"`
+		Reverse               bool              `default:"false" help:"Reverse the order of execution"`
+		IncludeDisabled       bool              `default:"false" help:"Include disabled and manual stacks that were not explicitly selected"`
+		Global                map[string]string `short:"g" help:"set/override globals. eg.: --global name=<expr>"`
+		GlobalsFile           string            `predictor:"file" help:"HCL or JSON file with globals to set/override"`
+		Command               []string          `arg:"" name:"cmd" predictor:"file" passthrough:"" help:"Command to execute"`
 	} `cmd:"" help:"Run command in the stacks"`
 
-	Generate struct{} `cmd:"" help:"Generate terraform code for stacks"`
+	Generate struct {
+		DryRun      bool              `default:"false" help:"Show the changes to the generated files as unified diffs but do not apply them"`
+		Check       bool              `default:"false" help:"Do not change any file, exit with 0 if all generated code is up to date, 1 otherwise"`
+		Force       bool              `default:"false" help:"Overwrite and delete generated files even if they were manually edited"`
+		Label       []string          `sep:"none" help:"Only generate the files whose label matches the given path glob. Example: --label \"*.tf\". If multiple --label are provided, files matching any of them are generated"`
+		Global      map[string]string `short:"g" help:"set/override globals. eg.: --global name=<expr>"`
+		GlobalsFile string            `predictor:"file" help:"HCL or JSON file with globals to set/override"`
+		Paths       []string          `arg:"" optional:"true" name:"paths" predictor:"file" help:"Only generate code for the stacks inside the given paths, relative to the working dir"`
+	} `cmd:"" help:"Generate terraform code for stacks"`
 
 	InstallCompletions kongplete.InstallCompletions `cmd:"" help:"Install shell completions"`
 
@@ -149,9 +166,28 @@ type cliSpec struct {
 			Reason string `default:"" name:"reason" help:"Reason for the stack being triggered"`
 		} `cmd:"" help:"Triggers a stack"`
 
+		Stack struct {
+			Deps struct {
+				Stack      string `arg:"" name:"stack" predictor:"file" help:"Path of the stack"`
+				Transitive bool   `help:"Include the dependencies of the dependencies"`
+				AsJSON     bool   `help:"Outputs the result as JSON"`
+			} `cmd:"" help:"List the stacks the given stack runs after or wants"`
+
+			Rdeps struct {
+				Stack      string `arg:"" name:"stack" predictor:"file" help:"Path of the stack"`
+				Transitive bool   `help:"Include the reverse dependencies of the reverse dependencies"`
+				AsJSON     bool   `help:"Outputs the result as JSON"`
+			} `cmd:"" help:"List the stacks that run after or want the given stack"`
+		} `cmd:"" help:"Stack dependency commands"`
+
 		Metadata struct{} `cmd:"" help:"Shows metadata available on the project"`
 
 		Globals struct {
+			GlobalPath  string            `arg:"" optional:"true" name:"global" help:"Global to explain, eg.: global.a.b"`
+			Explain     bool              `help:"Shows where each global is defined, the definitions it overrides and the globals it references"`
+			AsJSON      bool              `help:"Outputs the explanation as JSON"`
+			Global      map[string]string `short:"g" help:"set/override globals. eg.: --global name=<expr>"`
+			GlobalsFile string            `predictor:"file" help:"HCL or JSON file with globals to set/override"`
 		} `cmd:"" help:"List globals for all stacks"`
 
 		Generate struct {
@@ -180,22 +216,31 @@ type cliSpec struct {
 		} `cmd:"" help:"Manages vendored Terraform modules"`
 
 		Eval struct {
-			Global map[string]string `short:"g" help:"set/override globals. eg.: --global name=<expr>"`
-			AsJSON bool              `help:"Outputs the result as a JSON value"`
-			Exprs  []string          `arg:"" help:"expressions to be evaluated" name:"expr" passthrough:""`
+			Global      map[string]string `short:"g" help:"set/override globals. eg.: --global name=<expr>"`
+			GlobalsFile string            `predictor:"file" help:"HCL or JSON file with globals to set/override"`
+			AsJSON      bool              `help:"Outputs the result as a JSON value"`
+			Exprs       []string          `arg:"" help:"expressions to be evaluated" name:"expr" passthrough:""`
 		} `cmd:"" help:"Eval expression"`
 
 		PartialEval struct {
-			Global map[string]string `short:"g" help:"set/override globals. eg.: --global name=<expr>"`
-			Exprs  []string          `arg:"" help:"expressions to be partially evaluated" name:"expr" passthrough:""`
+			Global      map[string]string `short:"g" help:"set/override globals. eg.: --global name=<expr>"`
+			GlobalsFile string            `predictor:"file" help:"HCL or JSON file with globals to set/override"`
+			Exprs       []string          `arg:"" help:"expressions to be partially evaluated" name:"expr" passthrough:""`
 		} `cmd:"" help:"Partial evaluate the expressions"`
 
 		GetConfigValue struct {
-			Global map[string]string `short:"g" help:"set/override globals. eg.: --global name=<expr>"`
-			AsJSON bool              `help:"Outputs the result as a JSON value"`
-			Vars   []string          `arg:"" help:"variable to be retrieved" name:"var" passthrough:""`
+			Global      map[string]string `short:"g" help:"set/override globals. eg.: --global name=<expr>"`
+			GlobalsFile string            `predictor:"file" help:"HCL or JSON file with globals to set/override"`
+			AsJSON      bool              `help:"Outputs the result as a JSON value"`
+			Vars        []string          `arg:"" help:"variable to be retrieved" name:"var" passthrough:""`
 		} `cmd:"" help:"Get configuration value"`
 
+		Console struct {
+			Stack       string            `arg:"" optional:"true" name:"stack" predictor:"file" help:"Path of the stack (or directory) whose evaluation context is loaded"`
+			Global      map[string]string `short:"g" help:"set/override globals. eg.: --global name=<expr>"`
+			GlobalsFile string            `predictor:"file" help:"HCL or JSON file with globals to set/override"`
+		} `cmd:"" help:"Interactive console for evaluating expressions"`
+
 		Cloud struct {
 			Login struct {
 			} `cmd:"" help:"login for cloud.terramate.io"`
@@ -246,6 +291,9 @@ type cli struct {
 	checkpointResults chan *checkpoint.CheckResponse
 
 	tags filter.TagClause
+
+	stackPaths []string
+	where      hhcl.Expression
 }
 
 func newCLI(version string, args []string, stdin io.Reader, stdout, stderr io.Writer) *cli {
@@ -485,6 +533,7 @@ func (c *cli) run() {
 
 	c.checkVersion()
 	c.setupFilterTags()
+	c.setupStackSelection()
 
 	logger.Debug().Msg("Handle command.")
 
@@ -500,8 +549,11 @@ func (c *cli) run() {
 		log.Fatal().Msg("no command specified")
 	case "run <cmd>":
 		c.setupGit()
+		c.setGlobalOverrides(c.parsedArgs.Run.Global, c.parsedArgs.Run.GlobalsFile)
 		c.runOnStacks()
-	case "generate":
+	case "generate", "generate <paths>":
+		c.setupGit()
+		c.setGlobalOverrides(c.parsedArgs.Generate.Global, c.parsedArgs.Generate.GlobalsFile)
 		c.generate()
 	case "experimental clone <srcdir> <destdir>":
 		c.cloneStack()
@@ -511,10 +563,31 @@ func (c *cli) run() {
 		c.vendorDownload()
 	case "experimental globals":
 		c.setupGit()
-		c.printStacksGlobals()
+		c.setGlobalOverrides(c.parsedArgs.Experimental.Globals.Global,
+			c.parsedArgs.Experimental.Globals.GlobalsFile)
+		switch {
+		case c.parsedArgs.Experimental.Globals.Explain:
+			c.explainStacksGlobals()
+		case c.parsedArgs.Experimental.Globals.AsJSON:
+			fatal(errors.E("the --as-json flag requires the --explain flag"))
+		default:
+			c.printStacksGlobals()
+		}
+	case "experimental globals <global>":
+		if !c.parsedArgs.Experimental.Globals.Explain {
+			fatal(errors.E("the global argument requires the --explain flag"))
+		}
+		c.setupGit()
+		c.setGlobalOverrides(c.parsedArgs.Experimental.Globals.Global,
+			c.parsedArgs.Experimental.Globals.GlobalsFile)
+		c.explainStacksGlobals()
 	case "experimental generate debug":
 		c.setupGit()
 		c.generateDebug()
+	case "experimental stack deps <stack>":
+		c.printStackDeps(false)
+	case "experimental stack rdeps <stack>":
+		c.printStackDeps(true)
 	case "experimental metadata":
 		c.setupGit()
 		c.printMetadata()
@@ -539,6 +612,10 @@ func (c *cli) run() {
 		log.Fatal().Msg("no variable specified")
 	case "experimental get-config-value <var>":
 		c.getConfigValue()
+	case "experimental console":
+		c.console("")
+	case "experimental console <stack>":
+		c.console(c.parsedArgs.Experimental.Console.Stack)
 	case "experimental cloud info":
 		c.cloudInfo()
 	default:
@@ -753,11 +830,31 @@ func (c *cli) cloneStack() {
 	c.output.MsgStdOut("Cloned stack %s to %s with success", srcstack, deststack)
 	c.output.MsgStdOut("Generating code on the new cloned stack")
 
-	c.generate()
+	c.generateWith(c.generateOptions())
 }
 
+// generate generates code for the stacks selected by the same flags used by
+// run and by the paths given to generate. If no selection is made then code
+// is generated for the whole project, independent of the working dir.
 func (c *cli) generate() {
-	report, vendorReport := c.gencodeWithVendor()
+	opts := c.generateOptions()
+	opts.Stacks = c.generateSelectedStacks()
+	for _, pattern := range c.parsedArgs.Generate.Label {
+		if err := filter.ValidatePathGlob(pattern); err != nil {
+			fatal(err, "invalid --label")
+		}
+		opts.Labels = append(opts.Labels, pattern)
+	}
+	c.generateWith(opts)
+}
+
+func (c *cli) generateWith(opts generate.Options) {
+	if c.parsedArgs.Generate.DryRun || c.parsedArgs.Generate.Check {
+		c.generateDryRun(opts)
+		return
+	}
+
+	report, vendorReport := c.gencodeWithVendor(opts)
 
 	c.output.MsgStdOut(report.Full())
 
@@ -772,9 +869,31 @@ func (c *cli) generate() {
 	}
 }
 
-// gencodeWithVendor will generate code for the whole project providing automatic
+// generateDryRun computes the code generation changes without applying them,
+// printing the report and the unified diff of each file that would change.
+// No vendoring is done on dry run since it would change the file system.
+func (c *cli) generateDryRun(opts generate.Options) {
+	report := generate.DryRun(c.cfg(), c.vendorDir(), nil, opts)
+
+	c.output.MsgStdOut(report.Full())
+
+	if diff := report.Diff(); diff != "" {
+		c.output.MsgStdOut("")
+		c.output.MsgStdOut(strings.TrimSuffix(diff, "\n"))
+	}
+
+	if report.HasFailures() {
+		os.Exit(1)
+	}
+
+	if c.parsedArgs.Generate.Check && report.HasChanges() {
+		os.Exit(1)
+	}
+}
+
+// gencodeWithVendor will generate code for the project providing automatic
 // vendoring of all tm_vendor calls.
-func (c *cli) gencodeWithVendor() (generate.Report, download.Report) {
+func (c *cli) gencodeWithVendor(opts generate.Options) (generate.Report, download.Report) {
 	vendorProgressEvents := download.NewEventStream()
 	progressHandlerDone := c.handleVendorProgressEvents(vendorProgressEvents)
 
@@ -789,7 +908,7 @@ func (c *cli) gencodeWithVendor() (generate.Report, download.Report) {
 
 	log.Debug().Msg("generating code")
 
-	report := generate.Do(c.cfg(), c.vendorDir(), vendorRequestEvents)
+	report := generate.Do(c.cfg(), c.vendorDir(), vendorRequestEvents, opts)
 
 	log.Debug().Msg("code generation finished, waiting for vendor requests to be handled")
 
@@ -809,6 +928,91 @@ func (c *cli) gencodeWithVendor() (generate.Report, download.Report) {
 	return report, vendorReport
 }
 
+// generateOptions returns the code generation options for the whole project.
+func (c *cli) generateOptions() generate.Options {
+	return generate.Options{
+		Cache: c.generateCache(),
+		Force: c.parsedArgs.Generate.Force,
+	}
+}
+
+// generateSelectedStacks returns the stacks selected for code generation or
+// nil if no stack selection was made.
+func (c *cli) generateSelectedStacks() prj.Paths {
+	if !c.hasStackSelection() && len(c.parsedArgs.Generate.Paths) == 0 {
+		return nil
+	}
+
+	mgr := stack.NewManager(c.cfg(), c.prj.baseRef)
+	report, err := c.listStacks(mgr, c.parsedArgs.Changed)
+	if err != nil {
+		fatal(err, "generate: selecting stacks")
+	}
+
+	var dirs []prj.Path
+	for _, p := range c.parsedArgs.Generate.Paths {
+		abspath := p
+		if !filepath.IsAbs(abspath) {
+			abspath = filepath.Join(c.wd(), p)
+		}
+		abspath = filepath.Clean(abspath)
+		if abspath != c.rootdir() && !strings.HasPrefix(abspath, c.rootdir()+string(filepath.Separator)) {
+			fatal(errors.E("path %s is outside the project", p))
+		}
+		dirs = append(dirs, prj.PrjAbsPath(c.rootdir(), abspath))
+	}
+
+	// explicit paths are already resolved from the working dir, so stacks
+	// are only filtered by the working dir when no path is given.
+	entries := report.Stacks
+	if len(dirs) == 0 {
+		entries = c.filterStacksByWorkingDir(entries)
+	}
+
+	stacks := prj.Paths{}
+	for _, e := range c.filterStacksBySelection(entries) {
+		if len(dirs) > 0 && !isInsideAny(e.Stack.Dir, dirs) {
+			continue
+		}
+		stacks = append(stacks, e.Stack.Dir)
+	}
+	return stacks
+}
+
+// hasStackSelection tells if any of the stack selection flags is set.
+func (c *cli) hasStackSelection() bool {
+	return c.parsedArgs.Changed ||
+		len(c.parsedArgs.Tags) > 0 ||
+		len(c.parsedArgs.NoTags) > 0 ||
+		len(c.parsedArgs.StackPath) > 0 ||
+		len(c.parsedArgs.StackID) > 0 ||
+		c.parsedArgs.Where != ""
+}
+
+func isInsideAny(dir prj.Path, dirs []prj.Path) bool {
+	for _, other := range dirs {
+		if other.String() == "/" || dir == other || dir.HasPrefix(other.String()+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// generateCache returns the cache of generated code or nil if it is disabled.
+func (c *cli) generateCache() *generate.Cache {
+	if c.parsedArgs.DisableGenerateCache {
+		return nil
+	}
+
+	if disableCache, ok := os.LookupEnv("TM_DISABLE_GENERATE_CACHE"); ok {
+		if envVarIsSet(disableCache) {
+			return nil
+		}
+	}
+
+	return generate.NewCache(filepath.Join(c.clicfg.UserTerramateDir, "cache", "generate"))
+}
+
 func (c *cli) checkGitUntracked() bool {
 	if c.parsedArgs.DisableCheckGitUntracked {
 		return false
@@ -991,7 +1195,7 @@ func (c *cli) createStack() {
 		return
 	}
 
-	report, vendorReport := c.gencodeWithVendor()
+	report, vendorReport := c.gencodeWithVendor(c.generateOptions())
 	if report.HasFailures() {
 		c.output.MsgStdOut("Code generation failed")
 		c.output.MsgStdOut(report.Minimal())
@@ -1060,6 +1264,10 @@ func (c *cli) printStacks() {
 		log.Fatal().Msg("the --why flag must be used together with --changed")
 	}
 
+	if len(c.parsedArgs.List.Fields) > 0 && c.parsedArgs.List.Format == listFormatText {
+		log.Fatal().Msg("the --fields flag must be used together with --format")
+	}
+
 	mgr := stack.NewManager(c.cfg(), c.prj.baseRef)
 	report, err := c.listStacks(mgr, c.parsedArgs.Changed)
 	if err != nil {
@@ -1068,7 +1276,13 @@ func (c *cli) printStacks() {
 
 	c.gitFileSafeguards(report.Checks, false)
 
-	for _, entry := range c.filterStacks(report.Stacks) {
+	entries := c.filterStacks(report.Stacks)
+	if c.parsedArgs.List.Format != listFormatText {
+		c.printStacksStructured(entries)
+		return
+	}
+
+	for _, entry := range entries {
 		stack := entry.Stack
 
 		log.Debug().Msgf("printing stack %s", stack.Dir)
@@ -1078,6 +1292,10 @@ func (c *cli) printStacks() {
 			continue
 		}
 
+		if state := stackState(stack); state != "enabled" {
+			stackRepr += " (" + state + ")"
+		}
+
 		if c.parsedArgs.List.Why {
 			c.output.MsgStdOut("%s - %s", stackRepr, entry.Reason)
 		} else {
@@ -1094,7 +1312,7 @@ func (c *cli) printRunEnv() {
 	}
 
 	for _, stackEntry := range c.filterStacks(report.Stacks) {
-		envVars, err := run.LoadEnv(c.cfg(), stackEntry.Stack)
+		envVars, err := run.LoadRedactedEnv(c.cfg(), stackEntry.Stack)
 		if err != nil {
 			fatal(err, "loading stack run environment")
 		}
@@ -1427,7 +1645,10 @@ func (c *cli) checkGenCode() bool {
 }
 
 func (c *cli) eval() {
-	ctx := c.setupEvalContext(c.parsedArgs.Experimental.Eval.Global)
+	ctx := c.setupEvalContext(c.globalOverrides(
+		c.parsedArgs.Experimental.Eval.Global,
+		c.parsedArgs.Experimental.Eval.GlobalsFile,
+	))
 	for _, exprStr := range c.parsedArgs.Experimental.Eval.Exprs {
 		expr, err := ast.ParseExpression(exprStr, "<cmdline>")
 		if err != nil {
@@ -1442,7 +1663,10 @@ func (c *cli) eval() {
 }
 
 func (c *cli) partialEval() {
-	ctx := c.setupEvalContext(c.parsedArgs.Experimental.PartialEval.Global)
+	ctx := c.setupEvalContext(c.globalOverrides(
+		c.parsedArgs.Experimental.PartialEval.Global,
+		c.parsedArgs.Experimental.PartialEval.GlobalsFile,
+	))
 	for _, exprStr := range c.parsedArgs.Experimental.PartialEval.Exprs {
 		expr, err := ast.ParseExpression(exprStr, "<cmdline>")
 		if err != nil {
@@ -1452,6 +1676,7 @@ func (c *cli) partialEval() {
 		if err != nil {
 			fatal(err, "partial eval %q", exprStr)
 		}
+		eval.RedactExpr(newexpr)
 		c.output.MsgStdOut(string(hclwrite.Format(ast.TokensForExpression(newexpr).Bytes())))
 	}
 }
@@ -1461,7 +1686,10 @@ func (c *cli) getConfigValue() {
 		Str("action", "cli.getConfigValue()").
 		Logger()
 
-	ctx := c.setupEvalContext(c.parsedArgs.Experimental.GetConfigValue.Global)
+	ctx := c.setupEvalContext(c.globalOverrides(
+		c.parsedArgs.Experimental.GetConfigValue.Global,
+		c.parsedArgs.Experimental.GetConfigValue.GlobalsFile,
+	))
 	for _, exprStr := range c.parsedArgs.Experimental.GetConfigValue.Vars {
 		expr, err := ast.ParseExpression(exprStr, "<cmdline>")
 		if err != nil {
@@ -1488,70 +1716,75 @@ func (c *cli) getConfigValue() {
 }
 
 func (c *cli) outputEvalResult(val cty.Value, asJSON bool) {
-	var data []byte
+	data, err := formatEvalResult(val, asJSON)
+	if err != nil {
+		fatal(err)
+	}
+	c.output.MsgStdOut(data)
+}
+
+// formatEvalResult formats the redacted value as HCL, or as JSON if asJSON is
+// true. String values are not quoted when formatted as HCL.
+func formatEvalResult(val cty.Value, asJSON bool) (string, error) {
+	val = eval.Redact(val)
 	if asJSON {
-		var err error
-		data, err = json.Marshal(val, val.Type())
+		data, err := json.Marshal(val, val.Type())
 		if err != nil {
-			fatal(err, "converting value %s to json", val.GoString())
-		}
-	} else {
-		if val.Type() == cty.String {
-			data = []byte(val.AsString())
-		} else {
-			tokens := ast.TokensForValue(val)
-			data = []byte(hclwrite.Format(tokens.Bytes()))
+			return "", errors.E(err, "converting value %s to json", val.GoString())
 		}
+		return string(data), nil
+	}
+	if val.Type() == cty.String {
+		return val.AsString(), nil
 	}
+	tokens := ast.TokensForValue(val)
+	return string(hclwrite.Format(tokens.Bytes())), nil
+}
 
-	c.output.MsgStdOut(string(data))
+func (c *cli) setupEvalContext(overrides []config.GlobalOverride) *eval.Context {
+	ctx, _, err := c.newEvalContext(prj.PrjAbsPath(c.rootdir(), c.wd()), overrides)
+	if err != nil {
+		fatal(err, "setup eval context")
+	}
+	return ctx
 }
 
-func (c *cli) setupEvalContext(overrideGlobals map[string]string) *eval.Context {
-	ctx := eval.NewContext(stdlib.Functions(c.wd()))
+// newEvalContext creates the evaluation context of the given directory, with
+// the stdlib functions, the terramate runtime and the globals of the directory.
+// The globals that fail to evaluate are not set in the context and the returned
+// report has their errors.
+func (c *cli) newEvalContext(dir prj.Path, overrides []config.GlobalOverride) (*eval.Context, globals.EvalReport, error) {
+	tree, ok := c.cfg().Lookup(dir)
+	if !ok {
+		return nil, globals.EvalReport{}, errors.E("configuration at %s not found", dir)
+	}
+
+	absdir := filepath.Join(c.rootdir(), filepath.FromSlash(dir.String()))
+	ctx := eval.NewContext(stdlib.Functions(absdir))
 	runtime := c.cfg().Runtime()
-	if config.IsStack(c.cfg(), c.wd()) {
-		st, err := config.LoadStack(c.cfg(), prj.PrjAbsPath(c.rootdir(), c.wd()))
+	if config.IsStack(c.cfg(), absdir) {
+		st, err := config.LoadStack(c.cfg(), dir)
 		if err != nil {
-			fatal(err, "setup eval context: loading stack config")
+			return nil, globals.EvalReport{}, errors.E(err, "loading stack config")
 		}
 		runtime.Merge(st.RuntimeValues(c.cfg()))
 	}
 
 	ctx.SetNamespace("terramate", runtime)
 
-	wdPath := prj.PrjAbsPath(c.rootdir(), c.wd())
-	tree, ok := c.cfg().Lookup(wdPath)
-	if !ok {
-		fatal(errors.E("configuration at %s not found", wdPath))
-	}
 	exprs, err := globals.LoadExprs(tree)
 	if err != nil {
-		fatal(err, "loading globals expressions")
+		return nil, globals.EvalReport{}, errors.E(err, "loading globals expressions")
 	}
 
-	for name, exprStr := range overrideGlobals {
-		expr, err := ast.ParseExpression(exprStr, "<cmdline>")
-		if err != nil {
-			fatal(errors.E(err, "--global %s=%s is an invalid expresssion", name, exprStr))
-		}
-		parts := strings.Split(name, ".")
-		length := len(parts)
-		globalPath := globals.NewGlobalAttrPath(parts[0:length-1], parts[length-1])
-		exprs.SetOverride(
-			wdPath,
-			globalPath,
-			expr,
-			info.NewRange(c.rootdir(), hhcl.Range{
-				Filename: "<eval argument>",
-				Start:    hhcl.InitialPos,
-				End:      hhcl.InitialPos,
-			}),
-		)
-	}
-
-	_ = exprs.Eval(ctx)
-	return ctx
+	schemas, err := globals.LoadSchemas(tree)
+	if err != nil {
+		return nil, globals.EvalReport{}, errors.E(err, "loading globals schemas")
+	}
+
+	exprs.SetOverrides(dir, overrides)
+	report := exprs.EvalWithSchemas(ctx, schemas)
+	return ctx, report, nil
 }
 
 func envVarIsSet(val string) bool {
@@ -1570,7 +1803,7 @@ func (c *cli) checkOutdatedGeneratedCode() {
 
 	logger.Trace().Msg("checking if any stack has outdated code")
 
-	outdatedFiles, err := generate.DetectOutdated(c.cfg(), c.vendorDir())
+	outdatedFiles, err := generate.DetectOutdated(c.cfg(), c.vendorDir(), c.generateCache())
 
 	if err != nil {
 		fatal(err, "failed to check outdated code on project")
@@ -1644,6 +1877,9 @@ func (c *cli) runOnStacks() {
 		if err != nil {
 			fatal(err, "computing selected stacks")
 		}
+		if !c.parsedArgs.Run.IncludeDisabled {
+			stacks = c.filterDisabledStacks(stacks)
+		}
 	}
 
 	c.createCloudDeployment(stacks, c.parsedArgs.Run.Command)
@@ -1769,7 +2005,57 @@ func (c *cli) computeSelectedStacks(ensureCleanRepo bool) (config.List[*config.S
 }
 
 func (c *cli) filterStacks(stacks []stack.Entry) []stack.Entry {
-	return c.filterStacksByTags(c.filterStacksByWorkingDir(stacks))
+	return c.filterStacksBySelection(c.filterStacksByWorkingDir(stacks))
+}
+
+// filterStacksBySelection filters the stacks by the stack selection flags,
+// ignoring the working dir.
+func (c *cli) filterStacksBySelection(stacks []stack.Entry) []stack.Entry {
+	filtered := c.filterStacksByTags(stacks)
+	filtered = c.filterStacksByPath(filtered)
+	filtered = c.filterStacksByID(filtered)
+	return c.filterStacksByWhere(filtered)
+}
+
+// filterDisabledStacks removes the disabled and manual stacks, unless
+// they were explicitly selected by the working directory or by the
+// --stack-path and --stack-id flags.
+func (c *cli) filterDisabledStacks(stacks config.List[*config.SortableStack]) config.List[*config.SortableStack] {
+	wd := prj.PrjAbsPath(c.rootdir(), c.wd())
+	filtered := config.List[*config.SortableStack]{}
+	for _, st := range stacks {
+		if !st.Disabled && !st.Manual {
+			filtered = append(filtered, st)
+			continue
+		}
+		if st.Dir() == wd || c.isStackExplicitlySelected(st.Stack) {
+			filtered = append(filtered, st)
+			continue
+		}
+		log.Debug().
+			Stringer("stack", st.Dir()).
+			Bool("disabled", st.Disabled).
+			Bool("manual", st.Manual).
+			Msg("ignoring stack not explicitly selected")
+	}
+	return filtered
+}
+
+func (c *cli) isStackExplicitlySelected(st *config.Stack) bool {
+	for _, pattern := range c.stackPaths {
+		if filter.MatchPath(pattern, st.Dir.String()) {
+			return true
+		}
+	}
+	if st.ID == "" {
+		return false
+	}
+	for _, id := range c.parsedArgs.StackID {
+		if id == st.ID {
+			return true
+		}
+	}
+	return false
 }
 
 func (c *cli) filterStacksByWorkingDir(stacks []stack.Entry) []stack.Entry {
@@ -1805,6 +2091,76 @@ func (c *cli) filterStacksByTags(entries []stack.Entry) []stack.Entry {
 	return filtered
 }
 
+func (c *cli) filterStacksByPath(entries []stack.Entry) []stack.Entry {
+	if len(c.stackPaths) == 0 {
+		return entries
+	}
+	filtered := []stack.Entry{}
+	for _, entry := range entries {
+		for _, pattern := range c.stackPaths {
+			if filter.MatchPath(pattern, entry.Stack.Dir.String()) {
+				filtered = append(filtered, entry)
+				break
+			}
+		}
+	}
+	return filtered
+}
+
+func (c *cli) filterStacksByID(entries []stack.Entry) []stack.Entry {
+	if len(c.parsedArgs.StackID) == 0 {
+		return entries
+	}
+	ids := map[string]struct{}{}
+	for _, id := range c.parsedArgs.StackID {
+		ids[id] = struct{}{}
+	}
+	filtered := []stack.Entry{}
+	for _, entry := range entries {
+		if _, ok := ids[entry.Stack.ID]; ok && entry.Stack.ID != "" {
+			filtered = append(filtered, entry)
+		}
+	}
+	return filtered
+}
+
+func (c *cli) filterStacksByWhere(entries []stack.Entry) []stack.Entry {
+	if c.where == nil {
+		return entries
+	}
+	filtered := []stack.Entry{}
+	for _, entry := range entries {
+		st := entry.Stack
+		logger := log.With().
+			Str("action", "filterStacksByWhere()").
+			Stringer("stack", st.Dir).
+			Logger()
+
+		report := globals.ForStack(c.cfg(), st)
+		if err := report.AsError(); err != nil {
+			errlog.Fatal(logger, err, "--where: loading stack globals")
+		}
+
+		evalctx := stack.NewEvalCtx(c.cfg(), st, report.Globals)
+		val, err := evalctx.Eval(c.where)
+		if err != nil {
+			errlog.Fatal(logger, err, "--where: evaluating expression")
+		}
+		if !val.Type().Equals(cty.Bool) || !val.IsKnown() || val.IsNull() {
+			errlog.Fatal(logger, errors.E(
+				"--where expression must evaluate to a bool but got %s",
+				val.Type().FriendlyName(),
+			))
+		}
+		val, _ = val.UnmarkDeep()
+		if val.True() {
+			logger.Trace().Msg("stack selected by --where")
+			filtered = append(filtered, entry)
+		}
+	}
+	return filtered
+}
+
 func (c cli) checkVersion() {
 	logger := log.With().
 		Str("action", "cli.checkVersion()").
@@ -1921,6 +2277,30 @@ func (c *cli) setupFilterTags() {
 	}
 }
 
+func (c *cli) setupStackSelection() {
+	relwd := prj.PrjAbsPath(c.rootdir(), c.wd())
+	for _, pattern := range c.parsedArgs.StackPath {
+		if !path.IsAbs(pattern) {
+			pattern = relwd.Join(pattern).String()
+		}
+		pattern = path.Clean(pattern)
+		if err := filter.ValidatePathGlob(pattern); err != nil {
+			fatal(err)
+		}
+		c.stackPaths = append(c.stackPaths, pattern)
+	}
+
+	if c.parsedArgs.Where == "" {
+		return
+	}
+
+	expr, err := ast.ParseExpression(c.parsedArgs.Where, "<--where>")
+	if err != nil {
+		fatal(err, "parsing --where expression")
+	}
+	c.where = expr
+}
+
 func newGit(basedir string, checkrepo bool) (*git.Git, error) {
 	log.Debug().
 		Str("action", "newGit()").
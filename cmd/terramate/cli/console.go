@@ -0,0 +1,479 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/terramate-io/terramate/cmd/terramate/cli/lineedit"
+	"github.com/terramate-io/terramate/config"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/hcl/ast"
+	"github.com/terramate-io/terramate/hcl/eval"
+	prj "github.com/terramate-io/terramate/project"
+	"github.com/zclconf/go-cty/cty"
+)
+
+const (
+	consoleHistoryFile = "console_history"
+	consoleHistorySize = 1000
+)
+
+const consoleHelp = `Enter an expression to evaluate it or one of the commands below:
+  :help               Shows this help
+  :ns [name]          Lists the namespaces or shows the given namespace
+  :stack [path]       Shows the current stack or switches to the given one
+  :stacks             Lists the stacks of the project
+  :reload             Reloads the project configuration
+  :history            Lists the previous entries
+  !<n>                Runs the entry <n> of the history
+  :complete <prefix>  Lists the completions of a path, eg.: global.net
+  :quit               Exits the console
+
+On terminals, the tab key completes paths, eg.: global.net<TAB>, and the up and
+down arrows navigate the history. The entries are saved to the history file on
+the user Terramate dir, except the ones starting with a space.
+The values of the env namespace are redacted by :ns env.`
+
+// console is an interactive session evaluating expressions with the
+// evaluation context of a stack.
+type console struct {
+	cli       *cli
+	dir       prj.Path
+	overrides []config.GlobalOverride
+	ctx       *eval.Context
+
+	history     []string
+	historyFile string
+}
+
+func (c *cli) console(stackdir string) {
+	dir := prj.PrjAbsPath(c.rootdir(), c.wd())
+	if stackdir != "" {
+		absdir := stackdir
+		if !filepath.IsAbs(absdir) {
+			absdir = filepath.Join(c.wd(), absdir)
+		}
+		absdir = filepath.Clean(absdir)
+		if absdir != c.rootdir() &&
+			!strings.HasPrefix(absdir, c.rootdir()+string(filepath.Separator)) {
+			fatal(errors.E("stack %s is outside the project", stackdir))
+		}
+		dir = prj.PrjAbsPath(c.rootdir(), absdir)
+	}
+
+	cons := &console{
+		cli: c,
+		overrides: c.globalOverrides(
+			c.parsedArgs.Experimental.Console.Global,
+			c.parsedArgs.Experimental.Console.GlobalsFile,
+		),
+	}
+	if err := cons.load(dir); err != nil {
+		fatal(err, "loading console evaluation context")
+	}
+
+	if c.clicfg.UserTerramateDir != "" {
+		cons.historyFile = filepath.Join(c.clicfg.UserTerramateDir, consoleHistoryFile)
+		cons.loadHistory()
+	}
+
+	readLine := cons.lineReader(c.stdin, c.stdout)
+	for {
+		text, err := readLine()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			fatal(err, "reading console input")
+		}
+
+		// as in shells, entries starting with a space are kept out of the
+		// history file, so secrets typed in the console can be left out.
+		persist := !strings.HasPrefix(text, " ")
+		line := strings.TrimSpace(text)
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "!") {
+			entry, err := cons.historyEntry(line[1:])
+			if err != nil {
+				cons.printErr(err)
+				continue
+			}
+			c.output.MsgStdOut(entry)
+			line = entry
+		}
+
+		cons.addHistory(line, persist)
+		if quit := cons.exec(line); quit {
+			return
+		}
+	}
+}
+
+// lineReader returns a function reading the console entries, which returns
+// io.EOF when the input ends. On terminals supporting raw mode, the entries
+// are read with a line editor providing history navigation and completion of
+// paths with the tab key, otherwise plain lines are read.
+func (cons *console) lineReader(in io.Reader, out io.Writer) func() (string, error) {
+	f, ok := in.(*os.File)
+	interactive := ok && isTerminal(f)
+	if interactive {
+		if restore, err := lineedit.MakeRaw(int(f.Fd())); err == nil {
+			_ = restore()
+			return cons.editLines(f, out)
+		}
+	}
+
+	scanner := bufio.NewScanner(in)
+	return func() (string, error) {
+		if interactive {
+			fmt.Fprintf(out, "%s> ", cons.dir)
+		}
+		if scanner.Scan() {
+			return scanner.Text(), nil
+		}
+		if interactive {
+			fmt.Fprintln(out)
+		}
+		if err := scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+}
+
+// editLines returns a function reading the console entries from the terminal
+// with a line editor. The terminal is only in raw mode while an entry is being
+// read, so the output of the entries is written as usual.
+func (cons *console) editLines(f *os.File, out io.Writer) func() (string, error) {
+	editor := lineedit.New(f, out)
+	editor.Complete = cons.completions
+	return func() (string, error) {
+		restore, err := lineedit.MakeRaw(int(f.Fd()))
+		if err != nil {
+			return "", errors.E(err, "setting terminal raw mode")
+		}
+		defer func() {
+			if err := restore(); err != nil {
+				log.Warn().Err(err).Msg("failed to restore terminal mode")
+			}
+		}()
+
+		editor.Prompt = cons.dir.String() + "> "
+		editor.History = cons.history
+		return editor.ReadLine()
+	}
+}
+
+// exec executes the console entry and returns true if the console must exit.
+func (cons *console) exec(line string) bool {
+	if !strings.HasPrefix(line, ":") {
+		cons.eval(line)
+		return false
+	}
+
+	cmd, arg, _ := strings.Cut(line[1:], " ")
+	arg = strings.TrimSpace(arg)
+
+	switch cmd {
+	case "help":
+		cons.cli.output.MsgStdOut(consoleHelp)
+	case "quit", "exit":
+		return true
+	case "ns":
+		cons.namespace(arg)
+	case "stack":
+		if arg == "" {
+			cons.cli.output.MsgStdOut(cons.dir.String())
+			return false
+		}
+		cons.switchStack(arg)
+	case "stacks":
+		for _, stackdir := range cons.cli.cfg().Stacks() {
+			cons.cli.output.MsgStdOut(stackdir.String())
+		}
+	case "reload":
+		cons.reload()
+	case "history":
+		for i, entry := range cons.history {
+			cons.cli.output.MsgStdOut("%5d  %s", i+1, entry)
+		}
+	case "complete":
+		for _, completion := range cons.completions(arg) {
+			cons.cli.output.MsgStdOut(completion)
+		}
+	default:
+		cons.printErr(errors.E("unknown command :%s, type :help for the list of commands", cmd))
+	}
+	return false
+}
+
+// load creates the evaluation context of the given dir and makes it the
+// current one. On failure, the current evaluation context is kept.
+func (cons *console) load(dir prj.Path) error {
+	ctx, report, err := cons.cli.newEvalContext(dir, cons.overrides)
+	if err != nil {
+		return err
+	}
+
+	ctx.SetEnv(os.Environ())
+
+	if err := report.AsError(); err != nil {
+		cons.cli.output.MsgStdErr("Warning: globals that failed to evaluate are not available: %v", err)
+	}
+
+	cons.dir = dir
+	cons.ctx = ctx
+	return nil
+}
+
+func (cons *console) eval(exprStr string) {
+	expr, err := ast.ParseExpression(exprStr, "<console>")
+	if err != nil {
+		cons.printErr(err)
+		return
+	}
+	val, err := cons.ctx.Eval(expr)
+	if err != nil {
+		cons.printErr(err)
+		return
+	}
+	data, err := formatEvalResult(val, false)
+	if err != nil {
+		cons.printErr(err)
+		return
+	}
+	cons.cli.output.MsgStdOut(data)
+}
+
+func (cons *console) namespace(name string) {
+	vars := cons.ctx.Unwrap().Variables
+	if name == "" {
+		names := make([]string, 0, len(vars))
+		for name := range vars {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			cons.cli.output.MsgStdOut(name)
+		}
+		return
+	}
+
+	val, ok := vars[name]
+	if !ok {
+		cons.printErr(errors.E("namespace %s not found", name))
+		return
+	}
+	if name == "env" {
+		// the environment usually has credentials, so only the names of
+		// the variables are shown.
+		val = sensitiveAttrs(val)
+	}
+	data, err := formatEvalResult(val, false)
+	if err != nil {
+		cons.printErr(err)
+		return
+	}
+	cons.cli.output.MsgStdOut(data)
+}
+
+// switchStack loads the evaluation context of the given dir, which is either
+// a project absolute path or a path relative to the current dir.
+func (cons *console) switchStack(dir string) {
+	if !path.IsAbs(dir) {
+		dir = path.Join(cons.dir.String(), dir)
+	}
+	if err := cons.load(prj.NewPath(path.Clean(dir))); err != nil {
+		cons.printErr(err)
+	}
+}
+
+// reload reloads the project configuration from disk and recreates the
+// evaluation context of the current dir.
+func (cons *console) reload() {
+	cfg, err := config.LoadRoot(cons.cli.rootdir())
+	if err != nil {
+		cons.printErr(errors.E(err, "reloading configuration"))
+		return
+	}
+
+	oldcfg := cons.cli.prj.root
+	cons.cli.prj.root = *cfg
+	if err := cons.load(cons.dir); err != nil {
+		cons.cli.prj.root = oldcfg
+		cons.printErr(errors.E(err, "reloading configuration"))
+	}
+}
+
+// completions returns the paths of the evaluation context starting with the
+// given prefix, completing a single path element.
+func (cons *console) completions(prefix string) []string {
+	vars := cons.ctx.Unwrap().Variables
+	parts := strings.Split(prefix, ".")
+
+	var res []string
+	if len(parts) == 1 {
+		for name := range vars {
+			if strings.HasPrefix(name, prefix) {
+				res = append(res, name)
+			}
+		}
+		sort.Strings(res)
+		return res
+	}
+
+	val, ok := vars[parts[0]]
+	if !ok {
+		return nil
+	}
+	last := len(parts) - 1
+	for _, part := range parts[1:last] {
+		keys := objectKeys(val)
+		if _, ok := keys[part]; !ok {
+			return nil
+		}
+		val, _ = val.Unmark()
+		if val.Type().IsObjectType() {
+			val = val.GetAttr(part)
+		} else {
+			val = val.Index(cty.StringVal(part))
+		}
+	}
+
+	base := strings.Join(parts[:last], ".")
+	for key := range objectKeys(val) {
+		if strings.HasPrefix(key, parts[last]) {
+			res = append(res, base+"."+key)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+func (cons *console) historyEntry(index string) (string, error) {
+	n, err := strconv.Atoi(index)
+	if err != nil || n < 1 || n > len(cons.history) {
+		return "", errors.E("history entry %q not found", index)
+	}
+	return cons.history[n-1], nil
+}
+
+func (cons *console) loadHistory() {
+	logger := log.With().
+		Str("action", "console.loadHistory()").
+		Str("file", cons.historyFile).
+		Logger()
+
+	data, err := os.ReadFile(cons.historyFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Warn().Err(err).Msg("failed to read console history")
+		}
+		return
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) > consoleHistorySize {
+		lines = lines[len(lines)-consoleHistorySize:]
+		err := os.WriteFile(cons.historyFile, []byte(strings.Join(lines, "\n")+"\n"), 0600)
+		if err != nil {
+			logger.Warn().Err(err).Msg("failed to truncate console history")
+		}
+	}
+	for _, line := range lines {
+		if line != "" {
+			cons.history = append(cons.history, line)
+		}
+	}
+}
+
+func (cons *console) addHistory(line string, persist bool) {
+	cons.history = append(cons.history, line)
+	if len(cons.history) > consoleHistorySize {
+		cons.history = cons.history[len(cons.history)-consoleHistorySize:]
+	}
+
+	if cons.historyFile == "" || !persist {
+		return
+	}
+
+	logger := log.With().
+		Str("action", "console.addHistory()").
+		Str("file", cons.historyFile).
+		Logger()
+
+	if err := os.MkdirAll(filepath.Dir(cons.historyFile), 0700); err != nil {
+		logger.Warn().Err(err).Msg("failed to create console history dir")
+		return
+	}
+
+	f, err := os.OpenFile(cons.historyFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		logger.Warn().Err(err).Msg("failed to open console history")
+		return
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			logger.Warn().Err(err).Msg("failed to close console history")
+		}
+	}()
+	if _, err := f.WriteString(line + "\n"); err != nil {
+		logger.Warn().Err(err).Msg("failed to write console history")
+	}
+}
+
+func (cons *console) printErr(err error) {
+	cons.cli.output.MsgStdErr("Error: %v", err)
+}
+
+// objectKeys returns the keys of the object or map value, or nil if the value
+// has no keys.
+func objectKeys(val cty.Value) map[string]struct{} {
+	val, _ = val.Unmark()
+	if val.IsNull() || !val.IsKnown() {
+		return nil
+	}
+
+	keys := map[string]struct{}{}
+	switch {
+	case val.Type().IsObjectType():
+		for key := range val.Type().AttributeTypes() {
+			keys[key] = struct{}{}
+		}
+	case val.Type().IsMapType():
+		for it := val.ElementIterator(); it.Next(); {
+			key, _ := it.Element()
+			keys[key.AsString()] = struct{}{}
+		}
+	}
+	return keys
+}
+
+// sensitiveAttrs returns a copy of the object or map value with all of its
+// attributes marked as sensitive.
+func sensitiveAttrs(val cty.Value) cty.Value {
+	values := map[string]cty.Value{}
+	for key := range objectKeys(val) {
+		values[key] = cty.StringVal("").Mark(eval.SensitiveMark)
+	}
+	return cty.ObjectVal(values)
+}
+
+func isTerminal(f *os.File) bool {
+	st, err := f.Stat()
+	return err == nil && st.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,83 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package cli
+
+import (
+	stdjson "encoding/json"
+	"path"
+	"path/filepath"
+
+	"github.com/rs/zerolog/log"
+	"github.com/terramate-io/terramate/errors/errlog"
+	prj "github.com/terramate-io/terramate/project"
+	"github.com/terramate-io/terramate/stack"
+)
+
+type stackDepJSON struct {
+	Path   string   `json:"path"`
+	ID     string   `json:"id"`
+	Name   string   `json:"name"`
+	Kinds  []string `json:"kinds"`
+	Direct bool     `json:"direct"`
+}
+
+func (c *cli) printStackDeps(reverse bool) {
+	stackdir := c.parsedArgs.Experimental.Stack.Deps.Stack
+	transitive := c.parsedArgs.Experimental.Stack.Deps.Transitive
+	asJSON := c.parsedArgs.Experimental.Stack.Deps.AsJSON
+	if reverse {
+		stackdir = c.parsedArgs.Experimental.Stack.Rdeps.Stack
+		transitive = c.parsedArgs.Experimental.Stack.Rdeps.Transitive
+		asJSON = c.parsedArgs.Experimental.Stack.Rdeps.AsJSON
+	}
+
+	if !path.IsAbs(stackdir) {
+		stackdir = prj.PrjAbsPath(c.rootdir(), filepath.Join(c.wd(), filepath.FromSlash(stackdir))).String()
+	}
+	stackPath := prj.NewPath(path.Clean(stackdir))
+
+	logger := log.With().
+		Str("action", "cli.printStackDeps()").
+		Stringer("stack", stackPath).
+		Bool("reverse", reverse).
+		Logger()
+
+	mgr := stack.NewManager(c.cfg(), c.prj.baseRef)
+
+	var (
+		deps []stack.Dependency
+		err  error
+	)
+	if reverse {
+		deps, err = mgr.ReverseDependencies(stackPath, transitive)
+	} else {
+		deps, err = mgr.Dependencies(stackPath, transitive)
+	}
+	if err != nil {
+		errlog.Fatal(logger, err, "computing stack dependencies")
+	}
+
+	if !asJSON {
+		for _, dep := range deps {
+			c.output.MsgStdOut(dep.Stack.Dir.String())
+		}
+		return
+	}
+
+	depsJSON := make([]stackDepJSON, 0, len(deps))
+	for _, dep := range deps {
+		depsJSON = append(depsJSON, stackDepJSON{
+			Path:   dep.Stack.Dir.String(),
+			ID:     dep.Stack.ID,
+			Name:   dep.Stack.Name,
+			Kinds:  dep.Kinds,
+			Direct: dep.Direct,
+		})
+	}
+	data, err := stdjson.MarshalIndent(depsJSON, "", "  ")
+	if err != nil {
+		errlog.Fatal(logger, err, "encoding stack dependencies")
+	}
+	c.output.MsgStdOut(string(data))
+}
@@ -0,0 +1,284 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package cli
+
+import (
+	stdjson "encoding/json"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	hhcl "github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/rs/zerolog/log"
+	"github.com/terramate-io/terramate/config"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/errors/errlog"
+	"github.com/terramate-io/terramate/globals"
+	"github.com/terramate-io/terramate/hcl/ast"
+	"github.com/terramate-io/terramate/hcl/eval"
+	"github.com/terramate-io/terramate/hcl/info"
+	prj "github.com/terramate-io/terramate/project"
+	"github.com/terramate-io/terramate/stack"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/json"
+)
+
+type (
+	stackGlobalsJSON struct {
+		Stack   string              `json:"stack"`
+		Globals []globalExplainJSON `json:"globals"`
+	}
+
+	globalExplainJSON struct {
+		Name        string                 `json:"name"`
+		Value       stdjson.RawMessage     `json:"value"`
+		Unset       bool                   `json:"unset"`
+		Definitions []globalDefinitionJSON `json:"definitions"`
+	}
+
+	globalDefinitionJSON struct {
+		DefinedAt  string   `json:"defined_at"`
+		ConfigDir  string   `json:"config_dir"`
+		References []string `json:"references"`
+	}
+)
+
+func (c *cli) explainStacksGlobals() {
+	logger := log.With().
+		Str("action", "explainStacksGlobals()").
+		Logger()
+
+	var filter []string
+	if name := c.parsedArgs.Experimental.Globals.GlobalPath; name != "" {
+		parts := strings.Split(name, ".")
+		if len(parts) < 2 || parts[0] != "global" {
+			fatal(errors.E("invalid global %q: expected a global path, eg.: global.a.b", name))
+		}
+		filter = parts[1:]
+	}
+
+	mgr := stack.NewManager(c.cfg(), c.prj.baseRef)
+	report, err := c.listStacks(mgr, c.parsedArgs.Changed)
+	if err != nil {
+		fatal(err, "explaining stacks globals: listing stacks")
+	}
+
+	stacksJSON := []stackGlobalsJSON{}
+	for _, stackEntry := range c.filterStacks(report.Stacks) {
+		st := stackEntry.Stack
+		logger := logger.With().
+			Stringer("stack", st.Dir).
+			Logger()
+
+		explanations, err := globals.ExplainForStack(c.cfg(), st)
+		if err != nil {
+			errlog.Fatal(logger, err, "explaining stacks globals: loading stack")
+		}
+
+		var selected []globals.Explanation
+		for _, explanation := range explanations {
+			if globals.IsPathRelated(explanation.Path, filter) {
+				selected = append(selected, explanation)
+			}
+		}
+
+		if c.parsedArgs.Experimental.Globals.AsJSON {
+			stackJSON := stackGlobalsJSON{
+				Stack:   st.Dir.String(),
+				Globals: []globalExplainJSON{},
+			}
+			for _, explanation := range selected {
+				globalJSON, err := newGlobalExplainJSON(explanation)
+				if err != nil {
+					errlog.Fatal(logger, err, "encoding global %s", explanation.Name())
+				}
+				stackJSON.Globals = append(stackJSON.Globals, globalJSON)
+			}
+			stacksJSON = append(stacksJSON, stackJSON)
+			continue
+		}
+
+		if len(selected) == 0 {
+			continue
+		}
+
+		c.output.MsgStdOut("\nstack %q:", st.Dir)
+		for _, explanation := range selected {
+			c.printGlobalExplanation(explanation)
+		}
+	}
+
+	if !c.parsedArgs.Experimental.Globals.AsJSON {
+		return
+	}
+
+	data, err := stdjson.MarshalIndent(stacksJSON, "", "  ")
+	if err != nil {
+		errlog.Fatal(logger, err, "encoding globals explanation")
+	}
+	c.output.MsgStdOut(string(data))
+}
+
+func (c *cli) printGlobalExplanation(explanation globals.Explanation) {
+	if explanation.Value == cty.NilVal {
+		c.output.MsgStdOut("\t%s is unset", explanation.Name())
+	} else {
+		tokens := ast.TokensForValue(eval.Redact(explanation.Value))
+		value := string(hclwrite.Format(tokens.Bytes()))
+		value = strings.ReplaceAll(value, "\n", "\n\t")
+		c.output.MsgStdOut("\t%s = %s", explanation.Name(), value)
+	}
+
+	for i, def := range explanation.Definitions {
+		if i == 0 {
+			c.output.MsgStdOut("\t\tdefined at %s", def.Origin)
+		} else {
+			c.output.MsgStdOut("\t\toverrides %s", def.Origin)
+		}
+		if len(def.References) > 0 {
+			c.output.MsgStdOut("\t\t\treferences %s", strings.Join(referenceNames(def), ", "))
+		}
+	}
+}
+
+func newGlobalExplainJSON(explanation globals.Explanation) (globalExplainJSON, error) {
+	globalJSON := globalExplainJSON{
+		Name:        explanation.Name(),
+		Value:       stdjson.RawMessage("null"),
+		Unset:       explanation.Value == cty.NilVal,
+		Definitions: []globalDefinitionJSON{},
+	}
+	if !globalJSON.Unset {
+		value := eval.Redact(explanation.Value)
+		data, err := json.Marshal(value, value.Type())
+		if err != nil {
+			return globalExplainJSON{}, err
+		}
+		globalJSON.Value = data
+	}
+	for _, def := range explanation.Definitions {
+		globalJSON.Definitions = append(globalJSON.Definitions, globalDefinitionJSON{
+			DefinedAt:  def.Origin.String(),
+			ConfigDir:  def.ConfigDir.String(),
+			References: referenceNames(def),
+		})
+	}
+	return globalJSON, nil
+}
+
+func referenceNames(def globals.Definition) []string {
+	names := []string{}
+	for _, ref := range def.References {
+		names = append(names, "global."+strings.Join(ref, "."))
+	}
+	return names
+}
+
+// setGlobalOverrides sets the globals of the --global and --globals-file flags
+// as overrides of the configured globals of all stacks.
+func (c *cli) setGlobalOverrides(globalArgs map[string]string, globalsFile string) {
+	c.cfg().SetGlobalOverrides(c.globalOverrides(globalArgs, globalsFile))
+}
+
+// globalOverrides parses the globals of the --global and --globals-file flags.
+// The globals of the --global flags take precedence over the ones in the file.
+func (c *cli) globalOverrides(globalArgs map[string]string, globalsFile string) []config.GlobalOverride {
+	var overrides []config.GlobalOverride
+	if globalsFile != "" {
+		if !filepath.IsAbs(globalsFile) {
+			globalsFile = filepath.Join(c.wd(), globalsFile)
+		}
+		fileOverrides, err := loadGlobalsFile(c.rootdir(), globalsFile)
+		if err != nil {
+			fatal(err, "loading --globals-file")
+		}
+		overrides = append(overrides, fileOverrides...)
+	}
+
+	names := make([]string, 0, len(globalArgs))
+	for name := range globalArgs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		exprStr := globalArgs[name]
+		path, err := parseGlobalOverridePath(name)
+		if err != nil {
+			fatal(err)
+		}
+		expr, err := ast.ParseExpression(exprStr, "<cmdline>")
+		if err != nil {
+			fatal(errors.E(err, "--global %s=%s is an invalid expresssion", name, exprStr))
+		}
+		overrides = append(overrides, config.GlobalOverride{
+			Path:   path,
+			Expr:   expr,
+			Origin: info.NewRange(c.rootdir(), expr.Range()),
+		})
+	}
+	return overrides
+}
+
+// loadGlobalsFile loads the globals overrides defined as attributes of the
+// given HCL file, or of the given JSON file if it has the .json extension.
+func loadGlobalsFile(rootdir, filename string) ([]config.GlobalOverride, error) {
+	parser := hclparse.NewParser()
+
+	var (
+		file  *hhcl.File
+		diags hhcl.Diagnostics
+	)
+	if filepath.Ext(filename) == ".json" {
+		file, diags = parser.ParseJSONFile(filename)
+	} else {
+		file, diags = parser.ParseHCLFile(filename)
+	}
+	if diags.HasErrors() {
+		return nil, errors.E(diags, "parsing globals file %s", filename)
+	}
+
+	attrs, diags := file.Body.JustAttributes()
+	if diags.HasErrors() {
+		return nil, errors.E(diags, "globals file %s must only have attributes", filename)
+	}
+
+	names := make([]string, 0, len(attrs))
+	for name := range attrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	overrides := make([]config.GlobalOverride, 0, len(names))
+	for _, name := range names {
+		attr := attrs[name]
+		overrides = append(overrides, config.GlobalOverride{
+			Path:   []string{name},
+			Expr:   attr.Expr,
+			Origin: info.NewRange(rootdir, attr.Range),
+		})
+	}
+	return overrides, nil
+}
+
+// parseGlobalOverridePath parses the name of a --global flag, eg.: a.b.c
+func parseGlobalOverridePath(name string) ([]string, error) {
+	path := strings.Split(name, ".")
+	if len(path) > prj.MaxGlobalLabels {
+		return nil, errors.E("--global %s has more than %d path elements",
+			name, prj.MaxGlobalLabels)
+	}
+	if !hclsyntax.ValidIdentifier(path[0]) {
+		return nil, errors.E("--global %s must start with a valid identifier", name)
+	}
+	for _, elem := range path[1:] {
+		if elem == "" {
+			return nil, errors.E("--global %s has an empty path element", name)
+		}
+	}
+	return path, nil
+}
@@ -0,0 +1,280 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+// Package lineedit provides a minimal line editor for interactive terminals,
+// with cursor movement, history navigation and tab completion.
+package lineedit
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// Key codes handled by the editor.
+const (
+	keyCtrlA     = 1
+	keyCtrlB     = 2
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlF     = 6
+	keyBackspace = 8
+	keyTab       = 9
+	keyLF        = 10
+	keyCtrlK     = 11
+	keyCtrlL     = 12
+	keyCR        = 13
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlU     = 21
+	keyCtrlW     = 23
+	keyEscape    = 27
+	keyDelete    = 127
+)
+
+// Editor reads lines from a terminal in raw mode (see [MakeRaw]), echoing the
+// input and handling the editing keys.
+type Editor struct {
+	in  *bufio.Reader
+	out io.Writer
+
+	// Prompt is written before each line.
+	Prompt string
+
+	// History is the list of previous lines, from the oldest to the newest,
+	// navigated with the up and down arrows.
+	History []string
+
+	// Complete returns the completions of the word before the cursor. The
+	// completions replace the word.
+	Complete func(word string) []string
+
+	line []rune
+	pos  int
+}
+
+// New creates a line editor reading from in and echoing to out.
+func New(in io.Reader, out io.Writer) *Editor {
+	return &Editor{
+		in:  bufio.NewReader(in),
+		out: out,
+	}
+}
+
+// ReadLine reads a line. It returns io.EOF if the input ends, or Ctrl-D is
+// pressed, on an empty line. Ctrl-C discards the current line.
+func (e *Editor) ReadLine() (string, error) {
+	e.line = nil
+	e.pos = 0
+	histpos := len(e.History)
+	var edited []rune
+
+	e.write(e.Prompt)
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			if err == io.EOF && len(e.line) > 0 {
+				e.write("\r\n")
+				return string(e.line), nil
+			}
+			return "", err
+		}
+
+		switch r {
+		case keyCR, keyLF:
+			e.write("\r\n")
+			return string(e.line), nil
+		case keyCtrlC:
+			e.write("^C\r\n" + e.Prompt)
+			e.line = nil
+			e.pos = 0
+			histpos = len(e.History)
+			continue
+		case keyCtrlD:
+			if len(e.line) == 0 {
+				e.write("\r\n")
+				return "", io.EOF
+			}
+			e.deleteAt(e.pos)
+		case keyBackspace, keyDelete:
+			if e.pos > 0 {
+				e.pos--
+				e.deleteAt(e.pos)
+			}
+		case keyCtrlA:
+			e.pos = 0
+		case keyCtrlE:
+			e.pos = len(e.line)
+		case keyCtrlB:
+			e.moveLeft()
+		case keyCtrlF:
+			e.moveRight()
+		case keyCtrlK:
+			e.line = e.line[:e.pos]
+		case keyCtrlU:
+			e.line = append([]rune(nil), e.line[e.pos:]...)
+			e.pos = 0
+		case keyCtrlW:
+			start := e.pos
+			for start > 0 && e.line[start-1] == ' ' {
+				start--
+			}
+			for start > 0 && e.line[start-1] != ' ' {
+				start--
+			}
+			e.line = append(e.line[:start], e.line[e.pos:]...)
+			e.pos = start
+		case keyCtrlL:
+			e.write("\x1b[H\x1b[2J")
+		case keyTab:
+			e.complete()
+		case keyCtrlP, keyCtrlN:
+			histpos, edited = e.navigateHistory(histpos, edited, r == keyCtrlP)
+		case keyEscape:
+			switch e.readEscape() {
+			case "A":
+				histpos, edited = e.navigateHistory(histpos, edited, true)
+			case "B":
+				histpos, edited = e.navigateHistory(histpos, edited, false)
+			case "C":
+				e.moveRight()
+			case "D":
+				e.moveLeft()
+			case "H", "1~", "7~":
+				e.pos = 0
+			case "F", "4~", "8~":
+				e.pos = len(e.line)
+			case "3~":
+				e.deleteAt(e.pos)
+			}
+		default:
+			if !unicode.IsPrint(r) {
+				continue
+			}
+			e.insert([]rune{r})
+		}
+		e.refresh()
+	}
+}
+
+// readEscape reads the rest of an escape sequence and returns it without
+// the "ESC [" or "ESC O" prefix.
+func (e *Editor) readEscape() string {
+	r, _, err := e.in.ReadRune()
+	if err != nil || (r != '[' && r != 'O') {
+		return ""
+	}
+	var seq strings.Builder
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return ""
+		}
+		seq.WriteRune(r)
+		if !unicode.IsDigit(r) && r != ';' {
+			return seq.String()
+		}
+	}
+}
+
+// navigateHistory replaces the line by the previous (up) or next history
+// entry. The line being edited before navigating is kept in edited, so it
+// can be restored after the newest entry.
+func (e *Editor) navigateHistory(histpos int, edited []rune, up bool) (int, []rune) {
+	if histpos == len(e.History) {
+		edited = append([]rune(nil), e.line...)
+	}
+	switch {
+	case up && histpos > 0:
+		histpos--
+	case !up && histpos < len(e.History):
+		histpos++
+	default:
+		return histpos, edited
+	}
+	if histpos == len(e.History) {
+		e.line = append([]rune(nil), edited...)
+	} else {
+		e.line = []rune(e.History[histpos])
+	}
+	e.pos = len(e.line)
+	return histpos, edited
+}
+
+// complete replaces the word before the cursor by its completion or, if there
+// are multiple completions, by their common prefix. If the word can't be
+// extended, all completions are listed.
+func (e *Editor) complete() {
+	if e.Complete == nil {
+		return
+	}
+	start := e.pos
+	for start > 0 && isWordRune(e.line[start-1]) {
+		start--
+	}
+	word := string(e.line[start:e.pos])
+	completions := e.Complete(word)
+	if len(completions) == 0 {
+		e.write("\a")
+		return
+	}
+
+	prefix := completions[0]
+	for _, c := range completions[1:] {
+		for !strings.HasPrefix(c, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	if len(completions) == 1 || len(prefix) > len(word) {
+		rest := append([]rune(prefix), e.line[e.pos:]...)
+		e.line = append(e.line[:start], rest...)
+		e.pos = start + len([]rune(prefix))
+		return
+	}
+
+	e.write("\r\n" + strings.Join(completions, "  ") + "\r\n")
+}
+
+func (e *Editor) insert(runes []rune) {
+	rest := append(runes, e.line[e.pos:]...)
+	e.line = append(e.line[:e.pos], rest...)
+	e.pos += len(runes)
+}
+
+func (e *Editor) deleteAt(pos int) {
+	if pos < len(e.line) {
+		e.line = append(e.line[:pos], e.line[pos+1:]...)
+	}
+}
+
+func (e *Editor) moveLeft() {
+	if e.pos > 0 {
+		e.pos--
+	}
+}
+
+func (e *Editor) moveRight() {
+	if e.pos < len(e.line) {
+		e.pos++
+	}
+}
+
+// refresh redraws the prompt and the line, placing the cursor at its
+// position.
+func (e *Editor) refresh() {
+	e.write("\r" + e.Prompt + string(e.line) + "\x1b[K")
+	if back := len(e.line) - e.pos; back > 0 {
+		e.write(fmt.Sprintf("\x1b[%dD", back))
+	}
+}
+
+func (e *Editor) write(s string) {
+	_, _ = io.WriteString(e.out, s)
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.' || r == '-'
+}
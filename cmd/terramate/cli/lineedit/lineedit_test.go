@@ -0,0 +1,218 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package lineedit_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/cmd/terramate/cli/lineedit"
+)
+
+const (
+	up    = "\x1b[A"
+	down  = "\x1b[B"
+	right = "\x1b[C"
+	left  = "\x1b[D"
+	home  = "\x1b[H"
+	del   = "\x1b[3~"
+)
+
+func TestEditorReadLine(t *testing.T) {
+	t.Parallel()
+
+	type testcase struct {
+		name    string
+		input   string
+		history []string
+		want    []string
+	}
+
+	for _, tc := range []testcase{
+		{
+			name:  "plain lines",
+			input: "1+1\r\"a\"\n",
+			want:  []string{"1+1", `"a"`},
+		},
+		{
+			name:  "last line without newline",
+			input: "global.a",
+			want:  []string{"global.a"},
+		},
+		{
+			name:  "backspace",
+			input: "global.ab\x7f\r",
+			want:  []string{"global.a"},
+		},
+		{
+			name:  "insert and delete in the middle",
+			input: "gobal" + left + left + left + left + "l" + home + del + "G\r",
+			want:  []string{"Global"},
+		},
+		{
+			name:  "move to end and beginning",
+			input: "bc\x01a\x05d" + right + "\r",
+			want:  []string{"abcd"},
+		},
+		{
+			name:  "kill to end and to beginning",
+			input: "abc" + left + "\x0b\r" + "abc" + left + "\x15\r",
+			want:  []string{"ab", "c"},
+		},
+		{
+			name:  "delete word",
+			input: "a bc de\x17\r",
+			want:  []string{"a bc "},
+		},
+		{
+			name:    "history navigation",
+			input:   up + up + up + "\r" + "x" + up + down + "\r",
+			history: []string{"first", "second"},
+			want:    []string{"first", "x"},
+		},
+		{
+			name:    "history navigation with ctrl keys",
+			input:   "\x10\x10\x0e\r",
+			history: []string{"first", "second"},
+			want:    []string{"second"},
+		},
+		{
+			name:  "ctrl-c discards the line",
+			input: "abc\x03def\r",
+			want:  []string{"def"},
+		},
+		{
+			name:  "ctrl-d on empty line ends input",
+			input: "a\r\x04b\r",
+			want:  []string{"a"},
+		},
+		{
+			name:  "ctrl-d deletes the char under cursor",
+			input: "ab" + left + "\x04\r",
+			want:  []string{"a"},
+		},
+		{
+			name:  "non printable chars are ignored",
+			input: "a\x07\x1b[Zb\r",
+			want:  []string{"ab"},
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var out bytes.Buffer
+			editor := lineedit.New(strings.NewReader(tc.input), &out)
+			editor.History = tc.history
+			got := readAll(t, editor)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Fatalf("-(want) +(got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestEditorComplete(t *testing.T) {
+	t.Parallel()
+
+	words := []string{"global", "global.name", "global.network", "terramate"}
+	complete := func(word string) []string {
+		var res []string
+		for _, w := range words {
+			if strings.HasPrefix(w, word) {
+				res = append(res, w)
+			}
+		}
+		return res
+	}
+
+	type testcase struct {
+		name     string
+		input    string
+		want     []string
+		wantEcho string
+	}
+
+	for _, tc := range []testcase{
+		{
+			name:  "single completion",
+			input: "tm_upper(ter\t)\r",
+			want:  []string{"tm_upper(terramate)"},
+		},
+		{
+			name:  "common prefix of completions",
+			input: "global.n\tet\t\r",
+			want:  []string{"global.network"},
+		},
+		{
+			name:     "lists ambiguous completions",
+			input:    "global.n\t\r",
+			want:     []string{"global.n"},
+			wantEcho: "\r\nglobal.name  global.network\r\n",
+		},
+		{
+			name:  "completes the word before the cursor",
+			input: "glo + 1" + left + left + left + left + "\t\r",
+			want:  []string{"global + 1"},
+		},
+		{
+			name:     "no completions",
+			input:    "unknown\t\r",
+			want:     []string{"unknown"},
+			wantEcho: "\a",
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var out bytes.Buffer
+			editor := lineedit.New(strings.NewReader(tc.input), &out)
+			editor.Complete = complete
+			got := readAll(t, editor)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Fatalf("-(want) +(got):\n%s", diff)
+			}
+			if tc.wantEcho != "" && !strings.Contains(out.String(), tc.wantEcho) {
+				t.Fatalf("output %q does not contain %q", out.String(), tc.wantEcho)
+			}
+		})
+	}
+}
+
+func TestEditorEchoesPrompt(t *testing.T) {
+	t.Parallel()
+
+	var out bytes.Buffer
+	editor := lineedit.New(strings.NewReader("ab"+left+"\r"), &out)
+	editor.Prompt = "/> "
+	line, err := editor.ReadLine()
+	assert.NoError(t, err)
+	assert.EqualStrings(t, "ab", line)
+	assert.EqualStrings(t,
+		"/> "+
+			"\r/> a\x1b[K"+
+			"\r/> ab\x1b[K"+
+			"\r/> ab\x1b[K\x1b[1D"+
+			"\r\n",
+		out.String())
+}
+
+func readAll(t *testing.T, editor *lineedit.Editor) []string {
+	t.Helper()
+
+	var lines []string
+	for {
+		line, err := editor.ReadLine()
+		if err == io.EOF {
+			return lines
+		}
+		assert.NoError(t, err)
+		lines = append(lines, line)
+	}
+}
@@ -0,0 +1,14 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+
+package lineedit
+
+import "github.com/terramate-io/terramate/errors"
+
+// MakeRaw is not supported on this platform and always fails, so callers
+// must fall back to reading plain lines.
+func MakeRaw(fd int) (restore func() error, err error) {
+	return nil, errors.E("terminal raw mode is not supported on this platform")
+}
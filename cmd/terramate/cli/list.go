@@ -0,0 +1,250 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package cli
+
+import (
+	"bytes"
+	"encoding/csv"
+	stdjson "encoding/json"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/terramate-io/terramate/config"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/stack"
+)
+
+// ErrListInvalidField indicates an unknown field was requested on list --fields.
+const ErrListInvalidField errors.Kind = "invalid list field"
+
+const (
+	listFormatText  = "text"
+	listFormatJSON  = "json"
+	listFormatCSV   = "csv"
+	listFormatTable = "table"
+)
+
+// listFields is the ordered set of fields supported by the list structured
+// output formats. The order defines the default output order.
+var listFields = []string{
+	"id",
+	"name",
+	"description",
+	"path",
+	"tags",
+	"after",
+	"before",
+	"wants",
+	"wanted_by",
+	"watch",
+	"state",
+	"changed",
+	"reason",
+}
+
+// listFieldValue returns the value of the field for the stack entry. The
+// changed and reason fields are nil if change detection was not done, since
+// the stacks were not checked for changes.
+func listFieldValue(entry stack.Entry, field string, changeDetection bool) any {
+	st := entry.Stack
+	switch field {
+	case "id":
+		return st.ID
+	case "name":
+		return st.Name
+	case "description":
+		return st.Description
+	case "path":
+		return st.Dir.String()
+	case "tags":
+		return nonNilList(st.Tags)
+	case "after":
+		return nonNilList(st.After)
+	case "before":
+		return nonNilList(st.Before)
+	case "wants":
+		return nonNilList(st.Wants)
+	case "wanted_by":
+		return nonNilList(st.WantedBy)
+	case "watch":
+		watch := []string{}
+		for _, p := range st.Watch {
+			watch = append(watch, p.String())
+		}
+		return watch
+	case "state":
+		return stackState(st)
+	case "changed":
+		if !changeDetection {
+			return nil
+		}
+		return st.IsChanged
+	case "reason":
+		if !changeDetection {
+			return nil
+		}
+		return entry.Reason
+	}
+	panic(errors.E(errors.ErrInternal, "unknown list field %q", field))
+}
+
+// stackState returns the orchestration state of the stack: "disabled",
+// "manual" or "enabled".
+func stackState(st *config.Stack) string {
+	switch {
+	case st.Disabled:
+		return "disabled"
+	case st.Manual:
+		return "manual"
+	default:
+		return "enabled"
+	}
+}
+
+func nonNilList(lst []string) []string {
+	if lst == nil {
+		return []string{}
+	}
+	return lst
+}
+
+func (c *cli) listSelectedFields() ([]string, error) {
+	if len(c.parsedArgs.List.Fields) == 0 {
+		return listFields, nil
+	}
+	known := map[string]struct{}{}
+	for _, f := range listFields {
+		known[f] = struct{}{}
+	}
+	for _, f := range c.parsedArgs.List.Fields {
+		if _, ok := known[f]; !ok {
+			return nil, errors.E(ErrListInvalidField,
+				"unknown field %q, supported fields are: %s",
+				f, strings.Join(listFields, ","))
+		}
+	}
+	return c.parsedArgs.List.Fields, nil
+}
+
+func (c *cli) printStacksStructured(entries []stack.Entry) {
+	fields, err := c.listSelectedFields()
+	if err != nil {
+		fatal(err)
+	}
+
+	changeDetection := c.parsedArgs.Changed
+
+	var out []byte
+	switch c.parsedArgs.List.Format {
+	case listFormatJSON:
+		out, err = formatListJSON(entries, fields, changeDetection)
+	case listFormatCSV:
+		out, err = formatListCSV(entries, fields, changeDetection)
+	case listFormatTable:
+		out, err = formatListTable(entries, fields, changeDetection)
+	default:
+		panic(errors.E(errors.ErrInternal, "unexpected list format %q", c.parsedArgs.List.Format))
+	}
+	if err != nil {
+		fatal(err, "formatting list output")
+	}
+	c.output.MsgStdOut(strings.TrimSuffix(string(out), "\n"))
+}
+
+// listJSONObject is a JSON object whose keys are encoded in the order of the
+// selected fields, instead of the sorted order of encoded maps.
+type listJSONObject struct {
+	fields []string
+	values []any
+}
+
+// MarshalJSON encodes the object keeping the order of its fields.
+func (obj listJSONObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, field := range obj.fields {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := stdjson.Marshal(field)
+		if err != nil {
+			return nil, err
+		}
+		val, err := stdjson.Marshal(obj.values[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(val)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func formatListJSON(entries []stack.Entry, fields []string, changeDetection bool) ([]byte, error) {
+	objs := []listJSONObject{}
+	for _, entry := range entries {
+		obj := listJSONObject{fields: fields}
+		for _, field := range fields {
+			obj.values = append(obj.values, listFieldValue(entry, field, changeDetection))
+		}
+		objs = append(objs, obj)
+	}
+	return stdjson.MarshalIndent(objs, "", "  ")
+}
+
+func formatListCSV(entries []stack.Entry, fields []string, changeDetection bool) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(fields); err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if err := w.Write(listRecord(entry, fields, changeDetection)); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+func formatListTable(entries []stack.Entry, fields []string, changeDetection bool) ([]byte, error) {
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	header := make([]string, len(fields))
+	for i, f := range fields {
+		header[i] = strings.ToUpper(f)
+	}
+	if _, err := w.Write([]byte(strings.Join(header, "\t") + "\n")); err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if _, err := w.Write([]byte(strings.Join(listRecord(entry, fields, changeDetection), "\t") + "\n")); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// listRecord returns the values of the fields as strings. Nil values are
+// empty strings.
+func listRecord(entry stack.Entry, fields []string, changeDetection bool) []string {
+	record := make([]string, len(fields))
+	for i, field := range fields {
+		switch v := listFieldValue(entry, field, changeDetection).(type) {
+		case string:
+			record[i] = v
+		case bool:
+			record[i] = strconv.FormatBool(v)
+		case []string:
+			record[i] = strings.Join(v, ",")
+		}
+	}
+	return record
+}
@@ -90,6 +90,6 @@ generate_hcl "test2.hcl" {
 	genHCL := string(clonedStackEntry.ReadFile("test.hcl"))
 	genHCL2 := string(clonedStackEntry.ReadFile("test2.hcl"))
 
-	test.AssertGenCodeEquals(t, genHCL, `a = "literal"`)
+	test.AssertGenCodeEquals(t, genHCL, "// Commenting literal\na = \"literal\"")
 	test.AssertGenCodeEquals(t, genHCL2, `b = null`)
 }
@@ -0,0 +1,290 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package e2etest
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/test"
+	"github.com/terramate-io/terramate/test/hclwrite"
+	. "github.com/terramate-io/terramate/test/hclwrite/hclutils"
+	"github.com/terramate-io/terramate/test/sandbox"
+)
+
+func TestExpConsole(t *testing.T) {
+	t.Parallel()
+
+	type (
+		globalsBlock struct {
+			path string
+			add  *hclwrite.Block
+		}
+		testcase struct {
+			name    string
+			layout  []string
+			wd      string
+			globals []globalsBlock
+			args    []string
+			env     []string
+			input   []string
+			want    runExpected
+		}
+	)
+
+	testcases := []testcase{
+		{
+			name:  "evaluate expressions",
+			input: []string{`tm_upper("a")`, `[1, 1+1]`},
+			want: runExpected{
+				Stdout: addnl("A") + addnl("[1, 2]"),
+			},
+		},
+		{
+			name:   "stack globals and metadata",
+			layout: []string{"s:stacks/stack"},
+			wd:     "stacks/stack",
+			globals: []globalsBlock{
+				{
+					path: "/",
+					add: Globals(
+						Str("env", "dev"),
+					),
+				},
+				{
+					path: "/stacks/stack",
+					add: Globals(
+						Expr("name", `"${global.env}-${terramate.stack.path.basename}"`),
+					),
+				},
+			},
+			input: []string{"global.name", "terramate.stack.path.absolute"},
+			want: runExpected{
+				Stdout: addnl("dev-stack") + addnl("/stacks/stack"),
+			},
+		},
+		{
+			name:   "stack given as argument",
+			layout: []string{"s:stacks/stack"},
+			globals: []globalsBlock{
+				{
+					path: "/stacks/stack",
+					add: Globals(
+						Str("name", "stack"),
+					),
+				},
+			},
+			args:  []string{"stacks/stack"},
+			input: []string{":stack", "global.name"},
+			want: runExpected{
+				Stdout: addnl("/stacks/stack") + addnl("stack"),
+			},
+		},
+		{
+			name: "globals overrides",
+			globals: []globalsBlock{
+				{
+					path: "/",
+					add: Globals(
+						Str("env", "dev"),
+					),
+				},
+			},
+			args:  []string{"--global", `env="prod"`},
+			input: []string{"global.env"},
+			want: runExpected{
+				Stdout: addnl("prod"),
+			},
+		},
+		{
+			name:  "list namespaces",
+			input: []string{":ns"},
+			want: runExpected{
+				Stdout: addnl("env") + addnl("global") + addnl("terramate"),
+			},
+		},
+		{
+			name: "inspect namespace",
+			globals: []globalsBlock{
+				{
+					path: "/",
+					add: Globals(
+						Number("a", 1),
+						Expr("secret", `tm_sensitive("b")`),
+					),
+				},
+			},
+			input: []string{":ns global", ":ns unknown"},
+			want: runExpected{
+				Stdout: addnl(`{
+  a      = 1
+  secret = "(sensitive)"
+}`),
+				Stderr: addnl("Error: namespace unknown not found"),
+			},
+		},
+		{
+			name:  "env namespace is redacted",
+			env:   []string{"TM_CONSOLE_TEST_SECRET=hunter2"},
+			input: []string{":ns env", "env.TM_CONSOLE_TEST_SECRET"},
+			want: runExpected{
+				StdoutRegex: `(?s)TM_CONSOLE_TEST_SECRET\s+= "\(sensitive\)".*\nhunter2\n$`,
+			},
+		},
+		{
+			name: "complete global paths",
+			globals: []globalsBlock{
+				{
+					path: "/",
+					add: Globals(
+						Expr("network", `{ cidr = "10.0.0.0/16", zones = ["a"] }`),
+						Str("name", "test"),
+						Str("env", "dev"),
+					),
+				},
+			},
+			input: []string{
+				":complete glo",
+				":complete global.n",
+				":complete global.network.",
+				":complete global.env.",
+			},
+			want: runExpected{
+				Stdout: addnl("global") +
+					addnl("global.name") +
+					addnl("global.network") +
+					addnl("global.network.cidr") +
+					addnl("global.network.zones"),
+			},
+		},
+		{
+			name: "switch stacks",
+			layout: []string{
+				"s:stacks/stack-1",
+				"s:stacks/stack-2",
+			},
+			wd: "stacks/stack-1",
+			input: []string{
+				"terramate.stack.name",
+				":stack ../stack-2",
+				"terramate.stack.name",
+				":stack /stacks/stack-1",
+				":stack",
+				":stack /unknown",
+				":stack",
+				":stacks",
+			},
+			want: runExpected{
+				Stdout: addnl("stack-1") +
+					addnl("stack-2") +
+					addnl("/stacks/stack-1") +
+					addnl("/stacks/stack-1") +
+					addnl("/stacks/stack-1") +
+					addnl("/stacks/stack-2"),
+				Stderr: addnl("Error: configuration at /unknown not found"),
+			},
+		},
+		{
+			name: "history",
+			input: []string{
+				"1+1",
+				`"a"`,
+				":history",
+				"!1",
+				"!10",
+			},
+			want: runExpected{
+				Stdout: addnl("2") +
+					addnl("a") +
+					addnl("    1  1+1") +
+					addnl(`    2  "a"`) +
+					addnl("    3  :history") +
+					addnl("1+1") +
+					addnl("2"),
+				Stderr: addnl(`Error: history entry "10" not found`),
+			},
+		},
+		{
+			name:  "reload configuration",
+			input: []string{":reload", "true"},
+			want: runExpected{
+				Stdout: addnl("true"),
+			},
+		},
+		{
+			name:  "quit",
+			input: []string{"1", ":quit", "2"},
+			want: runExpected{
+				Stdout: addnl("1"),
+			},
+		},
+		{
+			name:  "errors do not stop the console",
+			input: []string{"global.undefined", ":unknown", "1"},
+			want: runExpected{
+				Stdout:      addnl("1"),
+				StderrRegex: "(?s)Error: .*undefined.*Error: unknown command :unknown",
+			},
+		},
+		{
+			name: "globals that fail to evaluate are reported",
+			globals: []globalsBlock{
+				{
+					path: "/",
+					add: Globals(
+						Expr("a", "global.undefined"),
+						Str("b", "b"),
+					),
+				},
+			},
+			input: []string{"global.b"},
+			want: runExpected{
+				Stdout:      addnl("b"),
+				StderrRegex: "Warning: globals that failed to evaluate are not available",
+			},
+		},
+		{
+			name:  "stack outside the project fails",
+			args:  []string{".."},
+			input: []string{"1"},
+			want: runExpected{
+				Status:      1,
+				StderrRegex: "outside the project",
+			},
+		},
+	}
+
+	for _, tcase := range testcases {
+		tc := tcase
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := sandbox.New(t)
+			s.BuildTree(tc.layout)
+			for _, globalBlock := range tc.globals {
+				path := filepath.Join(s.RootDir(), globalBlock.path)
+				test.AppendFile(t, path, "globals.tm",
+					globalBlock.add.String())
+			}
+
+			test.WriteRootConfig(t, s.RootDir())
+			tm := newCLI(t, filepath.Join(s.RootDir(), tc.wd))
+			tm.appendEnv = tc.env
+			args := append([]string{"experimental", "console"}, tc.args...)
+			cmd := tm.newCmd(args...)
+			_, err := cmd.stdin.Write([]byte(strings.Join(tc.input, "\n") + "\n"))
+			assert.NoError(t, err)
+			_ = cmd.run()
+
+			assertRunResult(t, runResult{
+				Cmd:    strings.Join(args, " "),
+				Stdout: cmd.stdout.String(),
+				Stderr: cmd.stderr.String(),
+				Status: cmd.exitCode(),
+			}, tc.want)
+		})
+	}
+}
@@ -259,3 +259,157 @@ stack "/stacks/stack-name":
 		})
 	}
 }
+
+func TestStacksGlobalsExplain(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:stack`,
+		`f:globals.tm:globals {
+  a = "root"
+  b = 1
+}
+`,
+		`f:stack/globals.tm:globals {
+  a = "child-${global.b}"
+}
+
+globals "obj" {
+  x = [global.a, global.b]
+}
+`,
+	})
+
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.run("experimental", "globals", "--explain"), runExpected{
+		Stdout: `
+stack "/stack":
+	global.a = "child-1"
+		defined at /stack/globals.tm:2,3-26
+			references global.b
+		overrides /globals.tm:2,3-13
+	global.b = 1
+		defined at /globals.tm:3,3-8
+	global.obj.x = ["child-1", 1]
+		defined at /stack/globals.tm:6,3-27
+			references global.a, global.b
+`,
+	})
+	assertRunResult(t, cli.run("experimental", "globals", "--explain", "global.b"), runExpected{
+		Stdout: `
+stack "/stack":
+	global.b = 1
+		defined at /globals.tm:3,3-8
+`,
+	})
+	assertRunResult(t, cli.run("experimental", "globals", "--explain", "--as-json", "global.obj"), runExpected{
+		Stdout: `[
+  {
+    "stack": "/stack",
+    "globals": [
+      {
+        "name": "global.obj.x",
+        "value": [
+          "child-1",
+          1
+        ],
+        "unset": false,
+        "definitions": [
+          {
+            "defined_at": "/stack/globals.tm:6,3-27",
+            "config_dir": "/stack",
+            "references": [
+              "global.a",
+              "global.b"
+            ]
+          }
+        ]
+      }
+    ]
+  }
+]
+`,
+	})
+	assertRunResult(t, cli.run("experimental", "globals", "global.a"), runExpected{
+		Status:      1,
+		StderrRegex: "requires the --explain flag",
+	})
+	assertRunResult(t, cli.run("experimental", "globals", "--explain", "a.b"), runExpected{
+		Status:      1,
+		StderrRegex: "invalid global",
+	})
+}
+
+func TestStacksGlobalsSensitiveAreRedacted(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:stack`,
+		`f:globals.tm:globals_schema "token" {
+  sensitive = true
+}
+
+globals {
+  token = "secret-token"
+  user  = "admin"
+  creds = {
+    user     = global.user
+    password = tm_sensitive("secret-password")
+  }
+}
+
+terramate {
+  config {
+    run {
+      env {
+        TOKEN = global.token
+        USER  = global.user
+      }
+    }
+  }
+}
+`,
+	})
+	s.Git().CommitAll("first commit")
+
+	cli := newCLI(t, filepath.Join(s.RootDir(), "stack"))
+	assertRunResult(t, cli.run("experimental", "globals"), runExpected{
+		Stdout: `
+stack "/stack":
+	creds = {
+	  password = "(sensitive)"
+	  user     = "admin"
+	}
+	token = "(sensitive)"
+	user  = "admin"
+`,
+	})
+	assertRunResult(t, cli.run("experimental", "globals", "--explain", "global.token"), runExpected{
+		Stdout: `
+stack "/stack":
+	global.token = "(sensitive)"
+		defined at /globals.tm:6,3-25
+`,
+	})
+	assertRunResult(t, cli.run("experimental", "eval", "global.creds"), runExpected{
+		Stdout: addnl(`{
+  password = "(sensitive)"
+  user     = "admin"
+}`),
+	})
+	assertRunResult(t, cli.run("experimental", "partial-eval", `"${global.user}:${global.token}"`), runExpected{
+		Stdout: addnl(`"admin:(sensitive)"`),
+	})
+	assertRunResult(t, cli.run("experimental", "get-config-value", "global.token"), runExpected{
+		Stdout: addnl(`(sensitive)`),
+	})
+	assertRunResult(t, cli.run("experimental", "run-env"), runExpected{
+		Stdout: `
+stack "/stack":
+	TOKEN=(sensitive)
+	USER=admin
+`,
+	})
+}
@@ -0,0 +1,72 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package e2etest
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/terramate-io/terramate/test/sandbox"
+)
+
+func TestExpStackDeps(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:network:id=network`,
+		`s:database:after=["/network"]`,
+		`s:app:after=["/database"];wants=["/monitoring"]`,
+		`s:monitoring`,
+	})
+
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.run("experimental", "stack", "deps", "app"), runExpected{
+		Stdout: listStacks("/database", "/monitoring"),
+	})
+	assertRunResult(t, cli.run("experimental", "stack", "deps", "--transitive", "/app"), runExpected{
+		Stdout: listStacks("/database", "/monitoring", "/network"),
+	})
+	assertRunResult(t, cli.run("experimental", "stack", "rdeps", "network"), runExpected{
+		Stdout: listStacks("/database"),
+	})
+	assertRunResult(t, cli.run("experimental", "stack", "rdeps", "--transitive", "network"), runExpected{
+		Stdout: listStacks("/app", "/database"),
+	})
+	assertRunResult(t, cli.run("experimental", "stack", "rdeps", "--as-json", "network"), runExpected{
+		Stdout: `[
+  {
+    "path": "/database",
+    "id": "",
+    "name": "database",
+    "kinds": [
+      "after"
+    ],
+    "direct": true
+  }
+]
+`,
+	})
+	assertRunResult(t, cli.run("experimental", "stack", "deps", "--as-json", "network"), runExpected{
+		Stdout: "[]\n",
+	})
+
+	subdirCli := newCLI(t, filepath.Join(s.RootDir(), "app"))
+	assertRunResult(t, subdirCli.run("experimental", "stack", "rdeps", "../monitoring"), runExpected{
+		Stdout: listStacks("/app"),
+	})
+}
+
+func TestExpStackDepsNotFound(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{`s:stack`})
+
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.run("experimental", "stack", "deps", "not-found"), runExpected{
+		Status:      1,
+		StderrRegex: "stack not found",
+	})
+}
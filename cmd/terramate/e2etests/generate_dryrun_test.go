@@ -0,0 +1,84 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package e2etest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/test/sandbox"
+
+	. "github.com/terramate-io/terramate/test/hclwrite/hclutils"
+)
+
+func TestGenerateDryRun(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		"s:stack",
+	})
+	stack := s.DirEntry("stack")
+	stack.CreateFile("config.tm", GenerateFile(
+		Labels("file.txt"),
+		Str("content", "data"),
+	).String())
+
+	cli := newCLI(t, s.RootDir())
+
+	const createdReport = "Code generation report\n\n" +
+		"Successes:\n\n" +
+		"- /stack\n" +
+		"\t[+] file.txt\n\n" +
+		"Hint: '+', '~' and '-' means the file was created, changed and deleted, respectively.\n"
+
+	const createdDiff = "--- /dev/null\n" +
+		"+++ b/stack/file.txt\n" +
+		"@@ -0,0 +1 @@\n" +
+		"+data\n"
+
+	assertRunResult(t, cli.run("generate", "--dry-run"), runExpected{
+		Stdout: createdReport + "\n" + createdDiff,
+	})
+
+	assertRunResult(t, cli.run("generate", "--check"), runExpected{
+		Stdout: createdReport + "\n" + createdDiff,
+		Status: 1,
+	})
+
+	_, err := os.Stat(filepath.Join(stack.Path(), "file.txt"))
+	assert.IsTrue(t, os.IsNotExist(err), "dry run must not create files: %v", err)
+
+	assertRunResult(t, cli.run("generate"), runExpected{
+		Stdout: createdReport,
+	})
+
+	assertRunResult(t, cli.run("generate", "--check"), runExpected{
+		Stdout: "Nothing to do, generated code is up to date\n",
+	})
+
+	stack.CreateFile("config.tm", GenerateFile(
+		Labels("file.txt"),
+		Str("content", "changed"),
+	).String())
+
+	assertRunResult(t, cli.run("generate", "--check"), runExpected{
+		Stdout: "Code generation report\n\n" +
+			"Successes:\n\n" +
+			"- /stack\n" +
+			"\t[~] file.txt\n\n" +
+			"Hint: '+', '~' and '-' means the file was created, changed and deleted, respectively.\n" +
+			"\n" +
+			"--- a/stack/file.txt\n" +
+			"+++ b/stack/file.txt\n" +
+			"@@ -1 +1 @@\n" +
+			"-data\n" +
+			"+changed\n",
+		Status: 1,
+	})
+
+	assert.EqualStrings(t, "data", string(stack.ReadFile("file.txt")))
+}
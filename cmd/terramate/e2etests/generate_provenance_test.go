@@ -0,0 +1,74 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package e2etest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/generate"
+	"github.com/terramate-io/terramate/test/sandbox"
+
+	. "github.com/terramate-io/terramate/test/hclwrite/hclutils"
+)
+
+func TestGenerateProvenanceForce(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		"s:stack",
+	})
+	s.RootEntry().CreateFile("terramate.tm", Terramate(
+		Config(
+			Block("generate",
+				Bool("provenance", true),
+			),
+		),
+	).String())
+
+	stack := s.DirEntry("stack")
+	stack.CreateFile("config.tm", GenerateHCL(
+		Labels("file.hcl"),
+		Content(
+			Str("a", "b"),
+		),
+	).String())
+
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.run("generate"), runExpected{
+		IgnoreStdout: true,
+	})
+
+	generated := string(stack.ReadFile("file.hcl"))
+	assert.IsTrue(t, strings.Contains(generated,
+		"// TERRAMATE: originated from generate_hcl block on /stack/config.tm:1\n"),
+		"missing provenance origin: %s", generated)
+
+	stack.CreateFile("file.hcl", generated+"manual = true\n")
+	stack.CreateFile("config.tm", GenerateHCL(
+		Labels("file.hcl"),
+		Content(
+			Str("a", "changed"),
+		),
+	).String())
+
+	assertRunResult(t, cli.run("generate"), runExpected{
+		StdoutRegex: string(generate.ErrManualEdits),
+		Status:      1,
+	})
+
+	assertRunResult(t, cli.run("generate", "--force"), runExpected{
+		Stdout: "Code generation report\n\n" +
+			"Successes:\n\n" +
+			"- /stack\n" +
+			"\t[~] file.hcl\n\n" +
+			"Hint: '+', '~' and '-' means the file was created, changed and deleted, respectively.\n",
+	})
+
+	assertRunResult(t, cli.run("generate"), runExpected{
+		Stdout: "Nothing to do, generated code is up to date\n",
+	})
+}
@@ -0,0 +1,106 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package e2etest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/test"
+	"github.com/terramate-io/terramate/test/sandbox"
+
+	. "github.com/terramate-io/terramate/test/hclwrite/hclutils"
+)
+
+func TestGenerateStackSelection(t *testing.T) {
+	t.Parallel()
+
+	const hint = "Hint: '+', '~' and '-' means the file was created, changed and deleted, respectively.\n"
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:stacks/a:tags=["prod"]`,
+		"s:stacks/b",
+		"s:other",
+	})
+	s.RootEntry().CreateFile("gen.tm", Doc(
+		GenerateFile(
+			Labels("file.txt"),
+			Expr("content", `"${terramate.stack.path.absolute}"`),
+		),
+		GenerateHCL(
+			Labels("file.tf"),
+			Content(
+				Expr("stack", "terramate.stack.path.absolute"),
+			),
+		),
+		GenerateFile(
+			Labels("/root.txt"),
+			Expr("context", "root"),
+			Expr("content", `"root"`),
+		),
+	).String())
+
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.run("generate", "stacks/a"), runExpected{
+		Stdout: "Code generation report\n\n" +
+			"Successes:\n\n" +
+			"- /stacks/a\n" +
+			"\t[+] file.tf\n" +
+			"\t[+] file.txt\n\n" +
+			hint,
+	})
+
+	stacksCLI := newCLI(t, filepath.Join(s.RootDir(), "stacks"))
+	assertRunResult(t, stacksCLI.run("generate", "b", "../other", "--label", "*.txt"), runExpected{
+		Stdout: "Code generation report\n\n" +
+			"Successes:\n\n" +
+			"- /other\n" +
+			"\t[+] file.txt\n\n" +
+			"- /stacks/b\n" +
+			"\t[+] file.txt\n\n" +
+			hint,
+	})
+
+	test.AssertFileContentEquals(t, filepath.Join(s.RootDir(), "stacks/b/file.txt"), "/stacks/b")
+	test.AssertFileContentEquals(t, filepath.Join(s.RootDir(), "other/file.txt"), "/other")
+	for _, notGenerated := range []string{"stacks/b/file.tf", "other/file.tf", "root.txt"} {
+		_, err := os.Stat(filepath.Join(s.RootDir(), notGenerated))
+		assert.IsTrue(t, os.IsNotExist(err), "file %s must not be generated: %v", notGenerated, err)
+	}
+
+	assertRunResult(t, cli.run("generate", "--tags", "prod"), runExpected{
+		Stdout: "Nothing to do, generated code is up to date\n",
+	})
+
+	assertRunResult(t, cli.run("generate", "--label", "root.txt"), runExpected{
+		Stdout: "Code generation report\n\n" +
+			"Successes:\n\n" +
+			"- /\n" +
+			"\t[+] root.txt\n\n" +
+			hint,
+	})
+
+	assertRunResult(t, cli.run("generate", "--label", "[*"), runExpected{
+		StderrRegex: "invalid --label",
+		Status:      1,
+	})
+
+	assertRunResult(t, cli.run("generate", ".."), runExpected{
+		StderrRegex: "outside the project",
+		Status:      1,
+	})
+
+	assertRunResult(t, stacksCLI.run("generate"), runExpected{
+		Stdout: "Code generation report\n\n" +
+			"Successes:\n\n" +
+			"- /other\n" +
+			"\t[+] file.tf\n\n" +
+			"- /stacks/b\n" +
+			"\t[+] file.tf\n\n" +
+			hint,
+	})
+}
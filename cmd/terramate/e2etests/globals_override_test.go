@@ -0,0 +1,170 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package e2etest
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/test/sandbox"
+)
+
+func TestGlobalsOverride(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:stacks/stack-a`,
+		`s:stacks/stack-b`,
+		`f:globals.tm:globals {
+  region = "us-east-1"
+  env    = "dev"
+  name   = "${global.env}-${global.region}"
+}
+
+generate_file "region.txt" {
+  content = global.name
+}
+
+terramate {
+  config {
+    run {
+      env {
+        REGION = global.region
+      }
+    }
+  }
+}
+`,
+		`f:stacks/stack-b/globals.tm:globals {
+  region = "sa-east-1"
+}
+`,
+		`f:overrides.hcl:env = "prod"
+region = "eu-central-1"
+`,
+		`f:overrides.json:{"env": "stg"}`,
+	})
+
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.run("generate"), runExpected{IgnoreStdout: true})
+
+	stackA := s.DirEntry("stacks/stack-a")
+	stackB := s.DirEntry("stacks/stack-b")
+	assert.EqualStrings(t, "dev-us-east-1", string(stackA.ReadFile("region.txt")))
+	assert.EqualStrings(t, "dev-sa-east-1", string(stackB.ReadFile("region.txt")))
+
+	s.Git().CommitAll("first commit")
+
+	t.Run("overrides have precedence over stack globals", func(t *testing.T) {
+		assertRunResult(t, cli.run("experimental", "globals", "-g", `region="eu-west-1"`), runExpected{
+			Stdout: `
+stack "/stacks/stack-a":
+	env    = "dev"
+	name   = "dev-eu-west-1"
+	region = "eu-west-1"
+
+stack "/stacks/stack-b":
+	env    = "dev"
+	name   = "dev-eu-west-1"
+	region = "eu-west-1"
+`,
+		})
+	})
+
+	t.Run("overrides can reference other globals", func(t *testing.T) {
+		stackCLI := newCLI(t, filepath.Join(s.RootDir(), "stacks", "stack-b"))
+		assertRunResult(t, stackCLI.run("experimental", "globals",
+			"--global", `env=tm_upper(global.region)`,
+		), runExpected{
+			Stdout: `
+stack "/stacks/stack-b":
+	env    = "SA-EAST-1"
+	name   = "SA-EAST-1-sa-east-1"
+	region = "sa-east-1"
+`,
+		})
+	})
+
+	t.Run("globals file", func(t *testing.T) {
+		// the file path is relative to the working dir.
+		stackCLI := newCLI(t, filepath.Join(s.RootDir(), "stacks", "stack-a"))
+		assertRunResult(t, stackCLI.run("experimental", "globals",
+			"--globals-file", "../../overrides.hcl",
+		), runExpected{
+			Stdout: `
+stack "/stacks/stack-a":
+	env    = "prod"
+	name   = "prod-eu-central-1"
+	region = "eu-central-1"
+`,
+		})
+	})
+
+	t.Run("global flags have precedence over globals file", func(t *testing.T) {
+		assertRunResult(t, cli.run("experimental", "eval",
+			"--globals-file", "overrides.hcl",
+			"-g", `region="us-west-2"`,
+			"global.name",
+		), runExpected{
+			Stdout: addnl("prod-us-west-2"),
+		})
+	})
+
+	t.Run("json globals file", func(t *testing.T) {
+		assertRunResult(t, cli.run("experimental", "get-config-value",
+			"--globals-file", "overrides.json",
+			"global.name",
+		), runExpected{
+			Stdout: addnl("stg-us-east-1"),
+		})
+	})
+
+	t.Run("generate with overrides", func(t *testing.T) {
+		defer func() {
+			assertRunResult(t, cli.run("generate"), runExpected{IgnoreStdout: true})
+		}()
+
+		assertRunResult(t, cli.run("generate", "-g", `env="prod"`), runExpected{IgnoreStdout: true})
+		assert.EqualStrings(t, "prod-us-east-1", string(stackA.ReadFile("region.txt")))
+		assert.EqualStrings(t, "prod-sa-east-1", string(stackB.ReadFile("region.txt")))
+
+		// the generated code is outdated when the overrides are not provided.
+		assertRunResult(t, cli.run("generate", "--check"), runExpected{
+			Status:       1,
+			IgnoreStdout: true,
+		})
+		assertRunResult(t, cli.run("generate", "--check", "-g", `env="prod"`), runExpected{
+			IgnoreStdout: true,
+		})
+	})
+
+	t.Run("run with overrides", func(t *testing.T) {
+		stackCLI := newCLI(t, filepath.Join(s.RootDir(), "stacks", "stack-a"))
+		res := stackCLI.run("run", "-g", `region="ap-south-1"`,
+			"--disable-check-gen-code", "--", testHelperBin, "env")
+		assertRunResult(t, res, runExpected{IgnoreStdout: true})
+		assertEnvVar(t, res.Stdout, "REGION", "ap-south-1")
+
+		res = stackCLI.run("run", "--", testHelperBin, "env")
+		assertRunResult(t, res, runExpected{IgnoreStdout: true})
+		assertEnvVar(t, res.Stdout, "REGION", "us-east-1")
+	})
+
+	t.Run("invalid overrides", func(t *testing.T) {
+		assertRunResult(t, cli.run("experimental", "globals", "-g", `1a="x"`), runExpected{
+			Status:      1,
+			StderrRegex: "must start with a valid identifier",
+		})
+		assertRunResult(t, cli.run("experimental", "globals", "-g", `a=[`), runExpected{
+			Status:      1,
+			StderrRegex: "invalid expresssion",
+		})
+		assertRunResult(t, cli.run("experimental", "globals", "--globals-file", "not-found.hcl"), runExpected{
+			Status:      1,
+			StderrRegex: "loading --globals-file",
+		})
+	})
+}
@@ -0,0 +1,160 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package e2etest
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/terramate-io/terramate/test/sandbox"
+)
+
+func TestListFormatJSON(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:stack-a:id=a;name=A;description=descA;tags=["x","y"];wants=["/stack-b"]`,
+		`s:stack-b:after=["/stack-a"]`,
+	})
+
+	cli := newCLI(t, s.RootDir())
+	res := cli.listStacks("--format", "json")
+	assertRunResult(t, res, runExpected{IgnoreStdout: true})
+
+	var got []map[string]any
+	if err := json.Unmarshal([]byte(res.Stdout), &got); err != nil {
+		t.Fatalf("invalid json output: %v: %s", err, res.Stdout)
+	}
+
+	want := []map[string]any{
+		{
+			"id":          "a",
+			"name":        "A",
+			"description": "descA",
+			"path":        "/stack-a",
+			"tags":        []any{"x", "y"},
+			"after":       []any{},
+			"before":      []any{},
+			"wants":       []any{"/stack-b"},
+			"wanted_by":   []any{},
+			"watch":       []any{},
+			"state":       "enabled",
+			"changed":     nil,
+			"reason":      nil,
+		},
+		{
+			"id":          "",
+			"name":        "stack-b",
+			"description": "",
+			"path":        "/stack-b",
+			"tags":        []any{},
+			"after":       []any{"/stack-a"},
+			"before":      []any{},
+			"wants":       []any{},
+			"wanted_by":   []any{},
+			"watch":       []any{},
+			"state":       "enabled",
+			"changed":     nil,
+			"reason":      nil,
+		},
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("-(want) +(got):\n%s", diff)
+	}
+
+	assertRunResult(t, cli.listStacks("--format", "json", "--fields", "path,tags,id"),
+		runExpected{
+			Stdout: `[
+  {
+    "path": "/stack-a",
+    "tags": [
+      "x",
+      "y"
+    ],
+    "id": "a"
+  },
+  {
+    "path": "/stack-b",
+    "tags": [],
+    "id": ""
+  }
+]
+`,
+		})
+}
+
+func TestListFormatChangedFields(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:stack-a:id=a`,
+		`s:stack-b:id=b`,
+	})
+
+	git := s.Git()
+	git.CommitAll("first commit")
+	git.Push("main")
+	git.CheckoutNew("change-stack")
+
+	s.DirEntry("stack-a").CreateFile("main.tf", "# changed")
+	git.CommitAll("stack changed")
+
+	cli := newCLI(t, s.RootDir())
+
+	assertRunResult(t, cli.listChangedStacks("--format", "csv", "--fields", "id,path,changed,reason"),
+		runExpected{
+			Stdout: "id,path,changed,reason\n" +
+				"a,/stack-a,true,stack has unmerged changes\n",
+		})
+
+	assertRunResult(t, cli.listChangedStacks("--format", "json", "--fields", "reason,changed,id"),
+		runExpected{
+			Stdout: `[
+  {
+    "reason": "stack has unmerged changes",
+    "changed": true,
+    "id": "a"
+  }
+]
+`,
+		})
+
+	assertRunResult(t, cli.listStacks("--format", "csv", "--fields", "id,changed,reason"),
+		runExpected{
+			Stdout: "id,changed,reason\n" +
+				"a,,\n" +
+				"b,,\n",
+		})
+
+	assertRunResult(t, cli.listStacks("--format", "table", "--fields", "path,id"),
+		runExpected{
+			Stdout: "PATH      ID\n" +
+				"/stack-a  a\n" +
+				"/stack-b  b\n",
+		})
+}
+
+func TestListFormatInvalidUsage(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{`s:stack`})
+	cli := newCLI(t, s.RootDir())
+
+	assertRunResult(t, cli.listStacks("--format", "json", "--fields", "unknown"),
+		runExpected{
+			Status:      1,
+			StderrRegex: "invalid list field",
+		})
+
+	assertRunResult(t, cli.listStacks("--fields", "id"),
+		runExpected{
+			Status:      1,
+			StderrRegex: "--fields flag must be used together with --format",
+		})
+}
@@ -0,0 +1,49 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package e2etest
+
+import (
+	"testing"
+
+	. "github.com/terramate-io/terramate/test/hclwrite/hclutils"
+	"github.com/terramate-io/terramate/test/sandbox"
+)
+
+func TestListChangedGlobalsDataFile(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		"s:stacks/stack-1",
+		"s:stacks/stack-2",
+		"s:other",
+		`f:data/accounts.json:{"dev": "111"}`,
+		`f:data/unused.json:{"dev": "111"}`,
+	})
+	s.DirEntry("stacks").CreateFile("globals.tm", Globals(
+		Block("data",
+			Labels("accounts"),
+			Str("file", "/data/accounts.json"),
+		),
+	).String())
+
+	cli := newCLI(t, s.RootDir())
+
+	git := s.Git()
+	git.CommitAll("all")
+	git.Push("main")
+	git.CheckoutNew("change-the-data")
+
+	s.RootEntry().CreateFile("data/unused.json", `{"dev": "222"}`)
+	git.CommitAll("unused data changed")
+
+	assertRunResult(t, cli.listChangedStacks(), runExpected{})
+
+	s.RootEntry().CreateFile("data/accounts.json", `{"dev": "222"}`)
+	git.CommitAll("data changed")
+
+	assertRunResult(t, cli.listChangedStacks(), runExpected{
+		Stdout: "stacks/stack-1\nstacks/stack-2\n",
+	})
+}
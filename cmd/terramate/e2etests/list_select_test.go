@@ -0,0 +1,179 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package e2etest
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/terramate-io/terramate/test/sandbox"
+)
+
+func TestListAndRunStackSelection(t *testing.T) {
+	t.Parallel()
+
+	type testcase struct {
+		name   string
+		layout []string
+		wd     string
+		args   []string
+		want   runExpected
+		run    []string
+	}
+
+	layout := []string{
+		`s:stacks/app/dev:id=app-dev`,
+		`s:stacks/app/prod:id=app-prod;tags=["app"]`,
+		`s:stacks/db/prod:id=db-prod`,
+		`s:other`,
+		`f:stacks/app/prod/globals.tm:globals {
+		  env = "prod"
+		}`,
+		`f:stacks/db/prod/globals.tm:globals {
+		  env = "prod"
+		}`,
+		`f:globals.tm:globals {
+		  env = "dev"
+		}`,
+	}
+
+	for _, tc := range []testcase{
+		{
+			name:   "absolute path glob",
+			layout: layout,
+			args:   []string{"--stack-path", "/stacks/**/prod"},
+			want: runExpected{
+				Stdout: listStacks("stacks/app/prod", "stacks/db/prod"),
+			},
+			run: []string{"/stacks/app/prod", "/stacks/db/prod"},
+		},
+		{
+			name:   "single element glob",
+			layout: layout,
+			args:   []string{"--stack-path", "/stacks/app/*"},
+			want: runExpected{
+				Stdout: listStacks("stacks/app/dev", "stacks/app/prod"),
+			},
+			run: []string{"/stacks/app/dev", "/stacks/app/prod"},
+		},
+		{
+			name:   "relative path glob is resolved from working dir",
+			layout: layout,
+			wd:     "stacks",
+			args:   []string{"--stack-path", "*/dev"},
+			want: runExpected{
+				Stdout: listStacks("app/dev"),
+			},
+			run: []string{"/stacks/app/dev"},
+		},
+		{
+			name:   "multiple path globs are ORed",
+			layout: layout,
+			args: []string{
+				"--stack-path", "/other",
+				"--stack-path", "/stacks/db/**",
+			},
+			want: runExpected{
+				Stdout: listStacks("other", "stacks/db/prod"),
+			},
+			run: []string{"/other", "/stacks/db/prod"},
+		},
+		{
+			name:   "by IDs",
+			layout: layout,
+			args:   []string{"--stack-id", "app-dev,db-prod"},
+			want: runExpected{
+				Stdout: listStacks("stacks/app/dev", "stacks/db/prod"),
+			},
+			run: []string{"/stacks/app/dev", "/stacks/db/prod"},
+		},
+		{
+			name:   "where on globals",
+			layout: layout,
+			args:   []string{"--where", `global.env == "prod"`},
+			want: runExpected{
+				Stdout: listStacks("stacks/app/prod", "stacks/db/prod"),
+			},
+			run: []string{"/stacks/app/prod", "/stacks/db/prod"},
+		},
+		{
+			name:   "where on metadata",
+			layout: layout,
+			args:   []string{"--where", `tm_contains(terramate.stack.tags, "app")`},
+			want: runExpected{
+				Stdout: listStacks("stacks/app/prod"),
+			},
+			run: []string{"/stacks/app/prod"},
+		},
+		{
+			name:   "all selections combined",
+			layout: layout,
+			args: []string{
+				"--stack-path", "/stacks/**",
+				"--stack-id", "app-dev,app-prod",
+				"--where", `global.env == "prod"`,
+			},
+			want: runExpected{
+				Stdout: listStacks("stacks/app/prod"),
+			},
+			run: []string{"/stacks/app/prod"},
+		},
+		{
+			name: "where on sensitive global",
+			layout: append([]string{
+				`f:secret.tm:globals {
+				  secret = tm_sensitive("")
+				}`,
+				`f:stacks/db/prod/secret.tm:globals {
+				  secret = tm_sensitive("abc")
+				}`,
+			}, layout...),
+			args: []string{"--where", `global.secret == "abc"`},
+			want: runExpected{
+				Stdout: listStacks("stacks/db/prod"),
+			},
+			run: []string{"/stacks/db/prod"},
+		},
+		{
+			name:   "where must be a bool",
+			layout: layout,
+			args:   []string{"--where", `global.env`},
+			want: runExpected{
+				Status:      1,
+				StderrRegex: "must evaluate to a bool",
+			},
+		},
+		{
+			name:   "invalid path glob",
+			layout: layout,
+			args:   []string{"--stack-path", "/stacks/[a"},
+			want: runExpected{
+				Status:      1,
+				StderrRegex: "invalid path glob",
+			},
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := sandbox.New(t)
+			s.BuildTree(tc.layout)
+			git := s.Git()
+			git.CommitAll("all stacks")
+
+			cli := newCLI(t, filepath.Join(s.RootDir(), tc.wd))
+			assertRunResult(t, cli.listStacks(tc.args...), tc.want)
+
+			if tc.want.Status != 0 {
+				return
+			}
+
+			runArgs := append(tc.args, "run", "--", testHelperBin, "stack-abs-path", s.RootDir())
+			assertRunResult(t, cli.run(runArgs...), runExpected{
+				Stdout: listStacks(tc.run...),
+			})
+		})
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package e2etest
+
+import (
+	"testing"
+
+	. "github.com/terramate-io/terramate/test/hclwrite/hclutils"
+	"github.com/terramate-io/terramate/test/sandbox"
+)
+
+func TestListChangedTemplateFile(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		"s:stacks/stack-1",
+		"s:stacks/stack-2",
+		"s:other",
+		"f:templates/file.txt.tpl:version 1",
+		"f:templates/unused.txt.tpl:version 1",
+	})
+	s.DirEntry("stacks").CreateFile("gen.tm", GenerateFile(
+		Labels("file.txt"),
+		Str("template_file", "/templates/file.txt.tpl"),
+	).String())
+
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.run("generate"), runExpected{
+		IgnoreStdout: true,
+	})
+
+	git := s.Git()
+	git.CommitAll("all")
+	git.Push("main")
+	git.CheckoutNew("change-the-template")
+
+	s.RootEntry().CreateFile("templates/unused.txt.tpl", "version 2")
+	git.CommitAll("unused template changed")
+
+	assertRunResult(t, cli.listChangedStacks(), runExpected{})
+
+	s.RootEntry().CreateFile("templates/file.txt.tpl", "version 2")
+	git.CommitAll("template changed")
+
+	assertRunResult(t, cli.listChangedStacks(), runExpected{
+		Stdout: "stacks/stack-1\nstacks/stack-2\n",
+	})
+}
@@ -0,0 +1,88 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package e2etest
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/terramate-io/terramate/test/sandbox"
+)
+
+func TestRunDisabledAndManualStacks(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:enabled`,
+		`s:disabled:id=disabled;disabled=true`,
+		`s:manual:manual=true`,
+	})
+
+	git := s.Git()
+	git.CommitAll("first commit")
+
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.listStacks(), runExpected{
+		Stdout: listStacks("disabled (disabled)", "enabled", "manual (manual)"),
+	})
+	assertRunResult(t, cli.listStacks("--format", "csv", "--fields", "path,state"), runExpected{
+		Stdout: "path,state\n" +
+			"/disabled,disabled\n" +
+			"/enabled,enabled\n" +
+			"/manual,manual\n",
+	})
+
+	runArgs := []string{"--", testHelperBin, "stack-abs-path", s.RootDir()}
+	run := func(cli tmcli, args ...string) runResult {
+		return cli.run(append(append([]string{"run", "--quiet"}, args...), runArgs...)...)
+	}
+
+	assertRunResult(t, run(cli), runExpected{
+		Stdout: listStacks("/enabled"),
+	})
+	assertRunResult(t, run(cli, "--include-disabled"), runExpected{
+		Stdout: listStacks("/disabled", "/enabled", "/manual"),
+	})
+	assertRunResult(t, cli.run(append([]string{"run", "--quiet", "--stack-id", "disabled"}, runArgs...)...), runExpected{
+		Stdout: listStacks("/disabled"),
+	})
+	assertRunResult(t, cli.run(append([]string{"run", "--quiet", "--stack-path", "/manual"}, runArgs...)...), runExpected{
+		Stdout: listStacks("/manual"),
+	})
+
+	manualCLI := newCLI(t, filepath.Join(s.RootDir(), "manual"))
+	assertRunResult(t, run(manualCLI), runExpected{
+		Stdout: listStacks("/manual"),
+	})
+}
+
+func TestChangeDetectionIgnoresDisabledStacks(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:enabled`,
+		`s:disabled:disabled=true`,
+		`s:manual:manual=true`,
+	})
+
+	git := s.Git()
+	git.CommitAll("first commit")
+	git.Push("main")
+	git.CheckoutNew("change-stacks")
+
+	s.DirEntry("enabled").CreateFile("main.tf", "# changed")
+	s.DirEntry("disabled").CreateFile("main.tf", "# changed")
+	s.DirEntry("manual").CreateFile("main.tf", "# changed")
+	git.CommitAll("stacks changed")
+
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.listChangedStacks(), runExpected{
+		Stdout: listStacks("enabled", "manual (manual)"),
+	})
+	assertRunResult(t, cli.run("run", "--changed", "--quiet", "--", testHelperBin, "stack-abs-path", s.RootDir()), runExpected{
+		Stdout: listStacks("/enabled"),
+	})
+}
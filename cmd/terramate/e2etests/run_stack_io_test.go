@@ -0,0 +1,237 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package e2etest
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/terramate-io/terramate/test/sandbox"
+)
+
+func TestRunStackInputsFromOutputs(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:vpc`,
+		`s:db`,
+		`s:app`,
+		`f:vpc/outputs.json:{"vpc_id": {"sensitive": false, "type": "string", "value": "vpc-123"}, "private": {"value": "hidden"}}`,
+	})
+	s.RootEntry().CreateFile("terramate.tm", fmt.Sprintf(`
+terramate {
+  config {
+    run {
+      outputs_command = [%q, "cat", "outputs.json"]
+      env {
+        VPC_ID  = tm_try(input.vpc_id, "none")
+        DB_ADDR = tm_try(input.db_addr, "none")
+      }
+    }
+  }
+}
+`, testHelperBin))
+	s.DirEntry("vpc").CreateFile("outputs.tm", `
+output "vpc_id" {}
+`)
+	s.DirEntry("db").CreateFile("outputs.tm", `
+output "addr" {}
+`)
+	s.DirEntry("app").CreateFile("inputs.tm", `
+input "vpc_id" {
+  from_stack = "../vpc"
+  value      = outputs.vpc_id
+}
+
+input "db_addr" {
+  from_stack = "/db"
+  value      = outputs.addr
+  mock       = "mocked-addr"
+}
+`)
+
+	git := s.Git()
+	git.CommitAll("first commit")
+
+	cli := newCLI(t, filepath.Join(s.RootDir(), "app"))
+	res := cli.run("run", "--", testHelperBin, "env")
+	assertRunResult(t, res, runExpected{IgnoreStdout: true})
+	assertEnvVar(t, res.Stdout, "VPC_ID", "vpc-123")
+	assertEnvVar(t, res.Stdout, "DB_ADDR", "mocked-addr")
+
+	// the run-env command and stacks without inputs use the mocks.
+	assertRunResult(t, cli.run("experimental", "run-env"), runExpected{
+		Stdout: "\nstack \"/app\":\n\tDB_ADDR=mocked-addr\n\tVPC_ID=none\n",
+	})
+
+	// inputs are ordered after the stacks they read from.
+	cli = newCLI(t, s.RootDir())
+	assertRunResult(t, cli.run("experimental", "run-order"), runExpected{
+		Stdout: listStacks("/db", "/vpc", "/app"),
+	})
+}
+
+func TestRunStackInputFailsWithoutOutputsOrMock(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:vpc`,
+		`s:app`,
+	})
+	s.RootEntry().CreateFile("terramate.tm", fmt.Sprintf(`
+terramate {
+  config {
+    run {
+      outputs_command = [%q, "cat", "outputs.json"]
+      env {
+        VPC_ID = tm_try(input.vpc_id, "none")
+      }
+    }
+  }
+}
+`, testHelperBin))
+	s.DirEntry("vpc").CreateFile("outputs.tm", `
+output "vpc_id" {}
+`)
+	s.DirEntry("app").CreateFile("inputs.tm", `
+input "vpc_id" {
+  from_stack = "/vpc"
+  value      = outputs.vpc_id
+}
+`)
+
+	git := s.Git()
+	git.CommitAll("first commit")
+
+	cli := newCLI(t, filepath.Join(s.RootDir(), "app"))
+	assertRunResult(t, cli.run("run", "--", testHelperBin, "env"), runExpected{
+		Status:      1,
+		StderrRegex: "reading stack outputs",
+	})
+}
+
+func TestRunStackInputMocks(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name  string
+		value string
+		want  runExpected
+		env   string
+	}{
+		{
+			name:  "declared output missing uses mock",
+			value: "outputs.subnet_id",
+			env:   "mocked",
+		},
+		{
+			name:  "undeclared output fails",
+			value: "outputs.vpc_idd",
+			want: runExpected{
+				Status:      1,
+				StderrRegex: "evaluating input",
+			},
+		},
+		{
+			name:  "invalid function call fails",
+			value: "tm_upper(outputs.vpc_id, 1)",
+			want: runExpected{
+				Status:      1,
+				StderrRegex: "evaluating input",
+			},
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := sandbox.New(t)
+			s.BuildTree([]string{
+				`s:vpc`,
+				`s:app`,
+				`f:vpc/outputs.json:{"vpc_id": {"value": "vpc-123"}}`,
+			})
+			s.RootEntry().CreateFile("terramate.tm", fmt.Sprintf(`
+terramate {
+  config {
+    run {
+      outputs_command = [%q, "cat", "outputs.json"]
+      env {
+        VPC_ID = tm_try(input.vpc_id, "none")
+      }
+    }
+  }
+}
+`, testHelperBin))
+			s.DirEntry("vpc").CreateFile("outputs.tm", `
+output "vpc_id" {}
+output "subnet_id" {}
+`)
+			s.DirEntry("app").CreateFile("inputs.tm", fmt.Sprintf(`
+input "vpc_id" {
+  from_stack = "/vpc"
+  value      = %s
+  mock       = "mocked"
+}
+`, tc.value))
+
+			git := s.Git()
+			git.CommitAll("first commit")
+
+			cli := newCLI(t, filepath.Join(s.RootDir(), "app"))
+			res := cli.run("run", "--", testHelperBin, "env")
+			if tc.want.Status != 0 {
+				assertRunResult(t, res, tc.want)
+				return
+			}
+			assertRunResult(t, res, runExpected{IgnoreStdout: true})
+			assertEnvVar(t, res.Stdout, "VPC_ID", tc.env)
+		})
+	}
+}
+
+func TestGenerateUsesStackInputMocks(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:db`,
+		`s:app`,
+	})
+	s.DirEntry("app").CreateFile("inputs.tm", `
+input "db_addr" {
+  from_stack = "/db"
+  value      = outputs.addr
+  mock       = "mocked-addr"
+}
+
+generate_file "db.txt" {
+  content = input.db_addr
+}
+`)
+
+	cli := newCLI(t, s.RootDir())
+	assertRunResult(t, cli.run("generate"), runExpected{IgnoreStdout: true})
+	got := s.DirEntry("app").ReadFile("db.txt")
+	if string(got) != "mocked-addr" {
+		t.Fatalf("generated %q but want %q", got, "mocked-addr")
+	}
+}
+
+func assertEnvVar(t *testing.T, environ string, name, want string) {
+	t.Helper()
+	for _, env := range strings.Split(environ, "\n") {
+		if v, ok := strings.CutPrefix(env, name+"="); ok {
+			if v != want {
+				t.Fatalf("env %s=%s but want %s", name, v, want)
+			}
+			return
+		}
+	}
+	t.Fatalf("env %s not found in:\n%s", name, environ)
+}
@@ -68,6 +68,7 @@ func evalBool(evalctx *eval.Context, expr hhcl.Expression, name string) (bool, e
 	if val.Type() != cty.Bool {
 		return false, errors.E(ErrSchema, "%s must be boolean, got %v", name, val.Type().FriendlyName())
 	}
+	val, _ = val.UnmarkDeep()
 	return val.True(), nil
 }
 
@@ -82,5 +83,6 @@ func evalString(evalctx *eval.Context, expr hhcl.Expression, name string) (strin
 	if val.Type() != cty.String {
 		return "", errors.E(ErrSchema, "%s must be string, got %v", name, val.Type().FriendlyName())
 	}
+	val, _ = val.UnmarkDeep()
 	return val.AsString(), nil
 }
@@ -10,11 +10,13 @@ import (
 	"sort"
 	"strings"
 
+	hhcl "github.com/hashicorp/hcl/v2"
 	"github.com/rs/zerolog/log"
 	"github.com/terramate-io/terramate"
 	"github.com/terramate-io/terramate/config/filter"
 	"github.com/terramate-io/terramate/errors"
 	"github.com/terramate-io/terramate/hcl"
+	"github.com/terramate-io/terramate/hcl/info"
 	"github.com/terramate-io/terramate/project"
 	"github.com/zclconf/go-cty/cty"
 )
@@ -39,6 +41,19 @@ type Root struct {
 	tree Tree
 
 	runtime project.Runtime
+
+	globalOverrides []GlobalOverride
+}
+
+// GlobalOverride is a global set for a single invocation. It has precedence
+// over all the definitions of the global in the configuration.
+type GlobalOverride struct {
+	// Path of the global.
+	Path []string
+	// Expr is the expression of the global value.
+	Expr hhcl.Expression
+	// Origin is where the override was defined. It's only used for debugging.
+	Origin info.Range
 }
 
 // Tree is the configuration tree.
@@ -250,6 +265,16 @@ func (root *Root) Runtime() project.Runtime {
 	return runtime
 }
 
+// SetGlobalOverrides sets the globals overriding the configured ones.
+func (root *Root) SetGlobalOverrides(overrides []GlobalOverride) {
+	root.globalOverrides = overrides
+}
+
+// GlobalOverrides returns the globals overriding the configured ones.
+func (root *Root) GlobalOverrides() []GlobalOverride {
+	return root.globalOverrides
+}
+
 func (root *Root) initRuntime() {
 	rootfs := cty.ObjectVal(map[string]cty.Value{
 		"absolute": cty.StringVal(root.HostDir()),
@@ -329,3 +329,52 @@ func isStack(root *config.Root, dir string) bool {
 func init() {
 	zerolog.SetGlobalLevel(zerolog.Disabled)
 }
+
+func TestStackInputsResolution(t *testing.T) {
+	t.Parallel()
+	s := sandbox.NoGit(t)
+	s.BuildTree([]string{
+		"s:stacks/vpc",
+		"s:stacks/app:after=[\"/stacks/vpc\"]",
+		"s:stacks/db",
+	})
+	s.DirEntry("stacks/vpc").CreateFile("io.tm", `
+output "vpc_id" {}
+output "subnets" {}
+`)
+	s.DirEntry("stacks/app").CreateFile("io.tm", `
+input "vpc_id" {
+  from_stack = "../vpc"
+  value      = outputs.vpc_id
+}
+input "db_addr" {
+  from_stack = "/stacks/db"
+  value      = outputs.addr
+  mock       = "localhost"
+}
+`)
+
+	root, err := config.LoadRoot(s.RootDir())
+	assert.NoError(t, err)
+
+	vpc, err := config.LoadStack(root, project.NewPath("/stacks/vpc"))
+	assert.NoError(t, err)
+	assert.EqualInts(t, 2, len(vpc.Outputs))
+	assert.EqualStrings(t, "vpc_id", vpc.Outputs[0])
+	assert.EqualStrings(t, "subnets", vpc.Outputs[1])
+
+	app, err := config.LoadStack(root, project.NewPath("/stacks/app"))
+	assert.NoError(t, err)
+	assert.EqualInts(t, 2, len(app.Inputs))
+	assert.EqualStrings(t, "/stacks/vpc", app.Inputs[0].FromStack.String())
+	assert.EqualStrings(t, "/stacks/db", app.Inputs[1].FromStack.String())
+
+	// the vpc stack is already in the after list, only db is implicitly added.
+	assert.EqualInts(t, 2, len(app.After))
+	assert.EqualStrings(t, "/stacks/vpc", app.After[0])
+	assert.EqualStrings(t, "/stacks/db", app.After[1])
+
+	mocks := app.InputMocks()
+	assert.EqualInts(t, 1, len(mocks))
+	assert.EqualStrings(t, "localhost", mocks["db_addr"].AsString())
+}
@@ -0,0 +1,69 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package filter
+
+import (
+	"path"
+	"strings"
+
+	"github.com/terramate-io/terramate/errors"
+)
+
+// ErrInvalidPathGlob indicates that a path glob pattern is malformed.
+const ErrInvalidPathGlob errors.Kind = "invalid path glob"
+
+// ValidatePathGlob checks if the pattern is a valid path glob.
+func ValidatePathGlob(pattern string) error {
+	for _, elem := range strings.Split(pattern, "/") {
+		if elem == "**" {
+			continue
+		}
+		if _, err := path.Match(elem, ""); err != nil {
+			return errors.E(ErrInvalidPathGlob, err, "pattern %q", pattern)
+		}
+	}
+	return nil
+}
+
+// MatchPath tells if the slash separated path p matches the glob pattern.
+// The pattern supports the same syntax as [path.Match] for each path element,
+// with the addition of the "**" element, which matches zero or more
+// directories. The pattern must be already validated with [ValidatePathGlob].
+func MatchPath(pattern, p string) bool {
+	return matchPathElems(splitPath(pattern), splitPath(p))
+}
+
+func matchPathElems(patterns, elems []string) bool {
+	for len(patterns) > 0 {
+		pattern := patterns[0]
+		if pattern == "**" {
+			for i := 0; i <= len(elems); i++ {
+				if matchPathElems(patterns[1:], elems[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(elems) == 0 {
+			return false
+		}
+		matched, err := path.Match(pattern, elems[0])
+		if err != nil || !matched {
+			return false
+		}
+		patterns = patterns[1:]
+		elems = elems[1:]
+	}
+	return len(elems) == 0
+}
+
+func splitPath(p string) []string {
+	var elems []string
+	for _, elem := range strings.Split(p, "/") {
+		if elem != "" {
+			elems = append(elems, elem)
+		}
+	}
+	return elems
+}
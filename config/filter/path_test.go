@@ -0,0 +1,57 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package filter_test
+
+import (
+	"testing"
+
+	"github.com/terramate-io/terramate/config/filter"
+	"github.com/terramate-io/terramate/errors"
+	errtest "github.com/terramate-io/terramate/test/errors"
+)
+
+func TestMatchPath(t *testing.T) {
+	t.Parallel()
+
+	type testcase struct {
+		pattern string
+		path    string
+		want    bool
+	}
+
+	for _, tc := range []testcase{
+		{pattern: "/", path: "/", want: true},
+		{pattern: "/stack", path: "/stack", want: true},
+		{pattern: "/stack", path: "/stack/child", want: false},
+		{pattern: "/stacks/*", path: "/stacks/a", want: true},
+		{pattern: "/stacks/*", path: "/stacks/a/b", want: false},
+		{pattern: "/stacks/*", path: "/stacks", want: false},
+		{pattern: "/stacks/**", path: "/stacks", want: true},
+		{pattern: "/stacks/**", path: "/stacks/a/b/c", want: true},
+		{pattern: "/**/prod", path: "/prod", want: true},
+		{pattern: "/**/prod", path: "/a/b/prod", want: true},
+		{pattern: "/**/prod", path: "/a/b/prod/x", want: false},
+		{pattern: "/**/prod/**", path: "/a/prod/x/y", want: true},
+		{pattern: "/app-?/*", path: "/app-1/svc", want: true},
+		{pattern: "/app-[0-9]", path: "/app-x", want: false},
+		{pattern: "/**", path: "/anything/at/all", want: true},
+	} {
+		tc := tc
+		t.Run(tc.pattern+" "+tc.path, func(t *testing.T) {
+			t.Parallel()
+
+			errtest.Assert(t, filter.ValidatePathGlob(tc.pattern), nil)
+			if got := filter.MatchPath(tc.pattern, tc.path); got != tc.want {
+				t.Fatalf("MatchPath(%q, %q) = %t, want %t", tc.pattern, tc.path, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestValidatePathGlobInvalid(t *testing.T) {
+	t.Parallel()
+
+	err := filter.ValidatePathGlob("/stacks/[a")
+	errtest.Assert(t, err, errors.E(filter.ErrInvalidPathGlob))
+}
@@ -10,10 +10,12 @@ import (
 	"regexp"
 	"strings"
 
+	hhcl "github.com/hashicorp/hcl/v2"
 	"github.com/rs/zerolog/log"
 	"github.com/terramate-io/terramate/config/tag"
 	"github.com/terramate-io/terramate/errors"
 	"github.com/terramate-io/terramate/hcl"
+	"github.com/terramate-io/terramate/hcl/info"
 	"github.com/terramate-io/terramate/project"
 	"github.com/zclconf/go-cty/cty"
 )
@@ -54,10 +56,44 @@ type (
 		// Watch is the list of files to be watched for changes.
 		Watch []project.Path
 
+		// Inputs is the list of inputs read from the outputs of other stacks.
+		Inputs []Input
+
+		// Outputs is the list of output names shared with other stacks.
+		Outputs []string
+
+		// Disabled tells if the stack is excluded from orchestration and
+		// change detection.
+		Disabled bool
+
+		// Manual tells if the stack only runs when explicitly selected.
+		Manual bool
+
 		// IsChanged tells if this is a changed stack.
 		IsChanged bool
 	}
 
+	// Input is a stack input whose value is computed from the outputs of
+	// another stack.
+	Input struct {
+		// Range is the range of the input block definition.
+		Range info.Range
+
+		// Name of the input.
+		Name string
+
+		// FromStack is the project path of the stack providing the outputs.
+		FromStack project.Path
+
+		// Value is the expression computing the input from the outputs
+		// (the "outputs" namespace) of the FromStack stack.
+		Value hhcl.Expression
+
+		// Mock is the value used when the outputs are not available.
+		// It is cty.NilVal if the input has no mock.
+		Mock cty.Value
+	}
+
 	// SortableStack is a wrapper for the Stack which implements the [DirElem] type.
 	SortableStack struct {
 		*Stack
@@ -82,6 +118,9 @@ const (
 
 	// ErrStackInvalidWantedBy indicates the stack.wanted_by is invalid.
 	ErrStackInvalidWantedBy errors.Kind = "invalid stack.wanted_by entry"
+
+	// ErrStackInvalidInput indicates the stack has an invalid input block.
+	ErrStackInvalidInput errors.Kind = "invalid stack input"
 )
 
 // NewStackFromHCL creates a new stack from raw configuration cfg.
@@ -106,8 +145,30 @@ func NewStackFromHCL(root string, cfg hcl.Config) (*Stack, error) {
 		Wants:       cfg.Stack.Wants,
 		WantedBy:    cfg.Stack.WantedBy,
 		Watch:       watchFiles,
+		Disabled:    cfg.Stack.Disabled,
+		Manual:      cfg.Stack.Manual,
 		Dir:         project.PrjAbsPath(root, cfg.AbsDir()),
 	}
+
+	for _, output := range cfg.Outputs {
+		stack.Outputs = append(stack.Outputs, output.Name)
+	}
+
+	for _, input := range cfg.Inputs {
+		fromStack, err := resolveInputStack(stack, input)
+		if err != nil {
+			return nil, err
+		}
+		stack.Inputs = append(stack.Inputs, Input{
+			Range:     input.Range,
+			Name:      input.Name,
+			FromStack: fromStack,
+			Value:     input.Value,
+			Mock:      input.Mock,
+		})
+		stack.addImplicitAfter(fromStack)
+	}
+
 	err = stack.Validate()
 	if err != nil {
 		return nil, err
@@ -189,6 +250,52 @@ func (s Stack) validateTagFilterNotAllowed(cfglst ...[]string) error {
 	return nil
 }
 
+// InputMocks returns the mocked values of the stack inputs, indexed by name.
+// Inputs without a mock are not present in the returned map.
+func (s *Stack) InputMocks() map[string]cty.Value {
+	mocks := map[string]cty.Value{}
+	for _, input := range s.Inputs {
+		if input.Mock != cty.NilVal {
+			mocks[input.Name] = input.Mock
+		}
+	}
+	return mocks
+}
+
+// resolveInputStack returns the project path of the stack referenced by
+// the input.from_stack attribute, which can be relative to the stack directory.
+func resolveInputStack(s *Stack, input hcl.Input) (project.Path, error) {
+	var fromStack project.Path
+	if path.IsAbs(input.FromStack) {
+		fromStack = project.NewPath(input.FromStack)
+	} else {
+		fromStack = s.Dir.Join(input.FromStack)
+	}
+	if fromStack == s.Dir {
+		return project.Path{}, errors.E(ErrStackInvalidInput, input.Range,
+			"input.from_stack must not reference the stack itself")
+	}
+	return fromStack, nil
+}
+
+// addImplicitAfter adds the stack at dir to the list of stacks that must
+// run before this stack, unless it's already present.
+func (s *Stack) addImplicitAfter(dir project.Path) {
+	for _, after := range s.After {
+		if strings.HasPrefix(after, "tag:") {
+			continue
+		}
+		afterPath := after
+		if !path.IsAbs(afterPath) {
+			afterPath = s.Dir.Join(afterPath).String()
+		}
+		if path.Clean(afterPath) == dir.String() {
+			return
+		}
+	}
+	s.After = append(s.After, dir.String())
+}
+
 // AppendBefore appends the path to the list of stacks that must run after this
 // stack.
 func (s *Stack) AppendBefore(path string) {
@@ -175,6 +175,7 @@ export default defineConfig({
           { text: 'Overview', link: 'code-generation/' },
           { text: 'Generate HCL', link: 'code-generation/generate-hcl' },
           { text: 'Generate File', link: 'code-generation/generate-file' },
+          { text: 'Generate JSON and YAML', link: 'code-generation/generate-json-yaml' },
         ],
       },
       {
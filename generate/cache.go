@@ -0,0 +1,294 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package generate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	hhcl "github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/rs/zerolog/log"
+	"github.com/terramate-io/terramate"
+	"github.com/terramate-io/terramate/config"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/hcl"
+	"github.com/terramate-io/terramate/hcl/info"
+	"github.com/terramate-io/terramate/project"
+)
+
+// cacheVersion must be changed whenever the format of the cache entries or
+// the way the cache keys are computed changes.
+const cacheVersion = "3"
+
+// uncacheableFuncs are the functions whose results don't depend only on the
+// Terramate configuration or that have side effects, so stacks depending on
+// them are always evaluated.
+var uncacheableFuncs = map[string]struct{}{
+	"tm_bcrypt":           {},
+	"tm_file":             {},
+	"tm_filebase64":       {},
+	"tm_filebase64sha256": {},
+	"tm_filebase64sha512": {},
+	"tm_fileexists":       {},
+	"tm_filemd5":          {},
+	"tm_fileset":          {},
+	"tm_filesha1":         {},
+	"tm_filesha256":       {},
+	"tm_filesha512":       {},
+	"tm_pathexpand":       {},
+	"tm_templatefile":     {},
+	"tm_timestamp":        {},
+	"tm_uuid":             {},
+	"tm_vendor":           {},
+}
+
+// Cache is a content-addressed cache of the code generated for stacks.
+//
+// The cache key of a stack is computed from the content of all the
+// configuration files from the stack directory up to the project root, the
+// template files used by their generate blocks, the stack path, the list of
+// stacks of the project, the vendor dir and the Terramate version, so stacks
+// whose configuration didn't change can skip the evaluation of globals and
+// generate blocks entirely.
+//
+// Stacks calling functions that access the file system, are non-deterministic
+// or have side effects (like tm_file, tm_timestamp and tm_vendor) are never
+// cached.
+//
+// A nil *Cache is valid and disables caching.
+type Cache struct {
+	dir string
+}
+
+type cacheEntry struct {
+	Files   []cachedFile    `json:"files"`
+	Asserts []config.Assert `json:"asserts"`
+}
+
+// cachedFile is a [GenFile] restored from the cache.
+type cachedFile struct {
+	FileType      string     `json:"type"`
+	FileLabel     string     `json:"label"`
+	FileContext   string     `json:"context"`
+	FileHeader    string     `json:"header"`
+	FileBody      string     `json:"body"`
+	FileCondition bool       `json:"condition"`
+	FileRange     hhcl.Range `json:"range"`
+
+	rootdir string
+}
+
+// NewCache creates a new cache that stores its entries at dir.
+// The dir is created, if needed, when the first entry is stored.
+func NewCache(dir string) *Cache {
+	return &Cache{dir: dir}
+}
+
+func (f cachedFile) Type() string             { return f.FileType }
+func (f cachedFile) Header() string           { return f.FileHeader }
+func (f cachedFile) Body() string             { return f.FileBody }
+func (f cachedFile) Label() string            { return f.FileLabel }
+func (f cachedFile) Context() string          { return f.FileContext }
+func (f cachedFile) Condition() bool          { return f.FileCondition }
+func (f cachedFile) Asserts() []config.Assert { return nil }
+
+// PostProcess returns no post-processors as the cached body is already
+// post-processed.
+func (f cachedFile) PostProcess() []hcl.PostProcessConfig { return nil }
+func (f cachedFile) Range() info.Range {
+	return info.NewRange(f.rootdir, f.FileRange)
+}
+
+// key computes the cache key of the given stack. It returns false if the
+// stack can't be cached, which is always the case when globals are overridden.
+func (c *Cache) key(root *config.Root, st *config.Stack, vendorDir project.Path) (string, bool) {
+	if c == nil || len(root.GlobalOverrides()) > 0 {
+		return "", false
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "version:%s:%s\n", cacheVersion, terramate.Version())
+	fmt.Fprintf(h, "root:%s\n", root.HostDir())
+	fmt.Fprintf(h, "stack:%s\n", st.Dir)
+	fmt.Fprintf(h, "vendor:%s\n", vendorDir)
+	for _, stackpath := range root.Stacks() {
+		fmt.Fprintf(h, "stacks:%s\n", stackpath)
+	}
+
+	cfgdir := st.Dir
+	for {
+		cfg, ok := root.Lookup(cfgdir)
+		if ok {
+			for _, fn := range cfg.Node.FunctionCalls() {
+				if _, uncacheable := uncacheableFuncs[fn]; uncacheable {
+					return "", false
+				}
+			}
+			fmt.Fprintf(h, "config:%s:%s\n", cfgdir, cfg.Node.Digest())
+			if !hashTemplateFiles(h, root, cfg) || !hashGlobalsDataFiles(h, root, cfg) {
+				return "", false
+			}
+		}
+		parent := cfgdir.Dir()
+		if parent == cfgdir {
+			break
+		}
+		cfgdir = parent
+	}
+	return hex.EncodeToString(h.Sum(nil)), true
+}
+
+// hashTemplateFiles writes the content of the template files of the generate
+// blocks of the given config to h. It returns false if any of the template
+// files can't be read or calls an uncacheable function.
+func hashTemplateFiles(h io.Writer, root *config.Root, cfg *config.Tree) bool {
+	var templateFiles []string
+	for _, block := range cfg.Node.Generate.Files {
+		if block.TemplateFile != "" {
+			templateFiles = append(templateFiles, block.TemplateFile)
+		}
+	}
+	for _, block := range cfg.Node.Generate.HCLs {
+		if block.TemplateFile != "" {
+			templateFiles = append(templateFiles, block.TemplateFile)
+		}
+	}
+
+	for _, templateFile := range templateFiles {
+		filename := project.NewPath(templateFile).HostPath(root.HostDir())
+		src, err := os.ReadFile(filename)
+		if err != nil {
+			return false
+		}
+		expr, diags := hclsyntax.ParseTemplate(src, filename, hhcl.InitialPos)
+		if diags.HasErrors() {
+			return false
+		}
+		uncacheable := false
+		_ = hclsyntax.VisitAll(expr, func(node hclsyntax.Node) hhcl.Diagnostics {
+			if call, ok := node.(*hclsyntax.FunctionCallExpr); ok {
+				if _, found := uncacheableFuncs[call.Name]; found {
+					uncacheable = true
+				}
+			}
+			return nil
+		})
+		if uncacheable {
+			return false
+		}
+		fmt.Fprintf(h, "template:%s:%d:", templateFile, len(src))
+		_, _ = h.Write(src)
+	}
+	return true
+}
+
+// hashGlobalsDataFiles writes the content of the files loaded by the globals
+// data blocks of the given config to h. It returns false if any of the files
+// can't be read.
+func hashGlobalsDataFiles(h io.Writer, root *config.Root, cfg *config.Tree) bool {
+	for _, data := range cfg.Node.GlobalsData {
+		src, err := os.ReadFile(project.NewPath(data.File).HostPath(root.HostDir()))
+		if err != nil {
+			return false
+		}
+		fmt.Fprintf(h, "globals.data:%s:%d:", data.File, len(src))
+		_, _ = h.Write(src)
+	}
+	return true
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.dir, key[:2], key+".json")
+}
+
+// load the generated files of the stack from the cache.
+func (c *Cache) load(root *config.Root, key string) (cacheEntry, bool) {
+	logger := log.With().
+		Str("action", "generate.Cache.load()").
+		Str("key", key).
+		Logger()
+
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			logger.Debug().Err(err).Msg("reading cache entry")
+		}
+		return cacheEntry{}, false
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		logger.Debug().Err(err).Msg("ignoring invalid cache entry")
+		return cacheEntry{}, false
+	}
+
+	for i := range entry.Files {
+		entry.Files[i].rootdir = root.HostDir()
+	}
+	return entry, true
+}
+
+// store the generated files of the stack on the cache.
+// Failing to store an entry is not fatal, the stack will just be evaluated
+// again next time.
+func (c *Cache) store(key string, files []GenFile, asserts []config.Assert) {
+	logger := log.With().
+		Str("action", "generate.Cache.store()").
+		Str("key", key).
+		Logger()
+
+	entry := cacheEntry{
+		Asserts: asserts,
+		Files:   make([]cachedFile, len(files)),
+	}
+	for i, file := range files {
+		entry.Files[i] = cachedFile{
+			FileType:      file.Type(),
+			FileLabel:     file.Label(),
+			FileContext:   file.Context(),
+			FileHeader:    file.Header(),
+			FileBody:      file.Body(),
+			FileCondition: file.Condition(),
+			FileRange:     file.Range().ToHCLRange(),
+		}
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		logger.Debug().Err(err).Msg("encoding cache entry")
+		return
+	}
+
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		logger.Debug().Err(err).Msg("creating cache dir")
+		return
+	}
+
+	// WHY: the entry is written to a temporary file and then renamed so
+	// concurrent Terramate processes never read partially written entries.
+	tmpfile, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		logger.Debug().Err(err).Msg("creating cache entry")
+		return
+	}
+	defer func() { _ = os.Remove(tmpfile.Name()) }()
+
+	_, err = tmpfile.Write(data)
+	errs := errors.L(err, tmpfile.Close())
+	if err := errs.AsError(); err != nil {
+		logger.Debug().Err(err).Msg("writing cache entry")
+		return
+	}
+	if err := os.Rename(tmpfile.Name(), path); err != nil {
+		logger.Debug().Err(err).Msg("saving cache entry")
+	}
+}
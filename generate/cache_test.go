@@ -0,0 +1,183 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package generate_test
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/generate"
+	"github.com/terramate-io/terramate/project"
+	. "github.com/terramate-io/terramate/test/hclwrite/hclutils"
+	"github.com/terramate-io/terramate/test/sandbox"
+)
+
+func TestGenerateCacheSkipsEvaluationOfUnchangedStacks(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		"s:stacks/stack-1",
+		"s:stacks/stack-2",
+	})
+	s.RootEntry().CreateFile("globals.tm", Globals(
+		Str("data", "global data"),
+	).String())
+	s.DirEntry("stacks").CreateFile("gen.tm", GenerateFile(
+		Labels("file.txt"),
+		Expr("content", `"${global.data} at ${terramate.stack.path.absolute}"`),
+	).String())
+
+	cachedir := t.TempDir()
+	cache := generate.NewCache(cachedir)
+	vendorDir := project.NewPath("/modules")
+
+	report := generate.Do(s.Config(), vendorDir, nil, generate.Options{Cache: cache})
+	assert.IsTrue(t, !report.HasFailures(), report.Full())
+	assertCacheEntries(t, cachedir, 2)
+
+	stack1 := s.DirEntry("stacks/stack-1")
+	stack2 := s.DirEntry("stacks/stack-2")
+	assert.EqualStrings(t, "global data at /stacks/stack-1", string(stack1.ReadFile("file.txt")))
+	assert.EqualStrings(t, "global data at /stacks/stack-2", string(stack2.ReadFile("file.txt")))
+
+	// WHY: changing the cached content proves that the stacks are not evaluated
+	// again when their configuration didn't change.
+	tamperCacheEntries(t, cachedir, "global data", "cached data")
+
+	stack1.RemoveFile("file.txt")
+	outdated, err := generate.DetectOutdated(s.Config(), vendorDir, cache)
+	assert.NoError(t, err)
+	assertEqualStringList(t, outdated, []string{
+		"stacks/stack-1/file.txt",
+		"stacks/stack-2/file.txt",
+	})
+
+	report = generate.Do(s.Config(), vendorDir, nil, generate.Options{Cache: cache})
+	assert.IsTrue(t, !report.HasFailures(), report.Full())
+	assert.EqualStrings(t, "cached data at /stacks/stack-1", string(stack1.ReadFile("file.txt")))
+	assert.EqualStrings(t, "cached data at /stacks/stack-2", string(stack2.ReadFile("file.txt")))
+
+	// changing the config of one stack only invalidates that stack.
+	stack2.CreateFile("globals.tm", Globals(
+		Str("data", "stack-2 data"),
+	).String())
+
+	report = generate.Do(s.ReloadConfig(), vendorDir, nil, generate.Options{Cache: cache})
+	assert.IsTrue(t, !report.HasFailures(), report.Full())
+	assert.EqualStrings(t, "cached data at /stacks/stack-1", string(stack1.ReadFile("file.txt")))
+	assert.EqualStrings(t, "stack-2 data at /stacks/stack-2", string(stack2.ReadFile("file.txt")))
+	assertCacheEntries(t, cachedir, 3)
+
+	// changing a parent config invalidates all stacks below it.
+	s.RootEntry().CreateFile("globals.tm", Globals(
+		Str("data", "new global data"),
+	).String())
+
+	report = generate.Do(s.ReloadConfig(), vendorDir, nil, generate.Options{Cache: cache})
+	assert.IsTrue(t, !report.HasFailures(), report.Full())
+	assert.EqualStrings(t, "new global data at /stacks/stack-1", string(stack1.ReadFile("file.txt")))
+	assert.EqualStrings(t, "stack-2 data at /stacks/stack-2", string(stack2.ReadFile("file.txt")))
+}
+
+func TestGenerateCacheIgnoresStacksCallingFileFunctions(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		"s:stack",
+		"f:stack/data.txt:data",
+	})
+	stack := s.DirEntry("stack")
+	stack.CreateFile("gen.tm", GenerateFile(
+		Labels("file.txt"),
+		Expr("content", `tm_file("data.txt")`),
+	).String())
+
+	cachedir := t.TempDir()
+	cache := generate.NewCache(cachedir)
+
+	report := generate.Do(s.Config(), project.NewPath("/modules"), nil, generate.Options{Cache: cache})
+	assert.IsTrue(t, !report.HasFailures(), report.Full())
+	assertCacheEntries(t, cachedir, 0)
+
+	stack.CreateFile("data.txt", "changed")
+
+	report = generate.Do(s.Config(), project.NewPath("/modules"), nil, generate.Options{Cache: cache})
+	assert.IsTrue(t, !report.HasFailures(), report.Full())
+	assert.EqualStrings(t, "changed", string(stack.ReadFile("file.txt")))
+}
+
+func TestGenerateCacheIsInvalidatedByGlobalsDataFiles(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		"s:stack",
+		`f:data.json:{"name": "first"}`,
+		`f:globals.tm:globals {
+		  data "cfg" {
+		    file = "/data.json"
+		  }
+		}`,
+	})
+	stack := s.DirEntry("stack")
+	stack.CreateFile("gen.tm", GenerateFile(
+		Labels("file.txt"),
+		Expr("content", `global.cfg.name`),
+	).String())
+
+	cachedir := t.TempDir()
+	cache := generate.NewCache(cachedir)
+	vendorDir := project.NewPath("/modules")
+
+	report := generate.Do(s.Config(), vendorDir, nil, generate.Options{Cache: cache})
+	assert.IsTrue(t, !report.HasFailures(), report.Full())
+	assertCacheEntries(t, cachedir, 1)
+	assert.EqualStrings(t, "first", string(stack.ReadFile("file.txt")))
+
+	s.RootEntry().CreateFile("data.json", `{"name": "second"}`)
+
+	report = generate.Do(s.Config(), vendorDir, nil, generate.Options{Cache: cache})
+	assert.IsTrue(t, !report.HasFailures(), report.Full())
+	assertCacheEntries(t, cachedir, 2)
+	assert.EqualStrings(t, "second", string(stack.ReadFile("file.txt")))
+}
+
+func assertCacheEntries(t *testing.T, cachedir string, want int) {
+	t.Helper()
+
+	got := 0
+	err := filepath.WalkDir(cachedir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.HasSuffix(path, ".json") {
+			got++
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.EqualInts(t, want, got, "unexpected number of cache entries")
+}
+
+func tamperCacheEntries(t *testing.T, cachedir string, old, new string) {
+	t.Helper()
+
+	err := filepath.WalkDir(cachedir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(path, []byte(strings.ReplaceAll(string(data), old, new)), 0644)
+	})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,58 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package generate
+
+import (
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/project"
+)
+
+const devNull = "/dev/null"
+
+// unifiedDiff returns the unified diff between the old and new contents of
+// the file at the given project path. The oldExists and newExists booleans
+// indicate if the file exists before and after the change, so created and
+// deleted files are diffed against /dev/null like git does.
+func unifiedDiff(
+	file project.Path,
+	oldContent string, oldExists bool,
+	newContent string, newExists bool,
+) string {
+	fromFile := devNull
+	if oldExists {
+		fromFile = "a" + file.String()
+	}
+	toFile := devNull
+	if newExists {
+		toFile = "b" + file.String()
+	}
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(oldContent),
+		B:        splitLines(newContent),
+		FromFile: fromFile,
+		ToFile:   toFile,
+		Context:  3,
+	})
+	if err != nil {
+		// WHY: the diff is written to a strings.Builder, which never fails.
+		panic(errors.E(errors.ErrInternal, err, "computing diff of %s", file))
+	}
+	return diff
+}
+
+// splitLines splits the content into lines, keeping the line terminators.
+// Unlike [difflib.SplitLines], no empty line is added after the trailing
+// newline, so empty content has no lines.
+func splitLines(content string) []string {
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	} else {
+		lines[len(lines)-1] += "\n"
+	}
+	return lines
+}
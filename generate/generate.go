@@ -9,25 +9,33 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"runtime"
 	"sort"
 	"strings"
+	"sync"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/terramate-io/terramate/config"
+	"github.com/terramate-io/terramate/config/filter"
 	"github.com/terramate-io/terramate/errors"
 	"github.com/terramate-io/terramate/event"
 	"github.com/terramate-io/terramate/generate/genfile"
 	"github.com/terramate-io/terramate/generate/genhcl"
+	"github.com/terramate-io/terramate/generate/genstruct"
 	"github.com/terramate-io/terramate/globals"
 	"github.com/terramate-io/terramate/hcl"
 	"github.com/terramate-io/terramate/hcl/eval"
 	"github.com/terramate-io/terramate/hcl/info"
+	"github.com/terramate-io/terramate/lets"
 	"github.com/terramate-io/terramate/project"
 	"github.com/terramate-io/terramate/stack"
 	"github.com/terramate-io/terramate/stdlib"
 )
 
+// maxWorkers is the maximum number of stacks generated concurrently.
+var maxWorkers = runtime.NumCPU()
+
 const (
 	// ErrLoadingGlobals indicates failure loading globals during code generation.
 	ErrLoadingGlobals errors.Kind = "loading globals"
@@ -36,6 +44,10 @@ const (
 	// was not previously generated by Terramate.
 	ErrManualCodeExists errors.Kind = "manually defined code found"
 
+	// ErrManualEdits indicates code generation would replace or delete a
+	// generated file that was manually edited after it was generated.
+	ErrManualEdits errors.Kind = "generated file was manually edited"
+
 	// ErrConflictingConfig indicates that two code generation configurations
 	// are conflicting, like both generates a file with the same name
 	// and would overwrite each other.
@@ -48,10 +60,68 @@ const (
 	// ErrAssertion indicates that code generation configuration
 	// has a failed assertion.
 	ErrAssertion errors.Kind = "assertion failed"
+
+	// ErrPostProcess indicates that the post-processing of a generated
+	// file failed.
+	ErrPostProcess errors.Kind = "post-processing generated file"
 )
 
+// Options are the code generation options.
+type Options struct {
+	// Cache is the cache of the code generated for stacks.
+	// A nil cache disables caching.
+	Cache *Cache
+
+	// Force overwrites and deletes generated files even if they were
+	// manually edited after they were generated.
+	Force bool
+
+	// Stacks restricts code generation to the given stacks. A nil list
+	// selects all stacks. When stacks are selected, the files generated
+	// outside stacks (context=root) are not generated and orphaned files
+	// are not deleted.
+	Stacks project.Paths
+
+	// Labels restricts code generation to the files whose label matches any
+	// of the given path globs (see [filter.MatchPath]). Files whose label
+	// doesn't match are neither written nor deleted, and orphaned files are
+	// not deleted. The globs must be validated with [filter.ValidatePathGlob].
+	Labels []string
+
+	dryRun bool
+}
+
+// selectsStack tells if the stack at dir is selected by the options.
+func (opts Options) selectsStack(dir project.Path) bool {
+	if opts.Stacks == nil {
+		return true
+	}
+	for _, selected := range opts.Stacks {
+		if selected == dir {
+			return true
+		}
+	}
+	return false
+}
+
+// selectsLabel tells if the file with the given label is selected by the
+// options.
+func (opts Options) selectsLabel(label string) bool {
+	if len(opts.Labels) == 0 {
+		return true
+	}
+	for _, pattern := range opts.Labels {
+		if filter.MatchPath(pattern, path.Clean(label)) {
+			return true
+		}
+	}
+	return false
+}
+
 // GenFile represents a generated file loaded from a Terramate configuration.
 type GenFile interface {
+	// Type is the type of the origin generate block, eg.: generate_hcl.
+	Type() string
 	// Header is the header of the generated file, if any.
 	Header() string
 	// Body is the body of the generated file, if any.
@@ -66,6 +136,8 @@ type GenFile interface {
 	Condition() bool
 	// Asserts is the origin generate block assert blocks.
 	Asserts() []config.Assert
+	// PostProcess is the origin generate block post-processors.
+	PostProcess() []hcl.PostProcessConfig
 }
 
 // LoadResult represents all generated files of a specific directory.
@@ -105,7 +177,7 @@ func Load(root *config.Root, vendorDir project.Path) ([]LoadResult, error) {
 			continue
 		}
 
-		generated, err := loadStackCodeCfgs(root, st.Stack, loadres.Globals, vendorDir, nil)
+		generated, _, err := loadStackCodeCfgs(root, st.Stack, loadres.Globals, vendorDir, nil)
 		if err != nil {
 			res.Err = errors.E(err, "while loading configs of stack %s", st.Dir())
 			results[i] = res
@@ -123,19 +195,19 @@ func Load(root *config.Root, vendorDir project.Path) ([]LoadResult, error) {
 		evalctx := eval.NewContext(stdlib.Functions(dircfg.HostDir()))
 
 		var generated []GenFile
-		for _, block := range dircfg.Node.Generate.Files {
-			if block.Context != genfile.RootContext {
-				continue
+		for _, block := range rootContextBlocks(dircfg) {
+			file, err := block.eval(evalctx)
+			if err == nil {
+				targetDir := project.NewPath(path.Clean("/" + path.Dir(block.label)))
+				file, err = withPostProcessing(root, targetDir, file)
 			}
-
-			file, err := genfile.Eval(block, evalctx)
 			if err != nil {
 				res.Err = errors.L(res.Err, err).AsError()
 				results = append(results, res)
 				continue
 			}
 
-			generated = append(generated, file)
+			generated = append(generated, withProvenance(root, file))
 		}
 		if len(generated) > 0 {
 			res.Files = generated
@@ -168,6 +240,17 @@ func Load(root *config.Root, vendorDir project.Path) ([]LoadResult, error) {
 // calls to communicate each vendor request. If the caller is not interested on
 // [event.VendorRequest] events just pass a nil channel.
 //
+// Stacks are generated concurrently and, if opts.Cache is not nil, stacks
+// whose configuration didn't change since they were cached skip the evaluation
+// of their globals and generate blocks. See [Cache] for details.
+//
+// Generated files with a provenance header that were manually edited after
+// they were generated are not overwritten nor deleted, unless opts.Force is
+// set, and the failure is reported with [ErrManualEdits].
+//
+// The opts.Stacks and opts.Labels options restrict the generation to some
+// stacks and files only, see [Options] for details.
+//
 // It will return a report including details of which directories succeed and
 // failed on code generation, any failure found is added to the report but does
 // not abort the overall code generation process, so partial results can be
@@ -176,20 +259,53 @@ func Do(
 	root *config.Root,
 	vendorDir project.Path,
 	vendorRequests chan<- event.VendorRequest,
+	opts Options,
+) Report {
+	return do(root, vendorDir, vendorRequests, opts)
+}
+
+// DryRun computes the same report as [Do] but without changing any file
+// on the file system. The report results also include an unified diff
+// of each file that would be created, changed or deleted.
+func DryRun(
+	root *config.Root,
+	vendorDir project.Path,
+	vendorRequests chan<- event.VendorRequest,
+	opts Options,
+) Report {
+	opts.dryRun = true
+	return do(root, vendorDir, vendorRequests, opts)
+}
+
+func do(
+	root *config.Root,
+	vendorDir project.Path,
+	vendorRequests chan<- event.VendorRequest,
+	opts Options,
 ) Report {
-	stackReport := forEachStack(root, vendorDir,
-		vendorRequests, doStackGeneration)
-	rootReport := doRootGeneration(root)
+	stackReport := forEachStack(root, func(root *config.Root, stack *config.Stack) dirReport {
+		if !opts.selectsStack(stack.Dir) {
+			return dirReport{}
+		}
+		return doStackGeneration(root, stack, vendorDir, vendorRequests, opts)
+	})
+	if opts.Stacks != nil {
+		return stackReport
+	}
+	rootReport := doRootGeneration(root, opts)
 	report := mergeReports(stackReport, rootReport)
-	return cleanupOrphaned(root, report)
+	if len(opts.Labels) > 0 {
+		return report
+	}
+	return cleanupOrphaned(root, report, opts)
 }
 
 func doStackGeneration(
 	root *config.Root,
 	stack *config.Stack,
-	globals *eval.Object,
 	vendorDir project.Path,
 	vendorRequests chan<- event.VendorRequest,
+	opts Options,
 ) dirReport {
 	stackpath := stack.HostDir(root)
 	logger := log.With().
@@ -201,7 +317,7 @@ func doStackGeneration(
 
 	logger.Debug().Msg("generating files")
 
-	generated, err := loadStackCodeCfgs(root, stack, globals, vendorDir, vendorRequests)
+	generated, err := loadStackFiles(root, stack, vendorDir, vendorRequests, opts.Cache)
 	if err != nil {
 		report.err = err
 		return report
@@ -229,6 +345,12 @@ func doStackGeneration(
 		return report
 	}
 
+	for filename := range allFiles {
+		if !opts.selectsLabel(filename) {
+			delete(allFiles, filename)
+		}
+	}
+
 	logger.Debug().Msg("saving generated files")
 
 	for _, file := range generated {
@@ -238,6 +360,11 @@ func doStackGeneration(
 			Str("filename", filename).
 			Logger()
 
+		if !opts.selectsLabel(filename) {
+			logger.Debug().Msg("label not selected, ignoring file")
+			continue
+		}
+
 		if !file.Condition() {
 			logger.Debug().Msg("condition is false, ignoring file")
 			continue
@@ -249,7 +376,7 @@ func doStackGeneration(
 		oldFileBody, oldExists := allFiles[filename]
 
 		if !oldExists || oldFileBody != body {
-			err := writeGeneratedCode(path, file)
+			err := writeGeneratedCode(path, file, opts)
 			if err != nil {
 				report.err = errors.E(err, "saving file %q", filename)
 				return report
@@ -263,6 +390,10 @@ func doStackGeneration(
 				Msg("created file")
 
 			report.addCreatedFile(filename)
+			if opts.dryRun {
+				report.addDiff(filename, unifiedDiff(
+					stack.Dir.Join(filename), "", false, body, true))
+			}
 		} else {
 			delete(allFiles, filename)
 			if body != oldFileBody {
@@ -272,11 +403,23 @@ func doStackGeneration(
 					Msg("changed file")
 
 				report.addChangedFile(filename)
+				if opts.dryRun {
+					report.addDiff(filename, unifiedDiff(
+						stack.Dir.Join(filename), oldFileBody, true, body, true))
+				}
 			}
 		}
 	}
 
-	for filename := range allFiles {
+	for filename, oldFileBody := range allFiles {
+		if !opts.Force {
+			err := checkProvenance(filepath.Join(stackpath, filename), oldFileBody)
+			if err != nil {
+				report.err = errors.E(err, "removing file %s", filename)
+				return report
+			}
+		}
+
 		log.Info().
 			Stringer("stack", stack.Dir).
 			Str("file", filename).
@@ -284,6 +427,12 @@ func doStackGeneration(
 
 		report.addDeletedFile(filename)
 
+		if opts.dryRun {
+			report.addDiff(filename, unifiedDiff(
+				stack.Dir.Join(filename), oldFileBody, true, "", false))
+			continue
+		}
+
 		path := filepath.Join(stackpath, filename)
 		err = os.Remove(path)
 		if err != nil {
@@ -298,82 +447,90 @@ func doStackGeneration(
 	return report
 }
 
-func doRootGeneration(root *config.Root) Report {
+func doRootGeneration(root *config.Root, opts Options) Report {
 	logger := log.With().
 		Str("action", "generate.doRootGeneration").
 		Logger()
 
 	report := Report{}
+
+	files, failedDir, err := loadRootFiles(root)
+	if err != nil {
+		report.addFailure(failedDir, err)
+		return report
+	}
+
+	logger.Debug().Msg("checking context=root conflicts")
+
+	errsmap := checkFileConflict(files)
+	if len(errsmap) > 0 {
+		if len(errsmap) > 0 {
+			for file, err := range errsmap {
+				targetDir := path.Dir(file)
+				report.addFailure(project.NewPath(targetDir), err)
+			}
+			return report
+		}
+	}
+
+	logger.Debug().Msg("no conflicts found")
+
+	selected := make([]GenFile, 0, len(files))
+	for _, file := range files {
+		if opts.selectsLabel(file.Label()) {
+			selected = append(selected, file)
+		}
+	}
+
+	generateRootFiles(root, selected, &report, opts)
+	return report
+}
+
+// loadRootFiles loads and evaluates all the files generated by blocks using
+// context=root. On failure it also returns the target dir of the failed block.
+func loadRootFiles(root *config.Root) ([]GenFile, project.Path, error) {
+	logger := log.With().
+		Str("action", "generate.loadRootFiles()").
+		Logger()
+
 	evalctx := eval.NewContext(stdlib.Functions(root.HostDir()))
 	evalctx.SetNamespace("terramate", root.Runtime())
 
 	var files []GenFile
 	for _, cfg := range root.Tree().AsList() {
-		logger = logger.With().
+		logger := logger.With().
 			Stringer("configDir", cfg.Dir()).
-			Bool("isEmpty", cfg.IsEmptyConfig()).
-			Bool("isStack", cfg.IsStack()).
 			Logger()
 
-		if cfg.IsEmptyConfig() || cfg.IsStack() {
-			logger.Debug().Msg("ignoring directory")
-			continue
-		}
-
-		blocks := cfg.Node.Generate.Files
-		if len(blocks) == 0 {
-			continue
-		}
-
-		for _, block := range blocks {
-			logger := genFileBlockLogger(logger, block)
-
-			if block.Context != genfile.RootContext {
-				logger.Debug().Msg("ignoring block")
-				continue
-			}
+		for _, block := range rootContextBlocks(cfg) {
+			logger := genBlockLogger(logger, block.typ, block.label, genfile.RootContext)
 
 			// TODO(i4k): generate report must be redesigned for context=root
 			// Here we use path.Clean("/"+path.Dir(label)) to ensure the
 			// report.Dir is always absolute.
-			targetDir := project.NewPath(path.Clean("/" + path.Dir(block.Label)))
+			targetDir := project.NewPath(path.Clean("/" + path.Dir(block.label)))
 			err := validateRootGenerateBlock(root, block)
 			if err != nil {
-				report.addFailure(targetDir, err)
-				return report
+				return nil, targetDir, err
 			}
 
 			logger.Debug().Msg("block validated successfully")
 
-			file, err := genfile.Eval(block, evalctx)
+			file, err := block.eval(evalctx)
 			if err != nil {
-				report.addFailure(targetDir, err)
-				return report
+				return nil, targetDir, err
 			}
 
 			logger.Debug().Msg("block evaluated successfully")
 
-			files = append(files, file)
-		}
-	}
-
-	logger.Debug().Msg("checking generate_file.context=root conflicts")
-
-	errsmap := checkFileConflict(files)
-	if len(errsmap) > 0 {
-		if len(errsmap) > 0 {
-			for file, err := range errsmap {
-				targetDir := path.Dir(file)
-				report.addFailure(project.NewPath(targetDir), err)
+			file, err = withPostProcessing(root, targetDir, file)
+			if err != nil {
+				return nil, targetDir, err
 			}
-			return report
+			files = append(files, withProvenance(root, file))
 		}
 	}
-
-	logger.Debug().Msg("no conflicts found")
-
-	generateRootFiles(root, files, &report)
-	return report
+	return files, project.Path{}, nil
 }
 
 func handleAsserts(rootdir string, dir string, asserts []config.Assert) error {
@@ -476,7 +633,7 @@ processSubdirs:
 				return nil, errors.E(err, "checking if file is generated %q", file)
 			}
 
-			if hasGenHCLHeader(string(data)) {
+			if hasGenCodeHeader(string(data)) {
 				genfiles = append(genfiles, filepath.ToSlash(
 					filepath.Join(relSubdir, entry.Name())))
 			}
@@ -488,7 +645,8 @@ processSubdirs:
 
 // DetectOutdated will verify if the given config has outdated code
 // and return a list of filenames that are outdated, ordered lexicographically.
-func DetectOutdated(root *config.Root, vendorDir project.Path) ([]string, error) {
+// Stacks are checked concurrently and the cache is used the same way as in [Do].
+func DetectOutdated(root *config.Root, vendorDir project.Path, cache *Cache) ([]string, error) {
 	logger := log.With().
 		Str("action", "generate.DetectOutdated()").
 		Logger()
@@ -503,8 +661,17 @@ func DetectOutdated(root *config.Root, vendorDir project.Path) ([]string, error)
 
 	logger.Debug().Msg("checking outdated code inside stacks")
 
-	for _, stack := range stacks {
-		outdated, err := stackOutdated(root, stack.Stack, vendorDir)
+	results := make([]struct {
+		outdated []string
+		err      error
+	}, len(stacks))
+
+	parallel(len(stacks), func(i int) {
+		results[i].outdated, results[i].err = stackOutdated(root, stacks[i].Stack, vendorDir, cache)
+	})
+
+	for i, stack := range stacks {
+		outdated, err := results[i].outdated, results[i].err
 		if err != nil {
 			errs.Append(err)
 			continue
@@ -528,6 +695,19 @@ func DetectOutdated(root *config.Root, vendorDir project.Path) ([]string, error)
 		return outdatedFiles, nil
 	}
 
+	logger.Debug().Msg("checking outdated code generated with context=root")
+
+	rootFiles, _, err := loadRootFiles(root)
+	if err != nil {
+		errs.Append(err)
+	} else {
+		outdated := newStringSet()
+		errs.Append(updateOutdatedFiles(root.HostDir(), rootFiles, outdated))
+		for _, file := range outdated.slice() {
+			outdatedFiles = append(outdatedFiles, path.Clean(file)[1:])
+		}
+	}
+
 	logger.Debug().Msg("checking for orphaned files")
 
 	orphanedFiles, err := ListGenFiles(root, root.HostDir())
@@ -539,7 +719,12 @@ func DetectOutdated(root *config.Root, vendorDir project.Path) ([]string, error)
 		return nil, err
 	}
 
-	outdatedFiles = append(outdatedFiles, orphanedFiles...)
+	rootLabels := rootContextLabels(root)
+	for _, file := range orphanedFiles {
+		if _, ok := rootLabels["/"+file]; !ok {
+			outdatedFiles = append(outdatedFiles, file)
+		}
+	}
 	sort.Strings(outdatedFiles)
 	return outdatedFiles, nil
 }
@@ -551,19 +736,14 @@ func stackOutdated(
 	root *config.Root,
 	st *config.Stack,
 	vendorDir project.Path,
+	cache *Cache,
 ) ([]string, error) {
 	logger := log.With().
 		Str("action", "generate.stackOutdated").
 		Stringer("stack", st).
 		Logger()
 
-	report := globals.ForStack(root, st)
-	if err := report.AsError(); err != nil {
-		return nil, errors.E(err, "checking for outdated code")
-	}
-
-	globals := report.Globals
-	generated, err := loadStackCodeCfgs(root, st, globals, vendorDir, nil)
+	generated, err := loadStackFiles(root, st, vendorDir, nil, cache)
 	if err != nil {
 		return nil, err
 	}
@@ -666,24 +846,38 @@ func updateOutdatedFiles(
 	return nil
 }
 
-func writeGeneratedCode(target string, genfile GenFile) error {
+func writeGeneratedCode(target string, file GenFile, opts Options) error {
 	logger := log.With().
 		Str("action", "writeGeneratedCode()").
 		Str("file", target).
 		Logger()
 
-	body := genfile.Header() + genfile.Body()
+	body := file.Header() + file.Body()
 
-	if genfile.Header() != "" {
+	if file.Header() != "" && file.Type() != genfile.BlockType {
 		// WHY: some file generation strategies don't provide
 		// headers, like generate_file, so we can't detect
 		// if we are overwriting a Terramate generated file.
+		// The generate_file header is optional (comment_prefix) so
+		// files generated before it was enabled are also allowed.
 		logger.Trace().Msg("checking file can be written")
 		if err := checkFileCanBeOverwritten(target); err != nil {
 			return err
 		}
 	}
 
+	if !opts.Force {
+		logger.Trace().Msg("checking file was not manually edited")
+		if err := checkFileNotEdited(target); err != nil {
+			return err
+		}
+	}
+
+	if opts.dryRun {
+		logger.Trace().Msg("dry run, not writing file")
+		return nil
+	}
+
 	logger.Trace().Msg("creating intermediary dirs")
 	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
 		return err
@@ -698,6 +892,16 @@ func checkFileCanBeOverwritten(path string) error {
 	return err
 }
 
+// checkFileNotEdited checks that the file at path, if it exists, was not
+// manually edited after it was generated. See [checkProvenance].
+func checkFileNotEdited(path string) error {
+	content, found, err := readFile(path)
+	if err != nil || !found {
+		return err
+	}
+	return checkProvenance(path, content)
+}
+
 // readGeneratedFile will read the generated file at the given path.
 // It returns an error if it can't read the file or if the file is not
 // a Terramate generated file.
@@ -724,7 +928,7 @@ func readGeneratedFile(path string) (string, bool, error) {
 
 	logger.Trace().Msg("Check if file has terramate header.")
 
-	if hasGenHCLHeader(data) {
+	if hasGenCodeHeader(data) {
 		return data, true, nil
 	}
 
@@ -763,20 +967,11 @@ func readFile(path string) (string, bool, error) {
 	return string(data), true, nil
 }
 
-type forEachStackFunc func(
-	*config.Root,
-	*config.Stack,
-	*eval.Object,
-	project.Path,
-	chan<- event.VendorRequest,
-) dirReport
+type forEachStackFunc func(*config.Root, *config.Stack) dirReport
 
-func forEachStack(
-	root *config.Root,
-	vendorDir project.Path,
-	vendorRequests chan<- event.VendorRequest,
-	fn forEachStackFunc,
-) Report {
+// forEachStack calls fn for each stack of the project concurrently, using
+// a bounded pool of workers. The stack reports are merged in the stacks order.
+func forEachStack(root *config.Root, fn forEachStackFunc) Report {
 	logger := log.With().
 		Str("action", "generate.forEachStack()").
 		Str("root", root.HostDir()).
@@ -792,26 +987,96 @@ func forEachStack(
 		return report
 	}
 
-	for _, elem := range stacks {
-		logger := logger.With().
-			Stringer("stack", elem).
-			Logger()
+	stackReports := make([]dirReport, len(stacks))
+	parallel(len(stacks), func(i int) {
+		logger.Trace().
+			Stringer("stack", stacks[i]).
+			Msg("Calling stack callback.")
 
-		logger.Trace().Msg("Load stack globals.")
+		stackReports[i] = fn(root, stacks[i].Stack)
+	})
 
-		globalsReport := globals.ForStack(root, elem.Stack)
-		if err := globalsReport.AsError(); err != nil {
-			report.addFailure(elem.Dir(), errors.E(ErrLoadingGlobals, err))
-			continue
+	for i, elem := range stacks {
+		report.addDirReport(elem.Dir(), stackReports[i])
+	}
+
+	return report
+}
+
+// parallel calls fn for each index from 0 to n-1 using at most maxWorkers
+// concurrent goroutines and waits for all of them to finish.
+func parallel(n int, fn func(i int)) {
+	workers := maxWorkers
+	if n < workers {
+		workers = n
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// loadStackFiles loads the files generated for the stack. If the stack is
+// cached then its globals and generate blocks are not evaluated at all,
+// otherwise they are evaluated and the result is stored in the cache.
+func loadStackFiles(
+	root *config.Root,
+	st *config.Stack,
+	vendorDir project.Path,
+	vendorRequests chan<- event.VendorRequest,
+	cache *Cache,
+) ([]GenFile, error) {
+	logger := log.With().
+		Str("action", "generate.loadStackFiles()").
+		Stringer("stack", st.Dir).
+		Logger()
+
+	key, cacheable := cache.key(root, st, vendorDir)
+	if cacheable {
+		if entry, ok := cache.load(root, key); ok {
+			logger.Debug().Msg("using cached generated files")
+
+			// replays assertion warnings.
+			if err := handleAsserts(root.HostDir(), st.HostDir(root), entry.Asserts); err != nil {
+				return nil, err
+			}
+			files := make([]GenFile, len(entry.Files))
+			for i, file := range entry.Files {
+				files[i] = file
+			}
+			return files, nil
 		}
+	}
 
-		logger.Trace().Msg("Calling stack callback.")
+	logger.Trace().Msg("Load stack globals.")
 
-		stackReport := fn(root, elem.Stack, globalsReport.Globals, vendorDir, vendorRequests)
-		report.addDirReport(elem.Dir(), stackReport)
+	globalsReport := globals.ForStack(root, st)
+	if err := globalsReport.AsError(); err != nil {
+		return nil, errors.E(ErrLoadingGlobals, err)
 	}
 
-	return report
+	files, asserts, err := loadStackCodeCfgs(root, st, globalsReport.Globals, vendorDir, vendorRequests)
+	if err != nil {
+		return nil, err
+	}
+
+	if cacheable {
+		cache.store(key, files, asserts)
+	}
+	return files, nil
 }
 
 func allStackGeneratedFiles(
@@ -828,11 +1093,18 @@ func allStackGeneratedFiles(
 	// WHY: not all Terramate files have headers and can be detected
 	// so we use the list of files to be generated to check for these
 	// They may or not exist.
-	for _, genfile := range genfiles {
+	hiddenFiles := map[string]struct{}{}
+	for _, file := range genfiles {
 		// Files that have header or that are inside the stack dir
-		// can be detected by ListGenFiles
-		if genfile.Header() == "" {
-			files = append(files, genfile.Label())
+		// can be detected by ListGenFiles. The generate_file files are
+		// always added because they may exist without a header yet.
+		if file.Header() == "" || file.Type() == genfile.BlockType {
+			files = append(files, file.Label())
+		} else if insideSkippedDir(file.Label()) {
+			// ListGenFiles ignores dot dirs, like .github, so these
+			// files are only considered if they have a header.
+			files = append(files, file.Label())
+			hiddenFiles[file.Label()] = struct{}{}
 		}
 	}
 
@@ -846,13 +1118,29 @@ func allStackGeneratedFiles(
 			return nil, errors.E(err, "reading generated file")
 		}
 
+		if _, hidden := hiddenFiles[filename]; hidden && !hasGenCodeHeader(string(body)) {
+			continue
+		}
+
 		allFiles[filename] = string(body)
 	}
 
 	return allFiles, nil
 }
 
-func generateRootFiles(root *config.Root, genfiles []GenFile, report *Report) {
+// insideSkippedDir tells if the relative path is inside a dir ignored by
+// Terramate, like dot dirs.
+func insideSkippedDir(relpath string) bool {
+	dirs := strings.Split(path.Dir(relpath), "/")
+	for _, dir := range dirs {
+		if dir != "." && dir != "" && config.Skip(dir) {
+			return true
+		}
+	}
+	return false
+}
+
+func generateRootFiles(root *config.Root, genfiles []GenFile, report *Report, opts Options) {
 	logger := log.With().
 		Str("action", "generate.generateRootFiles()").
 		Logger()
@@ -921,8 +1209,25 @@ func generateRootFiles(root *config.Root, genfiles []GenFile, report *Report) {
 			dirReport := dirReport{}
 			dir := path.Dir(label)
 
-			err := os.Remove(abspath)
-			if err != nil {
+			if !opts.Force {
+				if err := checkFileNotEdited(abspath); err != nil {
+					dirReport.err = errors.E(err, "deleting file")
+					report.addDirReport(project.NewPath(dir), dirReport)
+					continue
+				}
+			}
+
+			if opts.dryRun {
+				filename := path.Base(label)
+				diskContent, _, err := readFile(abspath)
+				if err != nil {
+					dirReport.err = errors.E(err, "reading file")
+				} else {
+					dirReport.addDeletedFile(filename)
+					dirReport.addDiff(filename, unifiedDiff(
+						project.NewPath(path.Clean("/"+label)), diskContent, true, "", false))
+				}
+			} else if err := os.Remove(abspath); err != nil {
 				dirReport.err = errors.E(err, "deleting file")
 			} else {
 				dirReport.addDeletedFile(path.Base(label))
@@ -952,7 +1257,7 @@ func generateRootFiles(root *config.Root, genfiles []GenFile, report *Report) {
 				Bool("fileChanged", body != diskContent).
 				Msg("writing file")
 
-			err := writeGeneratedCode(abspath, genfile)
+			err := writeGeneratedCode(abspath, genfile, opts)
 			if err != nil {
 				dirReport.err = errors.E(err, "saving file %s", label)
 				report.addDirReport(dir, dirReport)
@@ -964,8 +1269,16 @@ func generateRootFiles(root *config.Root, genfiles []GenFile, report *Report) {
 
 		if !existOnDisk {
 			dirReport.addCreatedFile(filename)
+			if opts.dryRun {
+				dirReport.addDiff(filename, unifiedDiff(
+					project.NewPath(path.Clean("/"+label)), "", false, body, true))
+			}
 		} else if body != diskContent {
 			dirReport.addChangedFile(label)
+			if opts.dryRun {
+				dirReport.addDiff(label, unifiedDiff(
+					project.NewPath(path.Clean("/"+label)), diskContent, true, body, true))
+			}
 		} else {
 			logger.Debug().Msg("nothing to do, file on disk is up to date.")
 		}
@@ -975,11 +1288,7 @@ func generateRootFiles(root *config.Root, genfiles []GenFile, report *Report) {
 }
 
 func genFileLogger(logger zerolog.Logger, genfile GenFile) zerolog.Logger {
-	return genBlockLogger(logger, "generate_file", genfile.Label(), genfile.Context())
-}
-
-func genFileBlockLogger(logger zerolog.Logger, block hcl.GenFileBlock) zerolog.Logger {
-	return genBlockLogger(logger, "generate_file", block.Label, block.Context)
+	return genBlockLogger(logger, genfile.Type(), genfile.Label(), genfile.Context())
 }
 
 func genBlockLogger(logger zerolog.Logger, blockname, label, context string) zerolog.Logger {
@@ -989,15 +1298,20 @@ func genBlockLogger(logger zerolog.Logger, blockname, label, context string) zer
 		Logger()
 }
 
-func hasGenHCLHeader(code string) bool {
+func hasGenCodeHeader(code string) bool {
 	// When changing headers we need to support old ones (or break).
 	// For now keeping them here, to avoid breaks.
-	for _, header := range []string{genhcl.Header, genhcl.HeaderV0} {
+	for _, header := range []string{
+		genhcl.Header,
+		genhcl.HeaderV0,
+		genstruct.JSONHeader,
+		genstruct.YAMLHeader,
+	} {
 		if strings.HasPrefix(code, header) {
 			return true
 		}
 	}
-	return false
+	return hasProvenanceHeader(code)
 }
 
 func validateStackGeneratedFiles(root *config.Root, stackpath string, generated []GenFile) error {
@@ -1076,11 +1390,11 @@ func validateStackGeneratedFiles(root *config.Root, stackpath string, generated
 	return errs.AsError()
 }
 
-func validateRootGenerateBlock(root *config.Root, block hcl.GenFileBlock) error {
-	target := block.Label
+func validateRootGenerateBlock(root *config.Root, block rootBlock) error {
+	target := block.label
 	if !path.IsAbs(target) {
 		return errors.E(
-			ErrInvalidGenBlockLabel, block.Range,
+			ErrInvalidGenBlockLabel, block.rng,
 			"%s: is not an absolute path", target,
 		)
 	}
@@ -1105,7 +1419,7 @@ func validateRootGenerateBlock(root *config.Root, block hcl.GenFileBlock) error
 			}
 			return errors.E(
 				ErrInvalidGenBlockLabel, err,
-				block.Range,
+				block.rng,
 				"%s: checking if dest dir is a symlink",
 				target,
 			)
@@ -1113,7 +1427,7 @@ func validateRootGenerateBlock(root *config.Root, block hcl.GenFileBlock) error
 		if (info.Mode() & fs.ModeSymlink) == fs.ModeSymlink {
 			return errors.E(
 				ErrInvalidGenBlockLabel, err,
-				block.Range,
+				block.rng,
 				"%s: generates code inside a symlink",
 				target,
 			)
@@ -1121,9 +1435,10 @@ func validateRootGenerateBlock(root *config.Root, block hcl.GenFileBlock) error
 
 		if config.IsStack(root, destdir) {
 			return errors.E(ErrInvalidGenBlockLabel,
-				block.Range,
-				"%s: generate_file.context=root generates inside a stack %s",
+				block.rng,
+				"%s: %s.context=root generates inside a stack %s",
 				target,
+				block.typ,
 				project.PrjAbsPath(root.HostDir(), destdir),
 			)
 		}
@@ -1163,15 +1478,21 @@ func (ss *stringSet) slice() []string {
 	return res
 }
 
+// checkFileConflict checks that no two files with condition = true are
+// generated at the same path, no matter the generate block type. The labels
+// are compared after cleaning, so "dir/file" and "dir//file" conflict.
 func checkFileConflict(generated []GenFile) map[string]error {
 	genset := map[string]GenFile{}
 	errsmap := map[string]error{}
 	for _, file := range generated {
-		if other, ok := genset[file.Label()]; ok && file.Condition() {
-			errsmap[file.Label()] = errors.E(ErrConflictingConfig,
-				"configs from %q and %q generate a file with same name %q have "+
+		target := path.Clean(file.Label())
+		if other, ok := genset[target]; ok && file.Condition() {
+			errsmap[target] = errors.E(ErrConflictingConfig,
+				"%s from %q and %s from %q generate a file with same name %q have "+
 					"`condition = true`",
+				file.Type(),
 				file.Range().Path(),
+				other.Type(),
 				other.Range().Path(),
 				file.Label(),
 			)
@@ -1180,7 +1501,7 @@ func checkFileConflict(generated []GenFile) map[string]error {
 		if !file.Condition() {
 			continue
 		}
-		genset[file.Label()] = file
+		genset[target] = file
 	}
 	return errsmap
 }
@@ -1228,28 +1549,35 @@ func loadAsserts(root *config.Root, st *config.Stack, globals *eval.Object) ([]c
 	return asserts, nil
 }
 
+// loadStackCodeCfgs loads and evaluates all the files generated for the stack
+// and returns them together with all the evaluated assertions.
 func loadStackCodeCfgs(
 	root *config.Root,
 	st *config.Stack,
 	globals *eval.Object,
 	vendorDir project.Path,
 	vendorRequests chan<- event.VendorRequest,
-) ([]GenFile, error) {
+) ([]GenFile, []config.Assert, error) {
 	asserts, err := loadAsserts(root, st, globals)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	var genfilesConfigs []GenFile
 
 	genfiles, err := genfile.Load(root, st, globals, vendorDir, vendorRequests)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	genhcls, err := genhcl.Load(root, st, globals, vendorDir, vendorRequests)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	genstructs, err := genstruct.Load(root, st, globals, vendorDir, vendorRequests)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	for _, f := range genfiles {
@@ -1260,23 +1588,35 @@ func loadStackCodeCfgs(
 		genfilesConfigs = append(genfilesConfigs, f)
 	}
 
+	for _, f := range genstructs {
+		genfilesConfigs = append(genfilesConfigs, f)
+	}
+
 	sort.Slice(genfilesConfigs, func(i, j int) bool {
 		return genfilesConfigs[i].Label() < genfilesConfigs[j].Label()
 	})
 
+	for i, file := range genfilesConfigs {
+		file, err := withPostProcessing(root, st.Dir, file)
+		if err != nil {
+			return nil, nil, err
+		}
+		genfilesConfigs[i] = withProvenance(root, file)
+	}
+
 	for _, gen := range genfilesConfigs {
 		asserts = append(asserts, gen.Asserts()...)
 	}
 
 	err = handleAsserts(root.HostDir(), st.HostDir(root), asserts)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return genfilesConfigs, nil
+	return genfilesConfigs, asserts, nil
 }
 
-func cleanupOrphaned(root *config.Root, report Report) Report {
+func cleanupOrphaned(root *config.Root, report Report, opts Options) Report {
 	logger := log.With().
 		Str("action", "generate.cleanupOrphaned()").
 		Logger()
@@ -1297,12 +1637,43 @@ func cleanupOrphaned(root *config.Root, report Report) Report {
 	}
 
 	deletedFiles := map[project.Path][]string{}
+	deletedDiffs := map[project.Path]map[string]string{}
 	deleteFailures := map[project.Path]*errors.List{}
 
+	// WHY: files generated by context=root blocks live outside stacks and
+	// may have a header, but they are handled by the root generation.
+	rootLabels := rootContextLabels(root)
+
 	for _, genfile := range orphanedGenFiles {
+		if _, ok := rootLabels["/"+genfile]; ok {
+			logger.Debug().
+				Str("file", genfile).
+				Msg("ignoring file generated with context=root")
+			continue
+		}
+
 		genfileAbspath := filepath.Join(root.HostDir(), genfile)
 		dir := project.NewPath("/" + filepath.ToSlash(filepath.Dir(genfile)))
-		if err := os.Remove(genfileAbspath); err != nil {
+		filename := filepath.Base(genfile)
+
+		var err error
+		if !opts.Force {
+			err = checkFileNotEdited(genfileAbspath)
+		}
+		if err == nil && opts.dryRun {
+			var content string
+			content, _, err = readFile(genfileAbspath)
+			if err == nil {
+				if deletedDiffs[dir] == nil {
+					deletedDiffs[dir] = map[string]string{}
+				}
+				deletedDiffs[dir][filename] = unifiedDiff(
+					dir.Join(filename), content, true, "", false)
+			}
+		} else if err == nil {
+			err = os.Remove(genfileAbspath)
+		}
+		if err != nil {
 			if deleteFailures[dir] == nil {
 				deleteFailures[dir] = errors.L()
 			}
@@ -1310,8 +1681,6 @@ func cleanupOrphaned(root *config.Root, report Report) Report {
 			continue
 		}
 
-		filename := filepath.Base(genfile)
-
 		log.Info().
 			Stringer("dir", dir).
 			Str("file", filename).
@@ -1328,6 +1697,7 @@ func cleanupOrphaned(root *config.Root, report Report) Report {
 			Result: Result{
 				Dir:     failedDir,
 				Deleted: delFiles,
+				Diffs:   deletedDiffs[failedDir],
 			},
 			Error: errs,
 		})
@@ -1337,9 +1707,78 @@ func cleanupOrphaned(root *config.Root, report Report) Report {
 		report.Successes = append(report.Successes, Result{
 			Dir:     dir,
 			Deleted: deletedFiles,
+			Diffs:   deletedDiffs[dir],
 		})
 	}
 
 	report.sort()
 	return report
 }
+
+// rootContextLabels returns the labels of all generate blocks of the project
+// using context=root.
+func rootContextLabels(root *config.Root) map[string]struct{} {
+	labels := map[string]struct{}{}
+	for _, cfg := range root.Tree().AsList() {
+		for _, block := range rootContextBlocks(cfg) {
+			labels[path.Clean(block.label)] = struct{}{}
+		}
+	}
+	return labels
+}
+
+// rootBlock is a generate block using context=root.
+type rootBlock struct {
+	typ   string
+	label string
+	rng   info.Range
+	eval  func(*eval.Context) (GenFile, error)
+}
+
+// rootContextBlocks returns the generate blocks using context=root of
+// the given config dir. Stacks are ignored since their blocks always use
+// the stack context.
+func rootContextBlocks(cfg *config.Tree) []rootBlock {
+	if cfg.IsEmptyConfig() || cfg.IsStack() {
+		return nil
+	}
+
+	var blocks []rootBlock
+	for _, block := range cfg.Node.Generate.Files {
+		if block.Context != genfile.RootContext {
+			continue
+		}
+		block := block
+		blocks = append(blocks, rootBlock{
+			typ:   genfile.BlockType,
+			label: block.Label,
+			rng:   block.Range,
+			eval: func(evalctx *eval.Context) (GenFile, error) {
+				scopedLets, err := lets.LoadScoped(cfg)
+				if err != nil {
+					return nil, errors.E("loading lets", err)
+				}
+				return genfile.Eval(cfg.RootDir(), block, scopedLets, evalctx)
+			},
+		})
+	}
+	for _, block := range cfg.Node.Generate.HCLs {
+		if block.Context != genhcl.RootContext {
+			continue
+		}
+		block := block
+		blocks = append(blocks, rootBlock{
+			typ:   genhcl.BlockType,
+			label: block.Label,
+			rng:   block.Range,
+			eval: func(evalctx *eval.Context) (GenFile, error) {
+				scopedLets, err := lets.LoadScoped(cfg)
+				if err != nil {
+					return nil, errors.E("loading lets", err)
+				}
+				return genhcl.Eval(cfg.RootDir(), block, scopedLets, evalctx)
+			},
+		})
+	}
+	return blocks
+}
@@ -61,7 +61,7 @@ func BenchmarkGenerate(b *testing.B) {
 
 	b.StartTimer()
 	for i := 0; i < b.N; i++ {
-		report := generate.Do(root, project.NewPath("/vendor"), nil)
+		report := generate.Do(root, project.NewPath("/vendor"), nil, generate.Options{})
 		if report.HasFailures() {
 			b.Fatal(report.Full())
 		}
@@ -116,9 +116,58 @@ func BenchmarkGenerateRegex(b *testing.B) {
 
 	b.StartTimer()
 	for i := 0; i < b.N; i++ {
-		report := generate.Do(root, project.NewPath("/vendor"), nil)
+		report := generate.Do(root, project.NewPath("/vendor"), nil, generate.Options{})
 		if report.HasFailures() {
 			b.Fatal(report.Full())
 		}
 	}
 }
+
+func BenchmarkGenerateManyStacks(b *testing.B) {
+	// benchmarks the case when there are a lot of stacks inheriting the same
+	// globals and generate blocks, with and without the cache.
+
+	b.StopTimer()
+	s := sandbox.New(b)
+
+	const numStacks = 100
+
+	layout := []string{}
+	for i := 0; i < numStacks; i++ {
+		layout = append(layout, fmt.Sprintf("s:stacks/stack-%d", i))
+	}
+	s.BuildTree(layout)
+
+	s.DirEntry("stacks").CreateFile("config.tm", `
+	globals {
+		list  = [for i in tm_range(100) : "item-${i}"]
+		items = [for i in global.list : "${terramate.stack.name}-${i}"]
+	}
+
+	generate_hcl "main.tf" {
+		content {
+			items = global.items
+		}
+	}
+	`)
+
+	root, err := config.LoadRoot(s.RootDir())
+	assert.NoError(b, err)
+
+	for _, tc := range []struct {
+		name  string
+		cache *generate.Cache
+	}{
+		{name: "no cache"},
+		{name: "cache", cache: generate.NewCache(b.TempDir())},
+	} {
+		b.Run(tc.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				report := generate.Do(root, project.NewPath("/vendor"), nil, generate.Options{Cache: tc.cache})
+				if report.HasFailures() {
+					b.Fatal(report.Full())
+				}
+			}
+		})
+	}
+}
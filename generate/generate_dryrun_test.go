@@ -0,0 +1,155 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package generate_test
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/generate"
+	"github.com/terramate-io/terramate/project"
+	. "github.com/terramate-io/terramate/test/hclwrite/hclutils"
+	"github.com/terramate-io/terramate/test/sandbox"
+)
+
+func TestGenerateDryRunDoesNotChangeFiles(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		"s:stack",
+		"f:stack/manual.txt:manual",
+	})
+	stack := s.DirEntry("stack")
+	stack.CreateFile("config.tm", GenerateFile(
+		Labels("file.txt"),
+		Expr("content", `"line1\nline2\n"`),
+	).String())
+
+	report := generate.DryRun(s.Config(), project.NewPath("/modules"), nil, generate.Options{})
+	assertEqualReports(t, report, generate.Report{
+		Successes: []generate.Result{
+			{
+				Dir:     project.NewPath("/stack"),
+				Created: []string{"file.txt"},
+				Diffs: map[string]string{
+					"file.txt": "--- /dev/null\n" +
+						"+++ b/stack/file.txt\n" +
+						"@@ -0,0 +1,2 @@\n" +
+						"+line1\n" +
+						"+line2\n",
+				},
+			},
+		},
+	})
+	assert.IsTrue(t, report.HasChanges())
+	assert.EqualStrings(t, report.Successes[0].Diffs["file.txt"], report.Diff())
+	_, err := os.Stat(filepath.Join(stack.Path(), "file.txt"))
+	assert.IsTrue(t, errors.Is(err, fs.ErrNotExist), "dry run created file: %v", err)
+
+	s.Generate()
+
+	stack.CreateFile("config.tm", GenerateFile(
+		Labels("file.txt"),
+		Expr("content", `"line1\nchanged\n"`),
+	).String())
+
+	report = generate.DryRun(s.ReloadConfig(), project.NewPath("/modules"), nil, generate.Options{})
+	assertEqualReports(t, report, generate.Report{
+		Successes: []generate.Result{
+			{
+				Dir:     project.NewPath("/stack"),
+				Changed: []string{"file.txt"},
+				Diffs: map[string]string{
+					"file.txt": "--- a/stack/file.txt\n" +
+						"+++ b/stack/file.txt\n" +
+						"@@ -1,2 +1,2 @@\n" +
+						" line1\n" +
+						"-line2\n" +
+						"+changed\n",
+				},
+			},
+		},
+	})
+	assert.EqualStrings(t, "line1\nline2\n", string(stack.ReadFile("file.txt")))
+
+	stack.CreateFile("config.tm", GenerateFile(
+		Labels("file.txt"),
+		Bool("condition", false),
+		Expr("content", `"line1\nchanged\n"`),
+	).String())
+
+	report = generate.DryRun(s.ReloadConfig(), project.NewPath("/modules"), nil, generate.Options{})
+	assertEqualReports(t, report, generate.Report{
+		Successes: []generate.Result{
+			{
+				Dir:     project.NewPath("/stack"),
+				Deleted: []string{"file.txt"},
+				Diffs: map[string]string{
+					"file.txt": "--- a/stack/file.txt\n" +
+						"+++ /dev/null\n" +
+						"@@ -1,2 +0,0 @@\n" +
+						"-line1\n" +
+						"-line2\n",
+				},
+			},
+		},
+	})
+	assert.EqualStrings(t, "line1\nline2\n", string(stack.ReadFile("file.txt")))
+	assert.EqualStrings(t, "manual", string(stack.ReadFile("manual.txt")))
+}
+
+func TestGenerateDryRunOrphanedFiles(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		"s:stack",
+		genfile("dir/orphan.hcl", "a = 1\n"),
+	})
+
+	report := generate.DryRun(s.Config(), project.NewPath("/modules"), nil, generate.Options{})
+	assertEqualReports(t, report, generate.Report{
+		Successes: []generate.Result{
+			{
+				Dir:     project.NewPath("/dir"),
+				Deleted: []string{"orphan.hcl"},
+				Diffs: map[string]string{
+					"orphan.hcl": "--- a/dir/orphan.hcl\n" +
+						"+++ /dev/null\n" +
+						"@@ -1,2 +0,0 @@\n" +
+						"-// TERRAMATE: GENERATED AUTOMATICALLY DO NOT EDIT\n" +
+						"-a = 1\n",
+				},
+			},
+		},
+	})
+
+	orphan := s.DirEntry("dir").ReadFile("orphan.hcl")
+	assert.IsTrue(t, len(orphan) > 0)
+}
+
+func TestGenerateDryRunUpToDate(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		"s:stack",
+	})
+	s.DirEntry("stack").CreateFile("config.tm", GenerateFile(
+		Labels("file.txt"),
+		Str("content", "data"),
+	).String())
+
+	s.Generate()
+
+	report := generate.DryRun(s.Config(), project.NewPath("/modules"), nil, generate.Options{})
+	assertEqualReports(t, report, generate.Report{})
+	assert.IsTrue(t, !report.HasChanges())
+	assert.EqualStrings(t, "", report.Diff())
+}
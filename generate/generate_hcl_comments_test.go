@@ -0,0 +1,146 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package generate_test
+
+import (
+	"testing"
+
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/generate"
+	"github.com/terramate-io/terramate/generate/genhcl"
+	"github.com/terramate-io/terramate/project"
+	"github.com/terramate-io/terramate/test/sandbox"
+)
+
+func TestGenerateHCLPreservesComments(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{"s:stack"})
+	stack := s.DirEntry("stack")
+	stack.CreateFile("globals.tm", `
+globals {
+  regions = ["us-east-1", "eu-west-1"]
+}
+`)
+	stack.CreateFile("gen.tm", `
+# this comment is not part of the content
+generate_hcl "file.tf" {
+  content {
+    # the provider configuration.
+    // it is shared by all stacks.
+    provider "aws" { # inline on the block
+      region = "us-east-1" # the default region
+      /* the profile */
+      profile = "default"
+      # dangling comment
+    }
+
+    tm_comment {
+      text = "stack ${terramate.stack.name}\nhas ${tm_length(global.regions)} regions"
+    }
+    tm_dynamic "provider" {
+      for_each = global.regions
+      labels   = ["aws"]
+      content {
+        # provider for the region
+        alias  = provider.value
+        region = provider.value # from globals
+      }
+    }
+    // end of content
+  }
+}
+`)
+
+	report := s.Generate()
+	assertEqualReports(t, report, generate.Report{
+		Successes: []generate.Result{
+			{
+				Dir:     project.NewPath("/stack"),
+				Created: []string{"file.tf"},
+			},
+		},
+	})
+
+	assert.EqualStrings(t,
+		"// TERRAMATE: GENERATED AUTOMATICALLY DO NOT EDIT\n"+
+			"\n"+
+			"# the provider configuration.\n"+
+			"// it is shared by all stacks.\n"+
+			"provider \"aws\" {\n"+
+			"  /* the profile */\n"+
+			"  profile = \"default\"\n"+
+			"  # inline on the block\n"+
+			"  region = \"us-east-1\" # the default region\n"+
+			"  # dangling comment\n"+
+			"}\n"+
+			"# stack stack\n"+
+			"# has 2 regions\n"+
+			"provider \"aws\" {\n"+
+			"  # provider for the region\n"+
+			"  alias  = \"us-east-1\"\n"+
+			"  region = \"us-east-1\" # from globals\n"+
+			"}\n"+
+			"provider \"aws\" {\n"+
+			"  # provider for the region\n"+
+			"  alias  = \"eu-west-1\"\n"+
+			"  region = \"eu-west-1\" # from globals\n"+
+			"}\n"+
+			"// end of content\n",
+		string(stack.ReadFile("file.tf")))
+}
+
+func TestGenerateHCLComputedCommentFailures(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name    string
+		comment string
+		want    errors.Kind
+	}{
+		{
+			name:    "text is not a string",
+			comment: `text = ["a"]`,
+			want:    genhcl.ErrCommentEval,
+		},
+		{
+			name:    "text references undefined global",
+			comment: `text = global.undefined`,
+			want:    genhcl.ErrCommentEval,
+		},
+		{
+			name:    "missing text",
+			comment: ``,
+			want:    genhcl.ErrParsing,
+		},
+		{
+			name:    "unsupported attribute",
+			comment: "text = \"a\"\nother = 1",
+			want:    genhcl.ErrParsing,
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := sandbox.New(t)
+			s.BuildTree([]string{"s:stack"})
+			s.DirEntry("stack").CreateFile("gen.tm", `
+generate_hcl "file.tf" {
+  content {
+    tm_comment {
+      `+tc.comment+`
+    }
+  }
+}
+`)
+			report := generate.Do(s.Config(), project.NewPath("/modules"), nil, generate.Options{})
+			assert.EqualInts(t, 1, len(report.Failures), "report: %s", report.Full())
+			assert.IsTrue(t, errors.IsKind(report.Failures[0].Error, tc.want),
+				"unexpected error: %v", report.Failures[0].Error)
+		})
+	}
+}
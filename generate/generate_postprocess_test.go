@@ -0,0 +1,229 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package generate_test
+
+import (
+	"testing"
+
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/generate"
+	"github.com/terramate-io/terramate/project"
+	"github.com/terramate-io/terramate/test/sandbox"
+)
+
+func TestGeneratePostProcess(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{"s:stack"})
+	s.RootEntry().CreateFile("terramate.tm", `
+terramate {
+  config {
+    generate {
+      provenance = true
+      post_process = [
+        {
+          files   = ["*.sh"]
+          command = ["tr", "a-z", "A-Z"]
+        },
+      ]
+    }
+  }
+}
+`)
+
+	stack := s.DirEntry("stack")
+	stack.CreateFile("gen.tm", `
+generate_file "main.tf" {
+  content      = "a=1\nbb=2\n"
+  post_process = [{ format = "hcl" }]
+}
+
+generate_file "run.sh" {
+  comment_prefix = "#"
+  content        = "echo hi\n"
+  post_process   = [{ command = ["sh", "-c", "cat; echo \"# $TM_GENERATED_FILE\""] }]
+}
+
+generate_yaml "file.yml" {
+  content = { a = "b" }
+}
+`)
+
+	report := s.Generate()
+	assertEqualReports(t, report, generate.Report{
+		Successes: []generate.Result{
+			{
+				Dir:     project.NewPath("/stack"),
+				Created: []string{"file.yml", "main.tf", "run.sh"},
+			},
+		},
+	})
+
+	assert.EqualStrings(t, "a  = 1\nbb = 2\n", string(stack.ReadFile("main.tf")))
+	assert.EqualStrings(t, withProvenanceHash(t,
+		"# TERRAMATE: GENERATED AUTOMATICALLY DO NOT EDIT\n"+
+			"# TERRAMATE: originated from generate_file block on /stack/gen.tm:7\n"+
+			"# TERRAMATE: sha256 \n"+
+			"\n"+
+			"ECHO HI\n"+
+			"# /stack/run.sh\n"),
+		string(stack.ReadFile("run.sh")))
+	assert.EqualStrings(t, withProvenanceHash(t,
+		"# TERRAMATE: GENERATED AUTOMATICALLY DO NOT EDIT\n"+
+			"# TERRAMATE: originated from generate_yaml block on /stack/gen.tm:13\n"+
+			"# TERRAMATE: sha256 \n"+
+			"\n"+
+			"\"a\": \"b\"\n"),
+		string(stack.ReadFile("file.yml")))
+
+	outdated, err := generate.DetectOutdated(s.Config(), project.NewPath("/modules"), nil)
+	assert.NoError(t, err)
+	assertEqualStringList(t, outdated, []string{})
+
+	report = s.Generate()
+	assertEqualReports(t, report, generate.Report{})
+}
+
+func TestGeneratePostProcessTemplateFile(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		"s:stack",
+		"f:stack/main.tf.tpl:a = \"x\"\n",
+	})
+
+	stack := s.DirEntry("stack")
+	stack.CreateFile("gen.tm", `
+generate_hcl "inline.tf" {
+  content {
+    a = "x"
+  }
+  post_process = [{ command = ["tr", "a-z", "A-Z"] }]
+}
+
+generate_hcl "template.tf" {
+  template_file = "main.tf.tpl"
+  post_process  = [{ command = ["tr", "a-z", "A-Z"] }]
+}
+`)
+
+	report := s.Generate()
+	assertEqualReports(t, report, generate.Report{
+		Successes: []generate.Result{
+			{
+				Dir:     project.NewPath("/stack"),
+				Created: []string{"inline.tf", "template.tf"},
+			},
+		},
+	})
+
+	want := "// TERRAMATE: GENERATED AUTOMATICALLY DO NOT EDIT\n\n" +
+		"A = \"X\"\n"
+	assert.EqualStrings(t, want, string(stack.ReadFile("inline.tf")))
+	assert.EqualStrings(t, want, string(stack.ReadFile("template.tf")))
+
+	outdated, err := generate.DetectOutdated(s.Config(), project.NewPath("/modules"), nil)
+	assert.NoError(t, err)
+	assertEqualStringList(t, outdated, []string{})
+}
+
+func TestGeneratePostProcessIgnoresJSONFiles(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{"s:stack"})
+	s.RootEntry().CreateFile("terramate.tm", `
+terramate {
+  config {
+    generate {
+      post_process = [{
+        files   = ["*"]
+        command = ["tr", "a-z", "A-Z"]
+      }]
+    }
+  }
+}
+`)
+
+	stack := s.DirEntry("stack")
+	stack.CreateFile("gen.tm", `
+generate_json "file.json" {
+  content = { a = "b" }
+}
+
+generate_file "file.txt" {
+  content = "a"
+}
+`)
+
+	report := s.Generate()
+	assertEqualReports(t, report, generate.Report{
+		Successes: []generate.Result{
+			{
+				Dir:     project.NewPath("/stack"),
+				Created: []string{"file.json", "file.txt"},
+			},
+		},
+	})
+
+	assert.EqualStrings(t, "{\n"+
+		"  \"//\": \"TERRAMATE: GENERATED AUTOMATICALLY DO NOT EDIT\",\n"+
+		"  \"a\": \"b\"\n"+
+		"}\n", string(stack.ReadFile("file.json")))
+	assert.EqualStrings(t, "A", string(stack.ReadFile("file.txt")))
+}
+
+func TestGeneratePostProcessFailures(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name string
+		gen  string
+	}{
+		{
+			name: "command fails",
+			gen: `
+generate_file "file.txt" {
+  content      = "a"
+  post_process = [{ command = ["false"] }]
+}
+`,
+		},
+		{
+			name: "command not found",
+			gen: `
+generate_file "file.txt" {
+  content      = "a"
+  post_process = [{ command = ["terramate-no-such-command"] }]
+}
+`,
+		},
+		{
+			name: "formatting invalid HCL code",
+			gen: `
+generate_file "file.tf" {
+  content      = "a = {"
+  post_process = [{ format = "hcl" }]
+}
+`,
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := sandbox.New(t)
+			s.BuildTree([]string{"s:stack"})
+			s.DirEntry("stack").CreateFile("gen.tm", tc.gen)
+
+			report := generate.Do(s.Config(), project.NewPath("/modules"), nil, generate.Options{})
+			assert.EqualInts(t, 1, len(report.Failures), "report: %s", report.Full())
+			assert.IsTrue(t, errors.IsKind(report.Failures[0].Error, generate.ErrPostProcess),
+				"unexpected error: %v", report.Failures[0].Error)
+		})
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/madlambda/spells/assert"
 	"github.com/terramate-io/terramate/errors"
 	"github.com/terramate-io/terramate/project"
 	. "github.com/terramate-io/terramate/test/hclwrite/hclutils"
@@ -62,6 +63,47 @@ func TestGenerateRootContext(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "generate.context=root has access to scoped lets",
+			configs: []hclconfig{
+				{
+					path: "/",
+					add: Lets(
+						Str("name", "root"),
+						Expr("content", `"name=${let.name}"`),
+					),
+				},
+				{
+					path: "/source",
+					add: Doc(
+						Lets(
+							Str("name", "source"),
+						),
+						GenerateFile(
+							Labels("/target/lets.txt"),
+							Expr("context", "root"),
+							Expr("content", "let.content"),
+						),
+					),
+				},
+			},
+			want: []generatedFile{
+				{
+					dir: "/target",
+					files: map[string]fmt.Stringer{
+						"lets.txt": stringer("name=source"),
+					},
+				},
+			},
+			wantReport: generate.Report{
+				Successes: []generate.Result{
+					{
+						Dir:     project.NewPath("/target"),
+						Created: []string{"lets.txt"},
+					},
+				},
+			},
+		},
 		{
 			name: "generate.context=root has access to project metadata",
 			layout: []string{
@@ -701,6 +743,120 @@ func TestGenerateRootContext(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "generate_hcl with context=root generates inside subdirs",
+			layout: []string{
+				"s:stacks/stack-1",
+				"s:stacks/stack-2",
+			},
+			configs: []hclconfig{
+				{
+					path: "/source",
+					add: GenerateHCL(
+						Labels("/policies/main/stacks.hcl"),
+						Expr("context", "root"),
+						Content(
+							Expr("count", "tm_length(terramate.stacks.list)"),
+						),
+					),
+				},
+			},
+			want: []generatedFile{
+				{
+					dir: "/policies/main",
+					files: map[string]fmt.Stringer{
+						"stacks.hcl": Doc(
+							Number("count", 2),
+						),
+					},
+				},
+			},
+			wantReport: generate.Report{
+				Successes: []generate.Result{
+					{
+						Dir:     project.NewPath("/policies/main"),
+						Created: []string{"stacks.hcl"},
+					},
+				},
+			},
+		},
+		{
+			name: "generate_hcl with context=root and false condition generates nothing",
+			configs: []hclconfig{
+				{
+					path: "/source",
+					add: GenerateHCL(
+						Labels("/target/file.hcl"),
+						Expr("context", "root"),
+						Bool("condition", false),
+						Content(
+							Str("a", "b"),
+						),
+					),
+				},
+			},
+		},
+		{
+			name: "generate_hcl with context=root fails when generating inside a stack",
+			layout: []string{
+				"s:stack",
+			},
+			configs: []hclconfig{
+				{
+					path: "/source",
+					add: GenerateHCL(
+						Labels("/stack/file.hcl"),
+						Expr("context", "root"),
+						Content(
+							Str("a", "b"),
+						),
+					),
+				},
+			},
+			wantReport: generate.Report{
+				Failures: []generate.FailureResult{
+					{
+						Result: generate.Result{
+							Dir: project.NewPath("/stack"),
+						},
+						Error: errors.E(generate.ErrInvalidGenBlockLabel),
+					},
+				},
+			},
+		},
+		{
+			name: "generate_file and generate_hcl with context=root and same label - fails",
+			configs: []hclconfig{
+				{
+					path: "/",
+					add: GenerateFile(
+						Labels("/target/file.hcl"),
+						Expr("context", "root"),
+						Str("content", "content"),
+					),
+				},
+				{
+					path: "/child",
+					add: GenerateHCL(
+						Labels("/target/file.hcl"),
+						Expr("context", "root"),
+						Content(
+							Str("a", "b"),
+						),
+					),
+				},
+			},
+			wantReport: generate.Report{
+				Failures: []generate.FailureResult{
+					{
+						Result: generate.Result{
+							Dir: project.NewPath("/target"),
+						},
+						Error: errors.E(generate.ErrConflictingConfig),
+					},
+				},
+			},
+		},
 	})
 }
 
@@ -772,3 +928,83 @@ func TestGenerateFileWithRootContextRemoveFilesWhenConditionIsFalse(t *testing.T
 	})
 	assertFileDontExist(filename)
 }
+
+func TestGenerateHCLWithRootContextOutdatedAndOrphaned(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{"s:stack"})
+
+	createConfig := func(value string) {
+		s.RootEntry().CreateConfig(
+			GenerateHCL(
+				Labels("/.github/policies/policy.hcl"),
+				Expr("context", "root"),
+				Content(
+					Str("value", value),
+				),
+			).String(),
+		)
+	}
+
+	assertOutdated := func(want []string) {
+		t.Helper()
+
+		got, err := generate.DetectOutdated(s.ReloadConfig(), project.NewPath("/modules"), nil)
+		assert.NoError(t, err)
+		assertEqualStringList(t, got, want)
+	}
+
+	createConfig("a")
+	assertOutdated([]string{".github/policies/policy.hcl"})
+
+	report := generate.Do(s.Config(), project.NewPath("/modules"), nil, generate.Options{})
+	assertEqualReports(t, report, generate.Report{
+		Successes: []generate.Result{
+			{
+				Dir:     project.NewPath("/.github/policies"),
+				Created: []string{"policy.hcl"},
+			},
+		},
+	})
+	assertOutdated([]string{})
+
+	createConfig("b")
+	assertOutdated([]string{".github/policies/policy.hcl"})
+
+	report = generate.Do(s.Config(), project.NewPath("/modules"), nil, generate.Options{})
+	assertEqualReports(t, report, generate.Report{
+		Successes: []generate.Result{
+			{
+				Dir:     project.NewPath("/.github/policies"),
+				Changed: []string{"/.github/policies/policy.hcl"},
+			},
+		},
+	})
+	assertOutdated([]string{})
+
+	s.RootEntry().CreateConfig(
+		GenerateHCL(
+			Labels("/policies/policy.hcl"),
+			Expr("context", "root"),
+			Content(
+				Str("value", "c"),
+			),
+		).String(),
+	)
+	s.GenerateWith(s.ReloadConfig(), project.NewPath("/modules"))
+
+	s.RootEntry().CreateConfig(Terramate().String())
+	assertOutdated([]string{"policies/policy.hcl"})
+
+	report = generate.Do(s.Config(), project.NewPath("/modules"), nil, generate.Options{})
+	assertEqualReports(t, report, generate.Report{
+		Successes: []generate.Result{
+			{
+				Dir:     project.NewPath("/policies"),
+				Deleted: []string{"policy.hcl"},
+			},
+		},
+	})
+	assertOutdated([]string{})
+}
@@ -0,0 +1,112 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package generate_test
+
+import (
+	"testing"
+
+	"github.com/terramate-io/terramate/generate"
+	"github.com/terramate-io/terramate/project"
+	. "github.com/terramate-io/terramate/test/hclwrite/hclutils"
+	"github.com/terramate-io/terramate/test/sandbox"
+)
+
+func TestGenerateSelection(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		"s:stacks/a",
+		"s:stacks/b",
+	})
+	s.RootEntry().CreateFile("gen.tm", Doc(
+		GenerateFile(
+			Labels("file.txt"),
+			Expr("content", `"txt"`),
+		),
+		GenerateFile(
+			Labels("dir/file.sh"),
+			Expr("content", `"sh"`),
+		),
+		GenerateFile(
+			Labels("/root.txt"),
+			Expr("context", "root"),
+			Expr("content", `"root"`),
+		),
+	).String())
+
+	vendorDir := project.NewPath("/modules")
+
+	report := generate.Do(s.Config(), vendorDir, nil, generate.Options{
+		Stacks: project.Paths{project.NewPath("/stacks/b")},
+	})
+	assertEqualReports(t, report, generate.Report{
+		Successes: []generate.Result{
+			{
+				Dir:     project.NewPath("/stacks/b"),
+				Created: []string{"dir/file.sh", "file.txt"},
+			},
+		},
+	})
+
+	report = generate.Do(s.Config(), vendorDir, nil, generate.Options{
+		Stacks: project.Paths{},
+	})
+	assertEqualReports(t, report, generate.Report{})
+
+	report = generate.Do(s.Config(), vendorDir, nil, generate.Options{
+		Labels: []string{"**/*.sh", "/root.txt"},
+	})
+	assertEqualReports(t, report, generate.Report{
+		Successes: []generate.Result{
+			{
+				Dir:     project.NewPath("/stacks/a"),
+				Created: []string{"dir/file.sh"},
+			},
+			{
+				Dir:     project.NewPath("/"),
+				Created: []string{"root.txt"},
+			},
+		},
+	})
+
+	// files whose label is not selected are not deleted.
+	s.RootEntry().CreateFile("gen.tm", Doc(
+		GenerateHCL(
+			Labels("a.tf"),
+			Content(Str("a", "a")),
+		),
+		GenerateHCL(
+			Labels("b.tf"),
+			Content(Str("b", "b")),
+		),
+	).String())
+	s.GenerateWith(s.ReloadConfig(), vendorDir)
+
+	s.RootEntry().CreateFile("gen.tm", GenerateHCL(
+		Labels("a.tf"),
+		Content(Str("a", "a")),
+	).String())
+
+	report = generate.Do(s.ReloadConfig(), vendorDir, nil, generate.Options{
+		Labels: []string{"a.tf"},
+	})
+	assertEqualReports(t, report, generate.Report{})
+
+	report = generate.Do(s.Config(), vendorDir, nil, generate.Options{
+		Labels: []string{"*.tf"},
+	})
+	assertEqualReports(t, report, generate.Report{
+		Successes: []generate.Result{
+			{
+				Dir:     project.NewPath("/stacks/a"),
+				Deleted: []string{"b.tf"},
+			},
+			{
+				Dir:     project.NewPath("/stacks/b"),
+				Deleted: []string{"b.tf"},
+			},
+		},
+	})
+}
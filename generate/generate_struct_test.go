@@ -0,0 +1,307 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package generate_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/generate"
+	"github.com/terramate-io/terramate/generate/genstruct"
+	"github.com/terramate-io/terramate/project"
+	. "github.com/terramate-io/terramate/test/hclwrite/hclutils"
+)
+
+func TestGenerateStructuredFiles(t *testing.T) {
+	t.Parallel()
+
+	testCodeGeneration(t, []testcase{
+		{
+			name: "generate_json with nested content",
+			layout: []string{
+				"s:stacks/stack",
+			},
+			configs: []hclconfig{
+				{
+					path: "/stacks",
+					add: GenerateJSON(
+						Labels("config.json"),
+						Expr("content", `{
+							name  = terramate.stack.name
+							list  = [1, "two", true]
+							empty = {}
+							nested = {
+								b = "b"
+								a = global.a
+							}
+						}`),
+					),
+				},
+				{
+					path: "/stacks",
+					add: Globals(
+						Number("a", 1),
+					),
+				},
+			},
+			want: []generatedFile{
+				{
+					dir: "/stacks/stack",
+					files: map[string]fmt.Stringer{
+						"config.json": stringer(`{
+  "//": "TERRAMATE: GENERATED AUTOMATICALLY DO NOT EDIT",
+  "empty": {},
+  "list": [
+    1,
+    "two",
+    true
+  ],
+  "name": "stack",
+  "nested": {
+    "a": 1,
+    "b": "b"
+  }
+}`),
+					},
+				},
+			},
+			wantReport: generate.Report{
+				Successes: []generate.Result{
+					{
+						Dir:     project.NewPath("/stacks/stack"),
+						Created: []string{"config.json"},
+					},
+				},
+			},
+		},
+		{
+			name: "generate_json with empty content",
+			layout: []string{
+				"s:stack",
+			},
+			configs: []hclconfig{
+				{
+					path: "/stack",
+					add: GenerateJSON(
+						Labels("empty.json"),
+						Expr("content", `{}`),
+					),
+				},
+			},
+			want: []generatedFile{
+				{
+					dir: "/stack",
+					files: map[string]fmt.Stringer{
+						"empty.json": stringer(`{
+  "//": "TERRAMATE: GENERATED AUTOMATICALLY DO NOT EDIT"
+}`),
+					},
+				},
+			},
+			wantReport: generate.Report{
+				Successes: []generate.Result{
+					{
+						Dir:     project.NewPath("/stack"),
+						Created: []string{"empty.json"},
+					},
+				},
+			},
+		},
+		{
+			name: "generate_yaml with lets and subdir label",
+			layout: []string{
+				"s:stack",
+			},
+			configs: []hclconfig{
+				{
+					path: "/stack",
+					add: GenerateYAML(
+						Labels("k8s/deployment.yml"),
+						Lets(
+							Number("replicas", 3),
+						),
+						Expr("content", `{
+							kind = "Deployment"
+							spec = {
+								replicas = let.replicas
+								ports    = ["80", "443"]
+							}
+						}`),
+					),
+				},
+			},
+			want: []generatedFile{
+				{
+					dir: "/stack",
+					files: map[string]fmt.Stringer{
+						"k8s/deployment.yml": stringer(`# TERRAMATE: GENERATED AUTOMATICALLY DO NOT EDIT
+
+"kind": "Deployment"
+"spec":
+  "ports":
+  - "80"
+  - "443"
+  "replicas": 3`),
+					},
+				},
+			},
+			wantReport: generate.Report{
+				Successes: []generate.Result{
+					{
+						Dir:     project.NewPath("/stack"),
+						Created: []string{"k8s/deployment.yml"},
+					},
+				},
+			},
+		},
+		{
+			name: "generate_yaml with false condition generates nothing",
+			layout: []string{
+				"s:stack",
+			},
+			configs: []hclconfig{
+				{
+					path: "/stack",
+					add: GenerateYAML(
+						Labels("file.yml"),
+						Bool("condition", false),
+						Expr("content", `{ a = 1 }`),
+					),
+				},
+			},
+		},
+		{
+			name: "content must be an object",
+			layout: []string{
+				"s:stack",
+			},
+			configs: []hclconfig{
+				{
+					path: "/stack",
+					add: GenerateJSON(
+						Labels("file.json"),
+						Expr("content", `["a"]`),
+					),
+				},
+			},
+			wantReport: generate.Report{
+				Failures: []generate.FailureResult{
+					{
+						Result: generate.Result{
+							Dir: project.NewPath("/stack"),
+						},
+						Error: errors.E(genstruct.ErrInvalidContentType),
+					},
+				},
+			},
+		},
+		{
+			name: "generate_json content cannot use the header key",
+			layout: []string{
+				"s:stack",
+			},
+			configs: []hclconfig{
+				{
+					path: "/stack",
+					add: GenerateJSON(
+						Labels("file.json"),
+						Expr("content", `{ "//" = "comment" }`),
+					),
+				},
+			},
+			wantReport: generate.Report{
+				Failures: []generate.FailureResult{
+					{
+						Result: generate.Result{
+							Dir: project.NewPath("/stack"),
+						},
+						Error: errors.E(genstruct.ErrInvalidContentType),
+					},
+				},
+			},
+		},
+		{
+			name: "generate_json content cannot use the provenance origin key",
+			layout: []string{
+				"s:stack",
+			},
+			configs: []hclconfig{
+				{
+					path: "/stack",
+					add: GenerateJSON(
+						Labels("file.json"),
+						Expr("content", `{ "//origin" = "comment" }`),
+					),
+				},
+			},
+			wantReport: generate.Report{
+				Failures: []generate.FailureResult{
+					{
+						Result: generate.Result{
+							Dir: project.NewPath("/stack"),
+						},
+						Error: errors.E(genstruct.ErrInvalidContentType),
+					},
+				},
+			},
+		},
+		{
+			name: "generate_json content cannot use the provenance hash key",
+			layout: []string{
+				"s:stack",
+			},
+			configs: []hclconfig{
+				{
+					path: "/stack",
+					add: GenerateJSON(
+						Labels("file.json"),
+						Expr("content", `{ "//sha256" = "comment" }`),
+					),
+				},
+			},
+			wantReport: generate.Report{
+				Failures: []generate.FailureResult{
+					{
+						Result: generate.Result{
+							Dir: project.NewPath("/stack"),
+						},
+						Error: errors.E(genstruct.ErrInvalidContentType),
+					},
+				},
+			},
+		},
+		{
+			name: "conflicts with other generate blocks",
+			layout: []string{
+				"s:stack",
+			},
+			configs: []hclconfig{
+				{
+					path: "/stack",
+					add: Doc(
+						GenerateJSON(
+							Labels("file"),
+							Expr("content", `{}`),
+						),
+						GenerateFile(
+							Labels("file"),
+							Str("content", "data"),
+						),
+					),
+				},
+			},
+			wantReport: generate.Report{
+				Failures: []generate.FailureResult{
+					{
+						Result: generate.Result{
+							Dir: project.NewPath("/stack"),
+						},
+						Error: errors.E(generate.ErrConflictingConfig),
+					},
+				},
+			},
+		},
+	})
+}
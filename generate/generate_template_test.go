@@ -0,0 +1,147 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package generate_test
+
+import (
+	"testing"
+
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/generate"
+	"github.com/terramate-io/terramate/generate/genhcl"
+	"github.com/terramate-io/terramate/project"
+	. "github.com/terramate-io/terramate/test/hclwrite/hclutils"
+	"github.com/terramate-io/terramate/test/sandbox"
+)
+
+func TestGenerateTemplateFile(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		"s:stacks/stack-1",
+		"s:stacks/stack-2",
+		"f:templates/script.sh.tpl:echo ${global.msg} from ${terramate.stack.name}\n",
+		"f:stacks/backend.tf.tpl:" +
+			"terraform {\n" +
+			"backend \"local\" {\n" +
+			"path = \"${let.path}\"\n" +
+			"}\n" +
+			"}\n",
+	})
+	s.RootEntry().CreateFile("globals.tm", Globals(
+		Str("msg", "hello"),
+	).String())
+	s.DirEntry("stacks").CreateFile("gen.tm", Doc(
+		GenerateFile(
+			Labels("script.sh"),
+			Str("template_file", "/templates/script.sh.tpl"),
+		),
+		GenerateHCL(
+			Labels("backend.tf"),
+			Str("template_file", "backend.tf.tpl"),
+			Block("lets",
+				Expr("path", `"${terramate.stack.path.basename}.tfstate"`),
+			),
+		),
+	).String())
+
+	report := s.Generate()
+	assertEqualReports(t, report, generate.Report{
+		Successes: []generate.Result{
+			{
+				Dir:     project.NewPath("/stacks/stack-1"),
+				Created: []string{"backend.tf", "script.sh"},
+			},
+			{
+				Dir:     project.NewPath("/stacks/stack-2"),
+				Created: []string{"backend.tf", "script.sh"},
+			},
+		},
+	})
+
+	for _, name := range []string{"stack-1", "stack-2"} {
+		stack := s.DirEntry("stacks/" + name)
+		assert.EqualStrings(t, "echo hello from "+name+"\n", string(stack.ReadFile("script.sh")))
+		assert.EqualStrings(t,
+			"// TERRAMATE: GENERATED AUTOMATICALLY DO NOT EDIT\n\n"+
+				"terraform {\n"+
+				"  backend \"local\" {\n"+
+				"    path = \""+name+".tfstate\"\n"+
+				"  }\n"+
+				"}\n",
+			string(stack.ReadFile("backend.tf")))
+	}
+}
+
+func TestGenerateTemplateFileIsDependency(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		"s:stack",
+		"f:templates/file.txt.tpl:version 1 of ${terramate.stack.name}",
+	})
+	s.RootEntry().CreateFile("gen.tm", GenerateFile(
+		Labels("file.txt"),
+		Str("template_file", "templates/file.txt.tpl"),
+	).String())
+
+	cachedir := t.TempDir()
+	cache := generate.NewCache(cachedir)
+	vendorDir := project.NewPath("/modules")
+
+	report := generate.Do(s.Config(), vendorDir, nil, generate.Options{Cache: cache})
+	assert.IsTrue(t, !report.HasFailures(), report.Full())
+	assertCacheEntries(t, cachedir, 1)
+
+	s.RootEntry().CreateFile("templates/file.txt.tpl", "version 2 of ${terramate.stack.name}")
+
+	outdated, err := generate.DetectOutdated(s.Config(), vendorDir, cache)
+	assert.NoError(t, err)
+	assertEqualStringList(t, outdated, []string{"stack/file.txt"})
+
+	report = generate.Do(s.Config(), vendorDir, nil, generate.Options{Cache: cache})
+	assertEqualReports(t, report, generate.Report{
+		Successes: []generate.Result{
+			{
+				Dir:     project.NewPath("/stack"),
+				Changed: []string{"file.txt"},
+			},
+		},
+	})
+	assert.EqualStrings(t, "version 2 of stack", string(s.DirEntry("stack").ReadFile("file.txt")))
+	assertCacheEntries(t, cachedir, 2)
+}
+
+func TestGenerateTemplateFileFailures(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		"s:stack",
+		"f:stack/invalid.tf.tpl:a = ${global.undefined}",
+		"f:stack/invalid-hcl.tf.tpl:a = {",
+	})
+	stack := s.DirEntry("stack")
+
+	for _, tc := range []struct {
+		name     string
+		template string
+	}{
+		{name: "missing template file", template: "missing.tf.tpl"},
+		{name: "undefined global", template: "invalid.tf.tpl"},
+		{name: "rendered code is not HCL", template: "invalid-hcl.tf.tpl"},
+	} {
+		stack.CreateFile("gen.tm", GenerateHCL(
+			Labels("file.tf"),
+			Str("template_file", tc.template),
+		).String())
+
+		report := generate.Do(s.ReloadConfig(), project.NewPath("/modules"), nil, generate.Options{})
+		assert.EqualInts(t, 1, len(report.Failures), "%s: report: %s", tc.name, report.Full())
+		assert.IsTrue(t, errors.IsKind(report.Failures[0].Error, genhcl.ErrContentEval),
+			"%s: unexpected error: %v", tc.name, report.Failures[0].Error)
+	}
+}
@@ -197,6 +197,52 @@ func TestGenerateStackContextSubDirsOnLabels(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "subdirs ignored by terramate are allowed",
+			layout: []string{
+				"s:stack",
+			},
+			configs: []hclconfig{
+				{
+					path: "/stack",
+					add: GenerateHCL(
+						Labels(".github/workflows/ci.hcl"),
+						Content(
+							Str("data", "data"),
+						),
+					),
+				},
+				{
+					path: "/stack",
+					add: GenerateFile(
+						Labels(".github/workflows/ci.yml"),
+						Str("content", "test"),
+					),
+				},
+			},
+			want: []generatedFile{
+				{
+					dir: "/stack",
+					files: map[string]fmt.Stringer{
+						".github/workflows/ci.hcl": Doc(
+							Str("data", "data"),
+						),
+						".github/workflows/ci.yml": stringer("test"),
+					},
+				},
+			},
+			wantReport: generate.Report{
+				Successes: []generate.Result{
+					{
+						Dir: project.NewPath("/stack"),
+						Created: []string{
+							".github/workflows/ci.hcl",
+							".github/workflows/ci.yml",
+						},
+					},
+				},
+			},
+		},
 		{
 			name: "if path is a child stack fails",
 			layout: []string{
@@ -761,7 +807,7 @@ func testCodeGeneration(t *testing.T, tcases []testcase) {
 			if tcase.vendorDir != "" {
 				vendorDir = project.NewPath(tcase.vendorDir)
 			}
-			report := generate.Do(s.Config(), vendorDir, nil)
+			report := generate.Do(s.Config(), vendorDir, nil, generate.Options{})
 			assertEqualReports(t, report, tcase.wantReport)
 
 			assertGeneratedFiles(t)
@@ -769,7 +815,7 @@ func testCodeGeneration(t *testing.T, tcases []testcase) {
 			// piggyback on the tests to validate that regeneration doesn't
 			// delete files or fail and has identical results.
 			t.Run("regenerate", func(t *testing.T) {
-				report := generate.Do(s.Config(), vendorDir, nil)
+				report := generate.Do(s.Config(), vendorDir, nil, generate.Options{})
 				// since we just generated everything, report should only contain
 				// the same failures as previous code generation.
 				assertEqualReports(t, report, generate.Report{
@@ -6,6 +6,7 @@ package genfile
 
 import (
 	"fmt"
+	"os"
 	"path"
 	"sort"
 
@@ -43,6 +44,9 @@ const (
 	ErrLabelConflict errors.Kind = "label conflict detected"
 )
 
+// BlockType is the type of the generate_file block.
+const BlockType = "generate_file"
+
 const (
 	// StackContext is the stack context name.
 	StackContext = "stack"
@@ -53,12 +57,14 @@ const (
 
 // File represents generated file from a single generate_file block.
 type File struct {
-	label     string
-	context   string
-	origin    info.Range
-	body      string
-	condition bool
-	asserts   []config.Assert
+	label         string
+	context       string
+	origin        info.Range
+	body          string
+	condition     bool
+	commentPrefix string
+	asserts       []config.Assert
+	postProcess   []hcl.PostProcessConfig
 }
 
 // Label of the original generate_file block.
@@ -87,6 +93,17 @@ func (f File) Context() string {
 	return f.context
 }
 
+// Type of the original block, always [BlockType].
+func (f File) Type() string {
+	return BlockType
+}
+
+// CommentPrefix is the line comment prefix of the generated file, if any.
+// Files that have a comment prefix get a provenance header.
+func (f File) CommentPrefix() string {
+	return f.commentPrefix
+}
+
 // Asserts returns all (if any) of the evaluated assert configs of the
 // generate_file block. If [File.Condition] returns false then assert configs
 // will always be empty since they are not evaluated at all in that case.
@@ -94,6 +111,11 @@ func (f File) Asserts() []config.Assert {
 	return f.asserts
 }
 
+// PostProcess returns the post-processors of the generate_file block.
+func (f File) PostProcess() []hcl.PostProcessConfig {
+	return f.postProcess
+}
+
 // Header returns the header of this file.
 func (f File) Header() string {
 	// For now we don't support headers for arbitrary files
@@ -129,6 +151,12 @@ func Load(
 		return nil, errors.E("loading generate_file", err)
 	}
 
+	tree, _ := root.Lookup(st.Dir)
+	scopedLets, err := lets.LoadScoped(tree)
+	if err != nil {
+		return nil, errors.E("loading lets", err)
+	}
+
 	var files []File
 
 	for _, genFileBlock := range genFileBlocks {
@@ -144,7 +172,7 @@ func Load(
 
 		evalctx.SetFunction(stdlib.Name("vendor"), stdlib.VendorFunc(vendorTargetDir, vendorDir, vendorRequests))
 
-		file, err := Eval(genFileBlock, evalctx.Context)
+		file, err := Eval(root.HostDir(), genFileBlock, scopedLets, evalctx.Context)
 		if err != nil {
 			return nil, err
 		}
@@ -159,9 +187,11 @@ func Load(
 }
 
 // Eval the generate_file block.
-func Eval(block hcl.GenFileBlock, evalctx *eval.Context) (File, error) {
+// The rootdir is used to read the template file of the block, if any.
+// The scopedLets are the lets shared by the generate blocks in scope.
+func Eval(rootdir string, block hcl.GenFileBlock, scopedLets lets.Exprs, evalctx *eval.Context) (File, error) {
 	name := block.Label
-	err := lets.Load(block.Lets, evalctx)
+	err := lets.Load(block.Lets, scopedLets, evalctx)
 	if err != nil {
 		return File{}, err
 	}
@@ -179,6 +209,7 @@ func Eval(block hcl.GenFileBlock, evalctx *eval.Context) (File, error) {
 				value.Type().FriendlyName(),
 			)
 		}
+		value, _ = value.UnmarkDeep()
 		condition = value.True()
 	}
 
@@ -221,29 +252,55 @@ func Eval(block hcl.GenFileBlock, evalctx *eval.Context) (File, error) {
 		}, nil
 	}
 
-	value, err := evalctx.Eval(block.Content.Expr)
-	if err != nil {
-		return File{}, errors.E(ErrContentEval, err)
-	}
+	var body string
+	if block.TemplateFile != "" {
+		body, err = evalTemplateFile(rootdir, block.TemplateFile, evalctx)
+		if err != nil {
+			return File{}, errors.E(ErrContentEval, err, "generate_file %q", name)
+		}
+	} else {
+		value, err := evalctx.Eval(block.Content.Expr)
+		if err != nil {
+			return File{}, errors.E(ErrContentEval, err)
+		}
 
-	if value.Type() != cty.String {
-		return File{}, errors.E(
-			ErrInvalidContentType,
-			"content has type %s but must be string",
-			value.Type().FriendlyName(),
-		)
+		if value.Type() != cty.String {
+			return File{}, errors.E(
+				ErrInvalidContentType,
+				"content has type %s but must be string",
+				value.Type().FriendlyName(),
+			)
+		}
+		if eval.IsSensitive(value) {
+			return File{}, errors.E(ErrContentEval, eval.ErrSensitive,
+				block.Content.Expr.Range(),
+				"content is sensitive: use tm_nonsensitive() to explicitly allow it")
+		}
+		body = value.AsString()
 	}
 
 	return File{
-		label:     name,
-		origin:    block.Range,
-		body:      value.AsString(),
-		condition: condition,
-		context:   block.Context,
-		asserts:   asserts,
+		label:         name,
+		origin:        block.Range,
+		body:          body,
+		condition:     condition,
+		context:       block.Context,
+		commentPrefix: block.CommentPrefix,
+		asserts:       asserts,
+		postProcess:   block.PostProcess,
 	}, nil
 }
 
+// evalTemplateFile renders the template file at the given project path.
+func evalTemplateFile(rootdir string, templateFile string, evalctx *eval.Context) (string, error) {
+	filename := project.NewPath(templateFile).HostPath(rootdir)
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		return "", errors.E(err, "reading template file %s", templateFile)
+	}
+	return evalctx.EvalTemplate(src, filename)
+}
+
 // loadGenFileBlocks will load all generate_file blocks.
 // The returned map maps the name of the block (its label)
 // to the original block and the path (relative to project root) of the config
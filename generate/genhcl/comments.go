@@ -0,0 +1,186 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package genhcl
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+
+	hhcl "github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/hcl"
+	"github.com/terramate-io/terramate/hcl/eval"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// comments are the comments of a generate_hcl content block.
+type comments hclsyntax.Tokens
+
+// bodyComments are the comments of a single body, assigned to the attributes
+// and blocks of the body by the byte offset where they start.
+//
+// A comment belongs to the attribute or block ending on the same line where
+// the comment starts (trailing) or, if none, to the next attribute or block
+// (leading). The comments after the last attribute or block are kept at the
+// end of the body. Comments inside expressions are not preserved.
+type bodyComments struct {
+	leading  map[int]hclsyntax.Tokens
+	trailing map[int]hclsyntax.Tokens
+	end      hclsyntax.Tokens
+}
+
+// forBody returns the comments of the given body, ignoring the comments of
+// the nested blocks.
+func (c comments) forBody(body *hclsyntax.Body) bodyComments {
+	res := bodyComments{
+		leading:  map[int]hclsyntax.Tokens{},
+		trailing: map[int]hclsyntax.Tokens{},
+	}
+	if len(c) == 0 {
+		return res
+	}
+
+	var items []hhcl.Range
+	for _, attr := range body.Attributes {
+		items = append(items, attr.SrcRange)
+	}
+	for _, block := range body.Blocks {
+		items = append(items, block.Range())
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Start.Byte < items[j].Start.Byte
+	})
+
+	bodyRange := body.Range()
+	for _, comment := range c {
+		start := comment.Range.Start.Byte
+		if start < bodyRange.Start.Byte || start >= bodyRange.End.Byte {
+			continue
+		}
+
+		next := sort.Search(len(items), func(i int) bool {
+			return items[i].End.Byte > start
+		})
+		if next < len(items) && items[next].Start.Byte <= start {
+			// inside an expression or a nested block.
+			continue
+		}
+
+		if next > 0 && items[next-1].End.Line == comment.Range.Start.Line {
+			prev := items[next-1].Start.Byte
+			res.trailing[prev] = append(res.trailing[prev], comment)
+		} else if next < len(items) {
+			item := items[next].Start.Byte
+			res.leading[item] = append(res.leading[item], comment)
+		} else {
+			res.end = append(res.end, comment)
+		}
+	}
+	return res
+}
+
+// commentTokens returns the hclwrite tokens of the given comments, each one
+// ending with a newline.
+func commentTokens(comments hclsyntax.Tokens) hclwrite.Tokens {
+	var tokens hclwrite.Tokens
+	for _, comment := range comments {
+		tokens = append(tokens, &hclwrite.Token{
+			Type:  hclsyntax.TokenComment,
+			Bytes: comment.Bytes,
+		})
+		if !bytes.HasSuffix(comment.Bytes, []byte("\n")) {
+			tokens = append(tokens, &hclwrite.Token{
+				Type:  hclsyntax.TokenNewline,
+				Bytes: []byte("\n"),
+			})
+		}
+	}
+	return tokens
+}
+
+// appendAttribute appends the attribute with the given name and expression
+// tokens to the body, with its trailing comments, if any, on the same line.
+func appendAttribute(body *hclwrite.Body, name string, expr hclwrite.Tokens, trailing hclsyntax.Tokens) {
+	if len(trailing) == 0 {
+		body.SetAttributeRaw(name, expr)
+		return
+	}
+
+	tokens := hclwrite.Tokens{
+		{Type: hclsyntax.TokenIdent, Bytes: []byte(name)},
+		{Type: hclsyntax.TokenEqual, Bytes: []byte("=")},
+	}
+	tokens = append(tokens, expr...)
+	for _, comment := range trailing {
+		tokens = append(tokens, &hclwrite.Token{
+			Type:  hclsyntax.TokenComment,
+			Bytes: comment.Bytes,
+		})
+	}
+	if !bytes.HasSuffix(trailing[len(trailing)-1].Bytes, []byte("\n")) {
+		tokens = append(tokens, &hclwrite.Token{
+			Type:  hclsyntax.TokenNewline,
+			Bytes: []byte("\n"),
+		})
+	}
+	body.AppendUnstructuredTokens(tokens)
+}
+
+// appendComputedComment evaluates the tm_comment block and appends the
+// resulting comment to the body, one line comment per line of text.
+func appendComputedComment(target *hclwrite.Body, block *hclsyntax.Block, evaluator hcl.Evaluator) error {
+	errs := errors.L()
+	if len(block.Labels) != 0 {
+		errs.Append(errors.E(ErrParsing, block.LabelRanges,
+			"tm_comment must have no labels"))
+	}
+	for _, subBlock := range block.Body.Blocks {
+		errs.Append(errors.E(ErrParsing, subBlock.TypeRange,
+			"unrecognized block %s inside tm_comment", subBlock.Type))
+	}
+	text, ok := block.Body.Attributes["text"]
+	if !ok {
+		errs.Append(errors.E(ErrParsing, block.Body.Range(),
+			"tm_comment.text attribute is required"))
+	}
+	for name, attr := range block.Body.Attributes {
+		if name != "text" {
+			errs.Append(attrErr(attr, "tm_comment unsupported attribute %q", name))
+		}
+	}
+	if err := errs.AsError(); err != nil {
+		return err
+	}
+
+	val, err := evaluator.Eval(text.Expr)
+	if err != nil {
+		return errors.E(ErrCommentEval, err, text.Expr.Range())
+	}
+	if val.Type() != cty.String || val.IsNull() {
+		return errors.E(ErrCommentEval, text.Expr.Range(),
+			"tm_comment.text must be a string, got %s", val.Type().FriendlyName())
+	}
+
+	if eval.IsSensitive(val) {
+		return errors.E(ErrCommentEval, eval.ErrSensitive, text.Expr.Range(),
+			"tm_comment.text is sensitive: use tm_nonsensitive() to explicitly allow it")
+	}
+
+	var tokens hclwrite.Tokens
+	for _, line := range strings.Split(strings.TrimSuffix(val.AsString(), "\n"), "\n") {
+		comment := "#"
+		if line != "" {
+			comment += " " + line
+		}
+		tokens = append(tokens, &hclwrite.Token{
+			Type:  hclsyntax.TokenComment,
+			Bytes: []byte(comment + "\n"),
+		})
+	}
+	target.AppendUnstructuredTokens(tokens)
+	return nil
+}
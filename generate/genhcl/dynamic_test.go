@@ -1181,6 +1181,271 @@ func TestGenerateHCLDynamic(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:  "nested tm_dynamic iterator shadows the outer iterator",
+			stack: "/stack",
+			configs: []hclconfig{
+				{
+					path: "/stack",
+					add: GenerateHCL(
+						Labels("tm_dynamic_test.tf"),
+						Content(
+							TmDynamic(
+								Labels("outer"),
+								Expr("for_each", `["a", "b"]`),
+								Expr("iterator", "it"),
+								Content(
+									TmDynamic(
+										Labels("inner"),
+										Expr("for_each", `[0, 1]`),
+										Expr("iterator", "it"),
+										Content(
+											Expr("value", "it.value"),
+										),
+									),
+									TmDynamic(
+										Labels("after"),
+										Content(
+											Expr("value", "it.value"),
+										),
+									),
+								),
+							),
+						),
+					),
+				},
+			},
+			want: []result{
+				{
+					name: "tm_dynamic_test.tf",
+					hcl: genHCL{
+						condition: true,
+						body: Doc(
+							Block("outer",
+								Block("inner",
+									Number("value", 0),
+								),
+								Block("inner",
+									Number("value", 1),
+								),
+								Block("after",
+									Str("value", "a"),
+								),
+							),
+							Block("outer",
+								Block("inner",
+									Number("value", 0),
+								),
+								Block("inner",
+									Number("value", 1),
+								),
+								Block("after",
+									Str("value", "b"),
+								),
+							),
+						),
+					},
+				},
+			},
+		},
+		{
+			name:  "nested tm_dynamic iterates over the outer iterator value",
+			stack: "/stack",
+			configs: []hclconfig{
+				{
+					path: "/stack",
+					add: Doc(
+						Globals(
+							Expr("groups", `{
+							  admins = ["alice", "bob"]
+							  devs   = ["carol"]
+							}`),
+						),
+						GenerateHCL(
+							Labels("tm_dynamic_test.tf"),
+							Content(
+								TmDynamic(
+									Labels("group"),
+									Expr("for_each", `global.groups`),
+									Expr("labels", `[group.key]`),
+									Content(
+										TmDynamic(
+											Labels("member"),
+											Expr("for_each", `group.value`),
+											Content(
+												Expr("name", "member.value"),
+												Expr("group", "group.key"),
+											),
+										),
+									),
+								),
+							),
+						),
+					),
+				},
+			},
+			want: []result{
+				{
+					name: "tm_dynamic_test.tf",
+					hcl: genHCL{
+						condition: true,
+						body: Doc(
+							Block("group",
+								Labels("admins"),
+								Block("member",
+									Str("group", "admins"),
+									Str("name", "alice"),
+								),
+								Block("member",
+									Str("group", "admins"),
+									Str("name", "bob"),
+								),
+							),
+							Block("group",
+								Labels("devs"),
+								Block("member",
+									Str("group", "devs"),
+									Str("name", "carol"),
+								),
+							),
+						),
+					},
+				},
+			},
+		},
+		{
+			name:  "attributes with names computed by for expression",
+			stack: "/stack",
+			configs: []hclconfig{
+				{
+					path: "/stack",
+					add: Doc(
+						Globals(
+							Expr("tags", `{
+							  env  = "prod"
+							  team = "platform"
+							}`),
+						),
+						GenerateHCL(
+							Labels("tm_dynamic_test.tf"),
+							Content(
+								TmDynamic(
+									Labels("tags"),
+									Expr("for_each", `["a", "b"]`),
+									Expr("attributes", `{
+									  for k, v in global.tags : "${k}_${tags.value}" => tm_upper(v)
+									}`),
+								),
+							),
+						),
+					),
+				},
+			},
+			want: []result{
+				{
+					name: "tm_dynamic_test.tf",
+					hcl: genHCL{
+						condition: true,
+						body: Doc(
+							Block("tags",
+								Str("env_a", "PROD"),
+								Str("team_a", "PLATFORM"),
+							),
+							Block("tags",
+								Str("env_b", "PROD"),
+								Str("team_b", "PLATFORM"),
+							),
+						),
+					},
+				},
+			},
+		},
+		{
+			name:  "attributes for expression with unknown references fails",
+			stack: "/stack",
+			configs: []hclconfig{
+				{
+					path: "/stack",
+					add: GenerateHCL(
+						Labels("tm_dynamic_test.tf"),
+						Content(
+							TmDynamic(
+								Labels("tags"),
+								Expr("attributes", `{
+								  for k in ["a"] : k => var.value
+								}`),
+							),
+						),
+					),
+				},
+			},
+			wantErr: errors.E(genhcl.ErrDynamicAttrsEval),
+		},
+		{
+			name:  "top level tm_dynamic blocks of different types",
+			stack: "/stack",
+			configs: []hclconfig{
+				{
+					path: "/stack",
+					add: Doc(
+						Globals(
+							Expr("instances", `{
+							  db  = "t3.large"
+							  web = "t3.micro"
+							}`),
+						),
+						GenerateHCL(
+							Labels("tm_dynamic_test.tf"),
+							Content(
+								TmDynamic(
+									Labels("resource"),
+									Expr("for_each", `global.instances`),
+									Expr("iterator", "instance"),
+									Expr("labels", `["aws_instance", instance.key]`),
+									Content(
+										Expr("instance_type", "instance.value"),
+									),
+								),
+								TmDynamic(
+									Labels("output"),
+									Expr("for_each", `global.instances`),
+									Expr("labels", `["${output.key}_id"]`),
+									Content(
+										Expr("value", "aws_instance[output.key].id"),
+									),
+								),
+							),
+						),
+					),
+				},
+			},
+			want: []result{
+				{
+					name: "tm_dynamic_test.tf",
+					hcl: genHCL{
+						condition: true,
+						body: Doc(
+							Block("resource",
+								Labels("aws_instance", "db"),
+								Str("instance_type", "t3.large"),
+							),
+							Block("resource",
+								Labels("aws_instance", "web"),
+								Str("instance_type", "t3.micro"),
+							),
+							Block("output",
+								Labels("db_id"),
+								Expr("value", `aws_instance["db"].id`),
+							),
+							Block("output",
+								Labels("web_id"),
+								Expr("value", `aws_instance["web"].id`),
+							),
+						),
+					},
+				},
+			},
+		},
 		{
 			name:  "tm_dynamic with invalid iterator",
 			stack: "/stack",
@@ -6,6 +6,7 @@ package genhcl
 
 import (
 	stdfmt "fmt"
+	"os"
 	"path"
 	"sort"
 
@@ -33,13 +34,26 @@ import (
 // Is contains parsed and evaluated code on it and information
 // about the origin of the generated code.
 type HCL struct {
-	label     string
-	origin    info.Range
-	body      string
-	condition bool
-	asserts   []config.Assert
+	label       string
+	context     string
+	origin      info.Range
+	body        string
+	condition   bool
+	asserts     []config.Assert
+	postProcess []hcl.PostProcessConfig
 }
 
+// BlockType is the type of the generate_hcl block.
+const BlockType = "generate_hcl"
+
+const (
+	// StackContext is the stack context name.
+	StackContext = "stack"
+
+	// RootContext is the root context name.
+	RootContext = "root"
+)
+
 const (
 	// Header is the current header string used by generate_hcl code generation.
 	Header = "// TERRAMATE: GENERATED AUTOMATICALLY DO NOT EDIT"
@@ -77,6 +91,9 @@ const (
 
 	// ErrDynamicAttrsConflict indicates fields of tm_dynamic conflicts.
 	ErrDynamicAttrsConflict errors.Kind = "tm_dynamic.attributes and tm_dynamic.content have conflicting fields"
+
+	// ErrCommentEval indicates that the text of a tm_comment block can't be evaluated.
+	ErrCommentEval errors.Kind = "evaluating tm_comment.text"
 )
 
 // Label of the original generate_hcl block.
@@ -91,6 +108,11 @@ func (h HCL) Asserts() []config.Assert {
 	return h.asserts
 }
 
+// PostProcess returns the post-processors of the generate_hcl block.
+func (h HCL) PostProcess() []hcl.PostProcessConfig {
+	return h.postProcess
+}
+
 // Header returns the header of the generated HCL file.
 func (h HCL) Header() string {
 	return Header + "\n\n"
@@ -114,7 +136,12 @@ func (h HCL) Condition() bool {
 
 // Context of the generate_hcl block.
 func (h HCL) Context() string {
-	return "stack"
+	return h.context
+}
+
+// Type of the original block, always [BlockType].
+func (h HCL) Type() string {
+	return BlockType
 }
 
 func (h HCL) String() string {
@@ -152,10 +179,20 @@ func Load(
 		return nil, errors.E("loading generate_hcl", err)
 	}
 
+	tree, _ := root.Lookup(st.Dir)
+	scopedLets, err := lets.LoadScoped(tree)
+	if err != nil {
+		return nil, errors.E("loading lets", err)
+	}
+
 	logger.Trace().Msg("generating HCL code.")
 
 	var hcls []HCL
 	for _, hclBlock := range hclBlocks {
+		if hclBlock.Context != StackContext {
+			continue
+		}
+
 		name := hclBlock.Label
 		evalctx := stack.NewEvalCtx(root, st, globals)
 
@@ -168,95 +205,125 @@ func Load(
 			stdlib.VendorFunc(vendorTargetDir, vendorDir, vendorRequests),
 		)
 
-		err := lets.Load(hclBlock.Lets, evalctx.Context)
+		file, err := Eval(root.HostDir(), hclBlock, scopedLets, evalctx.Context)
 		if err != nil {
 			return nil, err
 		}
+		hcls = append(hcls, file)
+	}
 
-		condition := true
-		if hclBlock.Condition != nil {
-			value, err := evalctx.Eval(hclBlock.Condition.Expr)
-			if err != nil {
-				return nil, errors.E(ErrConditionEval, err)
-			}
-			if value.Type() != cty.Bool {
-				return nil, errors.E(
-					ErrInvalidConditionType,
-					"condition has type %s but must be boolean",
-					value.Type().FriendlyName(),
-				)
-			}
-			condition = value.True()
-		}
-
-		if !condition {
-			hcls = append(hcls, HCL{
-				label:     name,
-				origin:    hclBlock.Range,
-				condition: condition,
-			})
-
-			continue
-		}
+	sort.SliceStable(hcls, func(i, j int) bool {
+		return hcls[i].Label() < hcls[j].Label()
+	})
 
-		asserts := make([]config.Assert, len(hclBlock.Asserts))
-		assertsErrs := errors.L()
-		assertFailed := false
+	logger.Trace().Msg("evaluated all blocks with success")
+	return hcls, nil
+}
 
-		for i, assertCfg := range hclBlock.Asserts {
-			assert, err := config.EvalAssert(evalctx.Context, assertCfg)
-			if err != nil {
-				assertsErrs.Append(err)
-				continue
-			}
-			asserts[i] = assert
-			if !assert.Assertion && !assert.Warning {
-				assertFailed = true
-			}
-		}
+// Eval the generate_hcl block.
+// The rootdir is used to read the template file of the block, if any.
+// The scopedLets are the lets shared by the generate blocks in scope.
+func Eval(rootdir string, block hcl.GenHCLBlock, scopedLets lets.Exprs, evalctx *eval.Context) (HCL, error) {
+	name := block.Label
+	err := lets.Load(block.Lets, scopedLets, evalctx)
+	if err != nil {
+		return HCL{}, err
+	}
 
-		if err := assertsErrs.AsError(); err != nil {
-			return nil, err
+	condition := true
+	if block.Condition != nil {
+		value, err := evalctx.Eval(block.Condition.Expr)
+		if err != nil {
+			return HCL{}, errors.E(ErrConditionEval, err)
 		}
-
-		if assertFailed {
-			hcls = append(hcls, HCL{
-				label:     name,
-				origin:    hclBlock.Range,
-				condition: condition,
-				asserts:   asserts,
-			})
-			continue
+		if value.Type() != cty.Bool {
+			return HCL{}, errors.E(
+				ErrInvalidConditionType,
+				"condition has type %s but must be boolean",
+				value.Type().FriendlyName(),
+			)
 		}
+		value, _ = value.UnmarkDeep()
+		condition = value.True()
+	}
 
-		evalctx.SetFunction(stdlib.Name("hcl_expression"), stdlib.HCLExpressionFunc())
+	if !condition {
+		return HCL{
+			label:     name,
+			context:   block.Context,
+			origin:    block.Range,
+			condition: condition,
+		}, nil
+	}
 
-		gen := hclwrite.NewEmptyFile()
-		if err := copyBody(gen.Body(), hclBlock.Content.Body, evalctx); err != nil {
-			return nil, errors.E(ErrContentEval, err, "generate_hcl %q", name)
-		}
+	asserts := make([]config.Assert, len(block.Asserts))
+	assertsErrs := errors.L()
+	assertFailed := false
 
-		formatted, err := fmt.FormatMultiline(string(gen.Bytes()), hclBlock.Range.HostPath())
+	for i, assertCfg := range block.Asserts {
+		assert, err := config.EvalAssert(evalctx, assertCfg)
 		if err != nil {
-			panic(errors.E(err,
-				"internal error: formatting generated code for generate_hcl %q:%s", name, string(gen.Bytes()),
-			))
+			assertsErrs.Append(err)
+			continue
 		}
-		hcls = append(hcls, HCL{
+		asserts[i] = assert
+		if !assert.Assertion && !assert.Warning {
+			assertFailed = true
+		}
+	}
+
+	if err := assertsErrs.AsError(); err != nil {
+		return HCL{}, err
+	}
+
+	if assertFailed {
+		return HCL{
 			label:     name,
-			origin:    hclBlock.Range,
-			body:      formatted,
+			context:   block.Context,
+			origin:    block.Range,
 			condition: condition,
 			asserts:   asserts,
-		})
+		}, nil
 	}
 
-	sort.SliceStable(hcls, func(i, j int) bool {
-		return hcls[i].Label() < hcls[j].Label()
-	})
+	if block.TemplateFile != "" {
+		formatted, err := evalTemplateFile(rootdir, block.TemplateFile, evalctx)
+		if err != nil {
+			return HCL{}, errors.E(ErrContentEval, err, "generate_hcl %q", name)
+		}
+		return HCL{
+			label:       name,
+			context:     block.Context,
+			origin:      block.Range,
+			body:        formatted,
+			condition:   condition,
+			asserts:     asserts,
+			postProcess: block.PostProcess,
+		}, nil
+	}
 
-	logger.Trace().Msg("evaluated all blocks with success")
-	return hcls, nil
+	evalctx.SetFunction(stdlib.Name("hcl_expression"), stdlib.HCLExpressionFunc())
+
+	gen := hclwrite.NewEmptyFile()
+	if err := copyBody(gen.Body(), block.Content.Body, evalctx, comments(block.Comments)); err != nil {
+		return HCL{}, errors.E(ErrContentEval, err, "generate_hcl %q", name)
+	}
+
+	formatted, err := fmt.FormatMultiline(string(gen.Bytes()), block.Range.HostPath())
+	if err != nil {
+		panic(errors.E(err,
+			"internal error: formatting generated code for generate_hcl %q:%s", name, string(gen.Bytes()),
+		))
+	}
+	return HCL{
+		label:       name,
+		context:     block.Context,
+		origin:      block.Range,
+		body:        formatted,
+		condition:   condition,
+		asserts:     asserts,
+		postProcess: block.PostProcess,
+	}, nil
 }
 
 type dynBlockAttributes struct {
@@ -267,6 +334,25 @@ type dynBlockAttributes struct {
 	condition  *hclsyntax.Attribute
 }
 
+// evalTemplateFile renders the template file at the given project path,
+// which must render to valid HCL code. The rendered code is formatted.
+func evalTemplateFile(rootdir string, templateFile string, evalctx *eval.Context) (string, error) {
+	filename := project.NewPath(templateFile).HostPath(rootdir)
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		return "", errors.E(err, "reading template file %s", templateFile)
+	}
+	rendered, err := evalctx.EvalTemplate(src, filename)
+	if err != nil {
+		return "", err
+	}
+	formatted, err := fmt.FormatMultiline(rendered, filename)
+	if err != nil {
+		return "", errors.E(err, "template file %s rendered invalid HCL code", templateFile)
+	}
+	return formatted, nil
+}
+
 // loadGenHCLBlocks will load all generate_hcl blocks.
 // The returned map maps the name of the block (its label)
 // to the original block and the path (relative to project root) of the config
@@ -299,12 +385,17 @@ func loadGenHCLBlocks(root *config.Root, cfgdir project.Path) ([]hcl.GenHCLBlock
 // Scoped traversals, like name.traverse, for unknown namespaces will be copied
 // as is (original expression form, no evaluation).
 //
+// The comments of the src body are copied along with the attributes and
+// blocks they belong to.
+//
 // Returns an error if the evaluation fails.
-func copyBody(dest *hclwrite.Body, src *hclsyntax.Body, eval hcl.Evaluator) error {
+func copyBody(dest *hclwrite.Body, src *hclsyntax.Body, evaluator hcl.Evaluator, comments comments) error {
 	logger := log.With().
 		Str("action", "genhcl.copyBody()").
 		Logger()
 
+	bodyComments := comments.forBody(src)
+
 	logger.Trace().Msg("sorting attributes")
 
 	attrs := ast.SortRawAttributes(ast.AsHCLAttributes(src.Attributes))
@@ -321,35 +412,47 @@ func copyBody(dest *hclwrite.Body, src *hclsyntax.Body, eval hcl.Evaluator) erro
 			Expression: attr.Expr.(hclsyntax.Expression),
 		}
 
-		newexpr, err := eval.PartialEval(expr)
+		newexpr, err := evaluator.PartialEval(expr)
 		if err != nil {
 			return errors.E(err, attr.Expr.Range())
 		}
+		if err := eval.CheckNotSensitive(newexpr); err != nil {
+			return errors.E(err, "attribute %s has a sensitive value", attr.Name)
+		}
 
 		logger.Trace().Str("attribute", attr.Name).Msg("Setting evaluated attribute.")
-		dest.SetAttributeRaw(attr.Name, ast.TokensForExpression(newexpr))
+		dest.AppendUnstructuredTokens(commentTokens(bodyComments.leading[attr.Range.Start.Byte]))
+		appendAttribute(dest, attr.Name, ast.TokensForExpression(newexpr),
+			bodyComments.trailing[attr.Range.Start.Byte])
 	}
 
 	logger.Trace().Msg("appending blocks")
 
 	for _, block := range src.Blocks {
-		err := appendBlock(dest, block, eval)
+		start := block.Range().Start.Byte
+		dest.AppendUnstructuredTokens(commentTokens(bodyComments.leading[start]))
+		err := appendBlock(dest, block, evaluator, comments)
 		if err != nil {
 			return err
 		}
+		dest.AppendUnstructuredTokens(commentTokens(bodyComments.trailing[start]))
 	}
 
+	dest.AppendUnstructuredTokens(commentTokens(bodyComments.end))
 	return nil
 }
 
-func appendBlock(target *hclwrite.Body, block *hclsyntax.Block, eval hcl.Evaluator) error {
-	if block.Type == "tm_dynamic" {
-		return appendDynamicBlocks(target, block, eval)
+func appendBlock(target *hclwrite.Body, block *hclsyntax.Block, evaluator hcl.Evaluator, comments comments) error {
+	switch block.Type {
+	case "tm_dynamic":
+		return appendDynamicBlocks(target, block, evaluator, comments)
+	case "tm_comment":
+		return appendComputedComment(target, block, evaluator)
 	}
 
 	targetBlock := target.AppendNewBlock(block.Type, block.Labels)
 	if block.Body != nil {
-		err := copyBody(targetBlock.Body(), block.Body, eval)
+		err := copyBody(targetBlock.Body(), block.Body, evaluator, comments)
 		if err != nil {
 			return err
 		}
@@ -363,6 +466,7 @@ func appendDynamicBlock(
 	genBlockType string,
 	attrs dynBlockAttributes,
 	contentBlock *hclsyntax.Block,
+	comments comments,
 ) error {
 	var labels []string
 	if attrs.labels != nil {
@@ -373,6 +477,12 @@ func appendDynamicBlock(
 				"failed to evaluate tm_dynamic.labels")
 		}
 
+		if eval.IsSensitive(labelsVal) {
+			return errors.E(ErrInvalidDynamicLabels, eval.ErrSensitive,
+				attrs.labels.Range(),
+				"tm_dynamic.labels has sensitive values: use tm_nonsensitive() to explicitly allow them")
+		}
+
 		labels, err = hcl.ValueAsStringList(labelsVal)
 		if err != nil {
 			return errors.E(ErrInvalidDynamicLabels,
@@ -385,9 +495,9 @@ func appendDynamicBlock(
 
 	attributeNames := map[string]struct{}{}
 	if attrs.attributes != nil {
-		attrsExpr, err := evaluator.PartialEval(attrs.attributes.Expr)
+		attrsExpr, err := evalDynamicAttributes(evaluator, attrs.attributes)
 		if err != nil {
-			return errors.E(ErrDynamicAttrsEval, err, attrs.attributes.Range())
+			return err
 		}
 
 		tmAttrs := []tmAttribute{}
@@ -401,6 +511,11 @@ func appendDynamicBlock(
 				return attrErr(attrs.attributes,
 					"tm_dynamic attributes must be an object, got %s instead", val.Type().FriendlyName())
 			}
+			if eval.IsSensitive(val) {
+				return errors.E(ErrDynamicAttrsEval, eval.ErrSensitive,
+					objectExpr.Range(),
+					"tm_dynamic.attributes has sensitive values: use tm_nonsensitive() to explicitly allow them")
+			}
 			iter := val.ElementIterator()
 			for iter.Next() {
 				key, val := iter.Element()
@@ -422,6 +537,11 @@ func appendDynamicBlock(
 						item.KeyExpr.Range(),
 						"evaluating tm_dynamic.attributes key")
 				}
+				if eval.IsSensitive(keyVal) {
+					return errors.E(ErrDynamicAttrsEval, eval.ErrSensitive,
+						item.KeyExpr.Range(),
+						"tm_dynamic.attributes key is sensitive: use tm_nonsensitive() to explicitly allow it")
+				}
 				if keyVal.Type() != cty.String {
 					return errors.E(ErrParsing, item.KeyExpr.Range(),
 						"tm_dynamic.attributes key %q has type %q, must be a string",
@@ -438,6 +558,10 @@ func appendDynamicBlock(
 						ast.TokensForExpression(item.ValueExpr),
 					)
 				}
+				if err := eval.CheckNotSensitive(valExpr); err != nil {
+					return errors.E(ErrDynamicAttrsEval, err,
+						"tm_dynamic.attributes.%s has a sensitive value", keyVal.AsString())
+				}
 				tmAttrs = append(tmAttrs, tmAttribute{
 					name:   keyVal.AsString(),
 					tokens: ast.TokensForExpression(valExpr),
@@ -471,7 +595,7 @@ func appendDynamicBlock(
 				)
 			}
 		}
-		err := copyBody(newblock.Body(), contentBlock.Body, evaluator)
+		err := copyBody(newblock.Body(), contentBlock.Body, evaluator, comments)
 		if err != nil {
 			return err
 		}
@@ -480,6 +604,36 @@ func appendDynamicBlock(
 	return nil
 }
 
+// evalDynamicAttributes partially evaluates the tm_dynamic.attributes
+// expression. A `for` expression is fully evaluated instead, so the names
+// of the attributes can be computed from globals, metadata and iterators.
+func evalDynamicAttributes(evaluator hcl.Evaluator, attr *hclsyntax.Attribute) (hhcl.Expression, error) {
+	expr := attr.Expr
+	for {
+		clone, ok := expr.(*ast.CloneExpression)
+		if !ok {
+			break
+		}
+		expr = clone.Expression
+	}
+	if _, ok := expr.(*hclsyntax.ForExpr); ok {
+		val, err := evaluator.Eval(attr.Expr)
+		if err != nil {
+			return nil, errors.E(ErrDynamicAttrsEval, err, attr.Range())
+		}
+		return &hclsyntax.LiteralValueExpr{
+			Val:      val,
+			SrcRange: attr.Expr.Range(),
+		}, nil
+	}
+
+	attrsExpr, err := evaluator.PartialEval(attr.Expr)
+	if err != nil {
+		return nil, errors.E(ErrDynamicAttrsEval, err, attr.Range())
+	}
+	return attrsExpr, nil
+}
+
 type tmAttribute struct {
 	name   string
 	tokens hclwrite.Tokens
@@ -498,7 +652,12 @@ func setBodyAttributes(body *hclwrite.Body, attrs []tmAttribute) error {
 	return nil
 }
 
-func appendDynamicBlocks(target *hclwrite.Body, dynblock *hclsyntax.Block, evaluator hcl.Evaluator) error {
+func appendDynamicBlocks(
+	target *hclwrite.Body,
+	dynblock *hclsyntax.Block,
+	evaluator hcl.Evaluator,
+	comments comments,
+) error {
 	logger := log.With().
 		Str("action", "genhcl.appendDynamicBlock").
 		Logger()
@@ -540,6 +699,7 @@ func appendDynamicBlocks(target *hclwrite.Body, dynblock *hclsyntax.Block, evalu
 		if condition.Type() != cty.Bool {
 			return errors.E(ErrDynamicConditionEval, "want boolean got %s", condition.Type().FriendlyName())
 		}
+		condition, _ = condition.UnmarkDeep()
 		if !condition.True() {
 			logger.Trace().Msg("condition is false, ignoring block")
 			return nil
@@ -563,6 +723,9 @@ func appendDynamicBlocks(target *hclwrite.Body, dynblock *hclsyntax.Block, evalu
 		}
 	}
 
+	// the marks of for_each (eg.: sensitive) are kept on each element.
+	foreach, foreachMarks := foreach.Unmark()
+
 	if foreach.IsNull() {
 		logger.Trace().Msg("no for_each, generating single block")
 
@@ -573,7 +736,7 @@ func appendDynamicBlocks(target *hclwrite.Body, dynblock *hclsyntax.Block, evalu
 		}
 
 		return appendDynamicBlock(target, evaluator,
-			genBlockType, attrs, contentBlock)
+			genBlockType, attrs, contentBlock, comments)
 	}
 
 	logger.Trace().Msg("defining iterator name")
@@ -603,14 +766,19 @@ func appendDynamicBlocks(target *hclwrite.Body, dynblock *hclsyntax.Block, evalu
 
 	var tmDynamicErr error
 
+	// the iterator is scoped to the tm_dynamic block, so an iterator of an
+	// outer block (or any namespace) with the same name is shadowed and
+	// restored afterwards.
+	shadowed, hasShadowed := evaluator.GetNamespace(iterator)
+
 	foreach.ForEachElement(func(key, value cty.Value) (stop bool) {
 		evaluator.SetNamespace(iterator, map[string]cty.Value{
-			"key":   key,
-			"value": value,
+			"key":   key.WithMarks(foreachMarks),
+			"value": value.WithMarks(foreachMarks),
 		})
 
 		if err := appendDynamicBlock(target, evaluator,
-			genBlockType, attrs, contentBlock); err != nil {
+			genBlockType, attrs, contentBlock, comments); err != nil {
 			tmDynamicErr = err
 			return true
 		}
@@ -619,6 +787,9 @@ func appendDynamicBlocks(target *hclwrite.Body, dynblock *hclsyntax.Block, evalu
 	})
 
 	evaluator.DeleteNamespace(iterator)
+	if hasShadowed {
+		evaluator.SetNamespace(iterator, shadowed.AsValueMap())
+	}
 	return tmDynamicErr
 }
 
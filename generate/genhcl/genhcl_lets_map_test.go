@@ -299,6 +299,47 @@ func TestGenHCLLetsMap(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:  "lets map with condition and group",
+			stack: "/stack",
+			configs: []hclconfig{
+				{
+					path: "/stack",
+					add: GenerateHCL(
+						Labels("test.tf"),
+						Lets(
+							Str("skip", "b"),
+							Map(
+								Labels("val"),
+								Expr("iterator", "el"),
+								Expr("for_each", `["a1", "b1", "a2", "b2", "c1"]`),
+								Expr("condition", `tm_substr(el.new, 0, 1) != let.skip`),
+								Bool("group", true),
+								Expr("key", `tm_substr(el.new, 0, 1)`),
+								Expr("value", "el.new"),
+							),
+						),
+						Content(
+							Expr("val", "let.val"),
+						),
+					),
+				},
+			},
+			want: []result{
+				{
+					name: "test.tf",
+					hcl: genHCL{
+						condition: true,
+						body: Doc(
+							EvalExpr(t, "val", `{
+								a = ["a1", "a2"]
+								c = ["c1"]
+							}`),
+						),
+					},
+				},
+			},
+		},
 	} {
 		tc.run(t)
 	}
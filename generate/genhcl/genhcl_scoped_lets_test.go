@@ -0,0 +1,299 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package genhcl_test
+
+import (
+	"testing"
+
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/hcl"
+	"github.com/terramate-io/terramate/hcl/eval"
+	. "github.com/terramate-io/terramate/test/hclwrite/hclutils"
+)
+
+func TestGenerateHCLScopedLets(t *testing.T) {
+	t.Parallel()
+
+	for _, tcase := range []testcase{
+		{
+			name:  "scoped lets shared by all generate blocks",
+			stack: "/stack",
+			configs: []hclconfig{
+				{
+					path: "/",
+					add: Lets(
+						Str("region", "us-east-1"),
+						Expr("name", `"${global.env}-${let.region}"`),
+					),
+				},
+				{
+					path: "/",
+					add: Globals(
+						Str("env", "dev"),
+					),
+				},
+				{
+					path: "/stack",
+					add: Doc(
+						GenerateHCL(
+							Labels("a.tf"),
+							Content(
+								Block("a",
+									Expr("name", "let.name"),
+								),
+							),
+						),
+						GenerateHCL(
+							Labels("b.tf"),
+							Content(
+								Block("b",
+									Expr("region", "let.region"),
+								),
+							),
+						),
+					),
+				},
+			},
+			want: []result{
+				{
+					name: "a.tf",
+					hcl: genHCL{
+						condition: true,
+						body: Block("a",
+							Str("name", "dev-us-east-1"),
+						),
+					},
+				},
+				{
+					name: "b.tf",
+					hcl: genHCL{
+						condition: true,
+						body: Block("b",
+							Str("region", "us-east-1"),
+						),
+					},
+				},
+			},
+		},
+		{
+			name:  "child dir lets take precedence over parent lets",
+			stack: "/stacks/stack",
+			configs: []hclconfig{
+				{
+					path: "/",
+					add: Lets(
+						Str("region", "us-east-1"),
+						Expr("zone", `"${let.region}a"`),
+					),
+				},
+				{
+					path: "/stacks",
+					add: Lets(
+						Str("region", "eu-west-1"),
+					),
+				},
+				{
+					path: "/",
+					add: GenerateHCL(
+						Labels("zone.tf"),
+						Content(
+							Block("zone",
+								Expr("region", "let.region"),
+								Expr("zone", "let.zone"),
+							),
+						),
+					),
+				},
+			},
+			want: []result{
+				{
+					name: "zone.tf",
+					hcl: genHCL{
+						condition: true,
+						body: Block("zone",
+							Str("region", "eu-west-1"),
+							Str("zone", "eu-west-1a"),
+						),
+					},
+				},
+			},
+		},
+		{
+			name:  "generate block lets take precedence over scoped lets",
+			stack: "/stack",
+			configs: []hclconfig{
+				{
+					path: "/",
+					add: Lets(
+						Str("region", "us-east-1"),
+						Expr("zone", `"${let.region}a"`),
+					),
+				},
+				{
+					path: "/stack",
+					add: Doc(
+						GenerateHCL(
+							Labels("local.tf"),
+							Lets(
+								Str("region", "sa-east-1"),
+							),
+							Content(
+								Block("zone",
+									Expr("zone", "let.zone"),
+								),
+							),
+						),
+						GenerateHCL(
+							Labels("scoped.tf"),
+							Content(
+								Block("zone",
+									Expr("zone", "let.zone"),
+								),
+							),
+						),
+					),
+				},
+			},
+			want: []result{
+				{
+					name: "local.tf",
+					hcl: genHCL{
+						condition: true,
+						body: Block("zone",
+							Str("zone", "sa-east-1a"),
+						),
+					},
+				},
+				{
+					name: "scoped.tf",
+					hcl: genHCL{
+						condition: true,
+						body: Block("zone",
+							Str("zone", "us-east-1a"),
+						),
+					},
+				},
+			},
+		},
+		{
+			name:  "scoped lets with map blocks",
+			stack: "/stack",
+			configs: []hclconfig{
+				{
+					path: "/",
+					add: Lets(
+						Block("map",
+							Labels("ports"),
+							Expr("for_each", `["http", "https"]`),
+							Expr("key", "element.new"),
+							Expr("value", `element.new == "http" ? 80 : 443`),
+						),
+					),
+				},
+				{
+					path: "/stack",
+					add: GenerateHCL(
+						Labels("ports.tf"),
+						Content(
+							Block("ports",
+								Expr("https", "let.ports.https"),
+							),
+						),
+					),
+				},
+			},
+			want: []result{
+				{
+					name: "ports.tf",
+					hcl: genHCL{
+						condition: true,
+						body: Block("ports",
+							Number("https", 443),
+						),
+					},
+				},
+			},
+		},
+		{
+			name:  "unset scoped let in generate block",
+			stack: "/stack",
+			configs: []hclconfig{
+				{
+					path: "/",
+					add: Lets(
+						Str("region", "us-east-1"),
+					),
+				},
+				{
+					path: "/stack",
+					add: GenerateHCL(
+						Labels("region.tf"),
+						Lets(
+							Expr("region", "unset"),
+						),
+						Content(
+							Block("region",
+								Expr("region", "let.region"),
+							),
+						),
+					),
+				},
+			},
+			wantErr: errors.E(eval.ErrPartial),
+		},
+		{
+			name:  "scoped lets are not globals",
+			stack: "/stack",
+			configs: []hclconfig{
+				{
+					path: "/",
+					add: Lets(
+						Str("region", "us-east-1"),
+					),
+				},
+				{
+					path: "/stack",
+					add: GenerateHCL(
+						Labels("region.tf"),
+						Content(
+							Block("region",
+								Expr("region", "global.region"),
+							),
+						),
+					),
+				},
+			},
+			wantErr: errors.E(eval.ErrPartial),
+		},
+		{
+			name:  "scoped lets with unrecognized block fails",
+			stack: "/stack",
+			configs: []hclconfig{
+				{
+					path: "/",
+					add: Lets(
+						Block("unknown"),
+					),
+				},
+			},
+			wantErr: errors.E(hcl.ErrTerramateSchema),
+		},
+		{
+			name:  "scoped lets with labels fails",
+			stack: "/stack",
+			configs: []hclconfig{
+				{
+					path: "/",
+					add: Lets(
+						Labels("region"),
+						Str("region", "us-east-1"),
+					),
+				},
+			},
+			wantErr: errors.E(hcl.ErrTerramateSchema),
+		},
+	} {
+		tcase.run(t)
+	}
+}
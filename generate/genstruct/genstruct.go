@@ -0,0 +1,344 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+// Package genstruct implements generate_json and generate_yaml code generation.
+package genstruct
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/terramate-io/terramate/config"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/event"
+	"github.com/terramate-io/terramate/hcl"
+	"github.com/terramate-io/terramate/hcl/eval"
+	"github.com/terramate-io/terramate/hcl/info"
+	"github.com/terramate-io/terramate/lets"
+	"github.com/terramate-io/terramate/project"
+	"github.com/terramate-io/terramate/stack"
+	"github.com/terramate-io/terramate/stdlib"
+	ctyyaml "github.com/zclconf/go-cty-yaml"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+const (
+	// ErrInvalidContentType indicates the content attribute
+	// has an invalid type.
+	ErrInvalidContentType errors.Kind = "invalid content type"
+
+	// ErrInvalidConditionType indicates the condition attribute
+	// has an invalid type.
+	ErrInvalidConditionType errors.Kind = "invalid condition type"
+
+	// ErrContentEval indicates an error when evaluating the content attribute.
+	ErrContentEval errors.Kind = "evaluating content"
+
+	// ErrConditionEval indicates an error when evaluating the condition attribute.
+	ErrConditionEval errors.Kind = "evaluating condition"
+)
+
+const (
+	// JSONBlockType is the type of the generate_json block.
+	JSONBlockType = "generate_json"
+
+	// YAMLBlockType is the type of the generate_yaml block.
+	YAMLBlockType = "generate_yaml"
+)
+
+const (
+	// HeaderMessage is the message used to mark the files as generated.
+	HeaderMessage = "TERRAMATE: GENERATED AUTOMATICALLY DO NOT EDIT"
+
+	// JSONHeaderKey is the key of the object attribute holding the
+	// header message on generated JSON files.
+	JSONHeaderKey = "//"
+
+	// JSONOriginKey is the key of the object attribute holding the origin
+	// of generated JSON files when provenance headers are enabled.
+	JSONOriginKey = JSONHeaderKey + "origin"
+
+	// JSONHashKey is the key of the object attribute holding the content
+	// hash of generated JSON files when provenance headers are enabled.
+	JSONHashKey = JSONHeaderKey + "sha256"
+
+	// JSONHeader is the header of the generated JSON files.
+	// JSON has no comments, so the header is the first attribute of the
+	// generated object.
+	JSONHeader = "{\n  \"" + JSONHeaderKey + "\": \"" + HeaderMessage + "\""
+
+	// YAMLHeader is the header of the generated YAML files.
+	YAMLHeader = "# " + HeaderMessage
+)
+
+// JSONReservedKeys are the keys of the generated JSON header attributes,
+// which cannot be used in the content of generate_json blocks.
+var JSONReservedKeys = []string{JSONHeaderKey, JSONOriginKey, JSONHashKey}
+
+// File represents generated file from a single generate_json or
+// generate_yaml block.
+type File struct {
+	label       string
+	blockType   string
+	origin      info.Range
+	header      string
+	body        string
+	condition   bool
+	asserts     []config.Assert
+	postProcess []hcl.PostProcessConfig
+}
+
+// Label of the original block.
+func (f File) Label() string {
+	return f.label
+}
+
+// Header returns the header of the generated file.
+// The header of JSON files is the opening of the generated object, so the
+// file content is always [File.Header] + [File.Body].
+func (f File) Header() string {
+	return f.header
+}
+
+// Body returns the file body.
+func (f File) Body() string {
+	return f.body
+}
+
+// Range returns the range information of the original block.
+func (f File) Range() info.Range {
+	return f.origin
+}
+
+// Condition returns the result of the evaluation of the
+// condition attribute for the generated code.
+func (f File) Condition() bool {
+	return f.condition
+}
+
+// Context of the block.
+func (f File) Context() string {
+	return "stack"
+}
+
+// Type of the original block, [JSONBlockType] or [YAMLBlockType].
+func (f File) Type() string {
+	return f.blockType
+}
+
+// Asserts returns all (if any) of the evaluated assert configs of the
+// block. If [File.Condition] returns false then assert configs
+// will always be empty since they are not evaluated at all in that case.
+func (f File) Asserts() []config.Assert {
+	return f.asserts
+}
+
+// PostProcess returns the post-processors of the block.
+func (f File) PostProcess() []hcl.PostProcessConfig {
+	return f.postProcess
+}
+
+func (f File) String() string {
+	return fmt.Sprintf("%s %q (condition %t) (body %q) (origin %q)",
+		f.Type(), f.Label(), f.Condition(), f.Body(), f.Range().Path())
+}
+
+// Load loads from the file system all generate_json and generate_yaml blocks
+// for a given stack. It will navigate the file system from the stack dir
+// until it reaches rootdir, loading the blocks found on Terramate
+// configuration files.
+//
+// Metadata and globals for the stack are used on the evaluation of the
+// blocks.
+func Load(
+	root *config.Root,
+	st *config.Stack,
+	globals *eval.Object,
+	vendorDir project.Path,
+	vendorRequests chan<- event.VendorRequest,
+) ([]File, error) {
+	blocks := loadBlocks(root, st.Dir)
+
+	tree, _ := root.Lookup(st.Dir)
+	scopedLets, err := lets.LoadScoped(tree)
+	if err != nil {
+		return nil, errors.E("loading lets", err)
+	}
+
+	var files []File
+	for _, block := range blocks {
+		evalctx := stack.NewEvalCtx(root, st, globals)
+		vendorTargetDir := project.NewPath(path.Join(
+			st.Dir.String(),
+			path.Dir(block.Label)))
+
+		evalctx.SetFunction(stdlib.Name("vendor"), stdlib.VendorFunc(vendorTargetDir, vendorDir, vendorRequests))
+
+		file, err := Eval(block, scopedLets, evalctx.Context)
+		if err != nil {
+			return nil, errors.E(err, "%s %q", block.Type, block.Label)
+		}
+		files = append(files, file)
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].Label() < files[j].Label()
+	})
+	return files, nil
+}
+
+// Eval the generate_json or generate_yaml block.
+// The scopedLets are the lets shared by the generate blocks in scope.
+func Eval(block hcl.GenStructBlock, scopedLets lets.Exprs, evalctx *eval.Context) (File, error) {
+	file := File{
+		label:     block.Label,
+		blockType: block.Type,
+		origin:    block.Range,
+	}
+
+	err := lets.Load(block.Lets, scopedLets, evalctx)
+	if err != nil {
+		return File{}, err
+	}
+
+	file.condition = true
+	if block.Condition != nil {
+		value, err := evalctx.Eval(block.Condition.Expr)
+		if err != nil {
+			return File{}, errors.E(ErrConditionEval, err)
+		}
+		if value.Type() != cty.Bool {
+			return File{}, errors.E(
+				ErrInvalidConditionType,
+				"condition has type %s but must be boolean",
+				value.Type().FriendlyName(),
+			)
+		}
+		value, _ = value.UnmarkDeep()
+		file.condition = value.True()
+	}
+
+	if !file.condition {
+		return file, nil
+	}
+
+	file.asserts = make([]config.Assert, len(block.Asserts))
+	assertsErrs := errors.L()
+	assertFailed := false
+
+	for i, assertCfg := range block.Asserts {
+		assert, err := config.EvalAssert(evalctx, assertCfg)
+		if err != nil {
+			assertsErrs.Append(err)
+			continue
+		}
+		file.asserts[i] = assert
+		if !assert.Assertion && !assert.Warning {
+			assertFailed = true
+		}
+	}
+
+	if err := assertsErrs.AsError(); err != nil {
+		return File{}, err
+	}
+
+	if assertFailed {
+		return file, nil
+	}
+
+	value, err := evalctx.Eval(block.Content.Expr)
+	if err != nil {
+		return File{}, errors.E(ErrContentEval, err)
+	}
+
+	if eval.IsSensitive(value) {
+		return File{}, errors.E(ErrContentEval, eval.ErrSensitive,
+			block.Content.Expr.Range(),
+			"content has sensitive values: use tm_nonsensitive() to explicitly allow them")
+	}
+
+	typ := value.Type()
+	if value.IsNull() || !(typ.IsObjectType() || typ.IsMapType()) {
+		return File{}, errors.E(
+			ErrInvalidContentType,
+			block.Content.Expr.Range(),
+			"content has type %s but must be an object",
+			typ.FriendlyName(),
+		)
+	}
+
+	if block.Type == JSONBlockType {
+		for _, key := range JSONReservedKeys {
+			if value.Type().IsObjectType() && value.Type().HasAttribute(key) ||
+				value.Type().IsMapType() && value.LengthInt() > 0 && value.HasIndex(cty.StringVal(key)).True() {
+				return File{}, errors.E(
+					ErrInvalidContentType,
+					block.Content.Expr.Range(),
+					"content object key %q is reserved for the generated file header",
+					key,
+				)
+			}
+		}
+		file.header, file.body, err = encodeJSON(value)
+	} else {
+		file.header, file.body, err = encodeYAML(value)
+	}
+	if err != nil {
+		return File{}, errors.E(ErrContentEval, err, block.Content.Expr.Range())
+	}
+	file.postProcess = block.PostProcess
+	return file, nil
+}
+
+// encodeJSON encodes the object as canonical (keys sorted and 2 spaces
+// indented) JSON, with the header message as the first attribute.
+func encodeJSON(obj cty.Value) (header string, body string, err error) {
+	data, err := ctyjson.Marshal(obj, obj.Type())
+	if err != nil {
+		return "", "", err
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, data, "", "  "); err != nil {
+		return "", "", err
+	}
+
+	// out is "{}" for empty objects or "{\n  ...\n}" otherwise, then the
+	// attributes are appended to the object opened by the header.
+	attrs := strings.TrimSuffix(strings.TrimPrefix(out.String(), "{"), "}")
+	attrs = strings.TrimSpace(attrs)
+	if attrs == "" {
+		return JSONHeader, "\n}\n", nil
+	}
+	return JSONHeader, ",\n  " + attrs + "\n}\n", nil
+}
+
+// encodeYAML encodes the object as canonical (keys sorted) YAML.
+func encodeYAML(obj cty.Value) (header string, body string, err error) {
+	data, err := ctyyaml.Standard.Marshal(obj)
+	if err != nil {
+		return "", "", err
+	}
+	return YAMLHeader + "\n\n", string(data), nil
+}
+
+// loadBlocks loads all generate_json and generate_yaml blocks from the
+// cfgdir up to the project root.
+func loadBlocks(root *config.Root, cfgdir project.Path) []hcl.GenStructBlock {
+	res := []hcl.GenStructBlock{}
+	cfg, ok := root.Lookup(cfgdir)
+	if ok && !cfg.IsEmptyConfig() {
+		res = append(res, cfg.Node.Generate.JSONs...)
+		res = append(res, cfg.Node.Generate.YAMLs...)
+	}
+
+	parentCfgDir := cfgdir.Dir()
+	if parentCfgDir == cfgdir {
+		return res
+	}
+	return append(res, loadBlocks(root, parentCfgDir)...)
+}
@@ -0,0 +1,136 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package generate
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/terramate-io/terramate/config"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/generate/genstruct"
+	"github.com/terramate-io/terramate/hcl"
+	"github.com/terramate-io/terramate/hcl/fmt"
+	"github.com/terramate-io/terramate/project"
+)
+
+// postProcessedFile is a generated file whose body was post-processed.
+type postProcessedFile struct {
+	GenFile
+	body string
+}
+
+// Body returns the post-processed body of the file.
+func (f postProcessedFile) Body() string {
+	return f.body
+}
+
+// CommentPrefix returns the comment prefix of the original file, if any.
+func (f postProcessedFile) CommentPrefix() string {
+	if file, ok := f.GenFile.(commentPrefixer); ok {
+		return file.CommentPrefix()
+	}
+	return ""
+}
+
+// withPostProcessing returns the file with its body post-processed by the
+// post-processors of the project whose files patterns match the base name
+// of the file, followed by the post-processors of its generate block, in the
+// order they are defined. The dir is the directory where the file is
+// generated. Files of generate_json blocks are never post-processed.
+//
+// External commands run on the project root directory and the project path
+// of the generated file is available on the TM_GENERATED_FILE environment
+// variable.
+//
+// The provenance header, if any, must be added after post-processing, so
+// the recorded hash matches the final content of the file.
+func withPostProcessing(root *config.Root, dir project.Path, file GenFile) (GenFile, error) {
+	// the post_process attribute is rejected on generate_json blocks by the
+	// parser, so only the project post-processors could match them.
+	if !file.Condition() || file.Type() == genstruct.JSONBlockType {
+		return file, nil
+	}
+
+	processors := append(projectPostProcessors(root, file.Label()), file.PostProcess()...)
+	if len(processors) == 0 {
+		return file, nil
+	}
+
+	filename := dir.Join(file.Label())
+	body := file.Body()
+	for _, processor := range processors {
+		var err error
+		body, err = postProcess(root, filename, processor, body)
+		if err != nil {
+			return nil, errors.E(ErrPostProcess, file.Range(), err,
+				"generate block %q", file.Label())
+		}
+	}
+	return postProcessedFile{
+		GenFile: file,
+		body:    body,
+	}, nil
+}
+
+// projectPostProcessors returns the post-processors defined on the project
+// configuration that match the given generate block label.
+func projectPostProcessors(root *config.Root, label string) []hcl.PostProcessConfig {
+	cfg := root.Tree().Node.Terramate
+	if cfg == nil || cfg.Config == nil || cfg.Config.Generate == nil {
+		return nil
+	}
+
+	name := path.Base(label)
+	var processors []hcl.PostProcessConfig
+	for _, processor := range cfg.Config.Generate.PostProcess {
+		for _, pattern := range processor.Files {
+			if matched, _ := path.Match(pattern, name); matched {
+				processors = append(processors, processor)
+				break
+			}
+		}
+	}
+	return processors
+}
+
+func postProcess(
+	root *config.Root,
+	filename project.Path,
+	processor hcl.PostProcessConfig,
+	body string,
+) (string, error) {
+	if processor.Format == hcl.PostProcessFormatHCL {
+		formatted, err := fmt.FormatMultiline(body, filename.String())
+		if err != nil {
+			return "", errors.E(err, "formatting HCL code")
+		}
+		return formatted, nil
+	}
+
+	logger := log.With().
+		Str("action", "generate.postProcess()").
+		Stringer("file", filename).
+		Strs("command", processor.Command).
+		Logger()
+
+	logger.Debug().Msg("running post-processing command")
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(processor.Command[0], processor.Command[1:]...)
+	cmd.Dir = root.HostDir()
+	cmd.Env = append(os.Environ(), "TM_GENERATED_FILE="+filename.String())
+	cmd.Stdin = strings.NewReader(body)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", errors.E(err, "running command %q: %s",
+			strings.Join(processor.Command, " "), strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,172 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package generate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/terramate-io/terramate/config"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/generate/genfile"
+	"github.com/terramate-io/terramate/generate/genhcl"
+	"github.com/terramate-io/terramate/generate/genstruct"
+)
+
+const (
+	// ProvenanceOriginMsg is the prefix of the provenance header line that
+	// records the origin generate block of the generated file.
+	ProvenanceOriginMsg = "TERRAMATE: originated from"
+
+	// ProvenanceHashMsg is the prefix of the provenance header line that
+	// records the hash of the generated file content.
+	ProvenanceHashMsg = "TERRAMATE: sha256 "
+
+	// provenanceHeaderLines is the maximum number of lines of a provenance
+	// header, the hash is only looked up on them.
+	provenanceHeaderLines = 4
+)
+
+// provenanceFile is a generated file with a provenance header.
+type provenanceFile struct {
+	GenFile
+	header string
+}
+
+// Header returns the provenance header of the file.
+func (f provenanceFile) Header() string {
+	return f.header
+}
+
+// commentPrefixer is a generated file with a configurable comment prefix,
+// like the generate_file files.
+type commentPrefixer interface {
+	CommentPrefix() string
+}
+
+// provenanceEnabled tells if the project enables the provenance header on
+// all generated files that support comments.
+func provenanceEnabled(root *config.Root) bool {
+	cfg := root.Tree().Node.Terramate
+	return cfg != nil &&
+		cfg.Config != nil &&
+		cfg.Config.Generate != nil &&
+		cfg.Config.Generate.Provenance
+}
+
+// withProvenance returns the file with a provenance header, if the file
+// needs one. The provenance header records the origin generate block and
+// the sha256 of the whole file content, computed with the hash itself
+// left empty on the header.
+//
+// The generate_hcl, generate_json and generate_yaml files get the header
+// when the project enables it, and generate_file files get it when the
+// block defines a comment_prefix.
+func withProvenance(root *config.Root, file GenFile) GenFile {
+	if !file.Condition() {
+		return file
+	}
+
+	origin := fmt.Sprintf("%s %s block on %s:%d",
+		ProvenanceOriginMsg, file.Type(), file.Range().Path(), file.Range().Start().Line())
+
+	var header string
+	switch file.Type() {
+	case genfile.BlockType:
+		f, ok := file.(commentPrefixer)
+		if !ok || f.CommentPrefix() == "" {
+			return file
+		}
+		header = commentProvenanceHeader(f.CommentPrefix(), origin)
+	case genhcl.BlockType:
+		if !provenanceEnabled(root) {
+			return file
+		}
+		header = commentProvenanceHeader("//", origin)
+	case genstruct.YAMLBlockType:
+		if !provenanceEnabled(root) {
+			return file
+		}
+		header = commentProvenanceHeader("#", origin)
+	case genstruct.JSONBlockType:
+		if !provenanceEnabled(root) {
+			return file
+		}
+		header = genstruct.JSONHeader +
+			",\n  " + jsonAttr(genstruct.JSONOriginKey, origin) +
+			",\n  " + jsonAttr(genstruct.JSONHashKey, ProvenanceHashMsg)
+	default:
+		panic(errors.E(errors.ErrInternal, "unexpected generate block type %q", file.Type()))
+	}
+
+	return provenanceFile{
+		GenFile: file,
+		header:  addProvenanceHash(header, file.Body()),
+	}
+}
+
+func commentProvenanceHeader(prefix string, origin string) string {
+	return prefix + " " + genstruct.HeaderMessage + "\n" +
+		prefix + " " + origin + "\n" +
+		prefix + " " + ProvenanceHashMsg + "\n\n"
+}
+
+func jsonAttr(key, value string) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		panic(errors.E(errors.ErrInternal, err, "encoding JSON string"))
+	}
+	return fmt.Sprintf("%q: %s", key, data)
+}
+
+// addProvenanceHash adds the hash of header+body right after the
+// [ProvenanceHashMsg] of the header.
+func addProvenanceHash(header, body string) string {
+	sum := sha256.Sum256([]byte(header + body))
+	pos := strings.Index(header, ProvenanceHashMsg) + len(ProvenanceHashMsg)
+	return header[:pos] + hex.EncodeToString(sum[:]) + header[pos:]
+}
+
+// hasProvenanceHeader tells if the code starts with a provenance header
+// using any comment prefix.
+func hasProvenanceHeader(code string) bool {
+	lines := strings.SplitN(code, "\n", 3)
+	return len(lines) == 3 &&
+		strings.HasSuffix(lines[0], " "+genstruct.HeaderMessage) &&
+		strings.Contains(lines[1], ProvenanceOriginMsg)
+}
+
+// checkProvenance checks that the given content of a generated file was not
+// manually edited since it was generated, by checking the hash recorded on
+// its provenance header. Files without a provenance header are not checked.
+func checkProvenance(path string, content string) error {
+	header := content
+	for i, pos := 0, 0; i < provenanceHeaderLines; i++ {
+		next := strings.IndexByte(content[pos:], '\n')
+		if next == -1 {
+			break
+		}
+		pos += next + 1
+		header = content[:pos]
+	}
+
+	start := strings.Index(header, ProvenanceHashMsg)
+	if start == -1 {
+		return nil
+	}
+	start += len(ProvenanceHashMsg)
+	end := start + 2*sha256.Size
+	if end > len(content) {
+		return errors.E(ErrManualEdits, "file %q has an invalid provenance hash", path)
+	}
+
+	sum := sha256.Sum256([]byte(content[:start] + content[end:]))
+	if hex.EncodeToString(sum[:]) != content[start:end] {
+		return errors.E(ErrManualEdits, "file %q was changed after it was generated", path)
+	}
+	return nil
+}
@@ -0,0 +1,273 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package generate_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/generate"
+	"github.com/terramate-io/terramate/project"
+	. "github.com/terramate-io/terramate/test/hclwrite/hclutils"
+	"github.com/terramate-io/terramate/test/sandbox"
+)
+
+func TestGenerateProvenanceHeader(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{"s:stack"})
+	s.RootEntry().CreateFile("terramate.tm", Terramate(
+		Config(
+			Block("generate",
+				Bool("provenance", true),
+			),
+		),
+	).String())
+
+	stack := s.DirEntry("stack")
+	stack.CreateFile("config.tm", Doc(
+		GenerateHCL(
+			Labels("file.hcl"),
+			Content(
+				Str("a", "b"),
+			),
+		),
+		GenerateJSON(
+			Labels("file.json"),
+			Expr("content", `{ a = "b" }`),
+		),
+		GenerateYAML(
+			Labels("file.yml"),
+			Expr("content", `{ a = "b" }`),
+		),
+		GenerateFile(
+			Labels("file.sh"),
+			Str("comment_prefix", "#"),
+			Expr("content", `"echo hi\n"`),
+		),
+		GenerateFile(
+			Labels("file.txt"),
+			Expr("content", `"no header\n"`),
+		),
+	).String())
+
+	s.Generate()
+
+	assertProvenance := func(filename, want string) {
+		t.Helper()
+		assert.EqualStrings(t, withProvenanceHash(t, want), string(stack.ReadFile(filename)),
+			"file %s", filename)
+	}
+
+	assertProvenance("file.hcl",
+		"// TERRAMATE: GENERATED AUTOMATICALLY DO NOT EDIT\n"+
+			"// TERRAMATE: originated from generate_hcl block on /stack/config.tm:1\n"+
+			"// TERRAMATE: sha256 \n"+
+			"\n"+
+			"a = \"b\"\n")
+
+	assertProvenance("file.json",
+		"{\n"+
+			"  \"//\": \"TERRAMATE: GENERATED AUTOMATICALLY DO NOT EDIT\",\n"+
+			"  \"//origin\": \"TERRAMATE: originated from generate_json block on /stack/config.tm:6\",\n"+
+			"  \"//sha256\": \"TERRAMATE: sha256 \",\n"+
+			"  \"a\": \"b\"\n"+
+			"}\n")
+
+	assertProvenance("file.yml",
+		"# TERRAMATE: GENERATED AUTOMATICALLY DO NOT EDIT\n"+
+			"# TERRAMATE: originated from generate_yaml block on /stack/config.tm:9\n"+
+			"# TERRAMATE: sha256 \n"+
+			"\n"+
+			"\"a\": \"b\"\n")
+
+	assertProvenance("file.sh",
+		"# TERRAMATE: GENERATED AUTOMATICALLY DO NOT EDIT\n"+
+			"# TERRAMATE: originated from generate_file block on /stack/config.tm:12\n"+
+			"# TERRAMATE: sha256 \n"+
+			"\n"+
+			"echo hi\n")
+
+	assert.EqualStrings(t, "no header\n", string(stack.ReadFile("file.txt")))
+
+	files, err := generate.ListGenFiles(s.Config(), stack.Path())
+	assert.NoError(t, err)
+	assertEqualStringList(t, files, []string{"file.hcl", "file.json", "file.sh", "file.yml"})
+
+	outdated, err := generate.DetectOutdated(s.Config(), project.NewPath("/modules"), nil)
+	assert.NoError(t, err)
+	assertEqualStringList(t, outdated, []string{})
+}
+
+func TestGenerateProvenanceRefusesManualEdits(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{"s:stack"})
+	stack := s.DirEntry("stack")
+	stack.CreateFile("config.tm", GenerateFile(
+		Labels("file.sh"),
+		Str("comment_prefix", "#"),
+		Expr("content", `"echo hi\n"`),
+	).String())
+
+	s.Generate()
+
+	edited := string(stack.ReadFile("file.sh")) + "echo manual\n"
+	stack.CreateFile("file.sh", edited)
+
+	stack.CreateFile("config.tm", GenerateFile(
+		Labels("file.sh"),
+		Str("comment_prefix", "#"),
+		Expr("content", `"echo changed\n"`),
+	).String())
+
+	vendorDir := project.NewPath("/modules")
+	report := generate.Do(s.ReloadConfig(), vendorDir, nil, generate.Options{})
+	assert.EqualInts(t, 1, len(report.Failures), "report: %s", report.Full())
+	assert.IsTrue(t, errors.IsKind(report.Failures[0].Error, generate.ErrManualEdits),
+		"unexpected error: %v", report.Failures[0].Error)
+	assert.EqualStrings(t, edited, string(stack.ReadFile("file.sh")))
+
+	report = generate.DryRun(s.Config(), vendorDir, nil, generate.Options{})
+	assert.EqualInts(t, 1, len(report.Failures), "report: %s", report.Full())
+	assert.IsTrue(t, errors.IsKind(report.Failures[0].Error, generate.ErrManualEdits))
+
+	report = generate.Do(s.Config(), vendorDir, nil, generate.Options{Force: true})
+	assertEqualReports(t, report, generate.Report{
+		Successes: []generate.Result{
+			{
+				Dir:     project.NewPath("/stack"),
+				Changed: []string{"file.sh"},
+			},
+		},
+	})
+	assert.IsTrue(t, strings.HasSuffix(string(stack.ReadFile("file.sh")), "\necho changed\n"))
+}
+
+func TestGenerateProvenanceRefusesDeletingManualEdits(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{"s:stack"})
+	s.RootEntry().CreateFile("terramate.tm", Terramate(
+		Config(
+			Block("generate",
+				Bool("provenance", true),
+			),
+		),
+	).String())
+
+	stack := s.DirEntry("stack")
+	stack.CreateFile("config.tm", GenerateHCL(
+		Labels("file.hcl"),
+		Content(
+			Str("a", "b"),
+		),
+	).String())
+
+	s.Generate()
+
+	edited := string(stack.ReadFile("file.hcl")) + "manual = true\n"
+	stack.CreateFile("file.hcl", edited)
+	stack.RemoveFile("config.tm")
+
+	vendorDir := project.NewPath("/modules")
+	report := generate.Do(s.ReloadConfig(), vendorDir, nil, generate.Options{})
+	assert.EqualInts(t, 1, len(report.Failures), "report: %s", report.Full())
+	assert.IsTrue(t, errors.IsKind(report.Failures[0].Error, generate.ErrManualEdits),
+		"unexpected error: %v", report.Failures[0].Error)
+	assert.EqualStrings(t, edited, string(stack.ReadFile("file.hcl")))
+
+	report = generate.Do(s.Config(), vendorDir, nil, generate.Options{Force: true})
+	assertEqualReports(t, report, generate.Report{
+		Successes: []generate.Result{
+			{
+				Dir:     project.NewPath("/stack"),
+				Deleted: []string{"file.hcl"},
+			},
+		},
+	})
+}
+
+func TestGenerateProvenanceOverwritesHeaderlessGenerateFile(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{"s:stack"})
+	stack := s.DirEntry("stack")
+	stack.CreateFile("config.tm", GenerateFile(
+		Labels("file.sh"),
+		Expr("content", `"echo hi\n"`),
+	).String())
+
+	s.Generate()
+
+	stack.CreateFile("config.tm", GenerateFile(
+		Labels("file.sh"),
+		Str("comment_prefix", "#"),
+		Expr("content", `"echo hi\n"`),
+	).String())
+
+	report := generate.Do(s.ReloadConfig(), project.NewPath("/modules"), nil, generate.Options{})
+	assertEqualReports(t, report, generate.Report{
+		Successes: []generate.Result{
+			{
+				Dir:     project.NewPath("/stack"),
+				Changed: []string{"file.sh"},
+			},
+		},
+	})
+	assert.IsTrue(t, strings.HasPrefix(string(stack.ReadFile("file.sh")),
+		"# TERRAMATE: GENERATED AUTOMATICALLY DO NOT EDIT\n"))
+}
+
+// withProvenanceHash adds the hash of the given content to its
+// provenance header.
+func withProvenanceHash(t *testing.T, content string) string {
+	t.Helper()
+
+	const marker = "TERRAMATE: sha256 "
+	pos := strings.Index(content, marker)
+	assert.IsTrue(t, pos != -1, "content has no provenance hash: %s", content)
+	pos += len(marker)
+
+	sum := sha256.Sum256([]byte(content))
+	return content[:pos] + hex.EncodeToString(sum[:]) + content[pos:]
+}
+
+func TestGenerateProvenanceRootContext(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{"s:stack"})
+	s.RootEntry().CreateFile("config.tm", GenerateFile(
+		Labels("/ci/deploy.sh"),
+		Expr("context", "root"),
+		Str("comment_prefix", "#"),
+		Expr("content", `"echo hi\n"`),
+	).String())
+
+	report := generate.Do(s.Config(), project.NewPath("/modules"), nil, generate.Options{})
+	assertEqualReports(t, report, generate.Report{
+		Successes: []generate.Result{
+			{
+				Dir:     project.NewPath("/ci"),
+				Created: []string{"deploy.sh"},
+			},
+		},
+	})
+
+	report = generate.Do(s.Config(), project.NewPath("/modules"), nil, generate.Options{})
+	assertEqualReports(t, report, generate.Report{})
+
+	assert.IsTrue(t, strings.HasPrefix(string(s.DirEntry("ci").ReadFile("deploy.sh")),
+		"# TERRAMATE: GENERATED AUTOMATICALLY DO NOT EDIT\n"+
+			"# TERRAMATE: originated from generate_file block on /config.tm:1\n"))
+}
@@ -22,6 +22,10 @@ type Result struct {
 	Changed []string
 	// Deleted contains filenames of all deleted files inside the stack
 	Deleted []string
+	// Diffs contains the unified diff of each created, changed and deleted
+	// file, indexed by the same filename used on Created, Changed and Deleted.
+	// It is only set for reports created by [DryRun].
+	Diffs map[string]string
 }
 
 // FailureResult represents a failure on code generation.
@@ -181,6 +185,49 @@ func (r Report) Minimal() string {
 	return strings.Join(report, "\n")
 }
 
+// Diff returns the unified diffs of all the files of the report, ordered
+// by directory and filename. It is empty if the report was not created by
+// [DryRun] or if the generated code is up to date.
+func (r Report) Diff() string {
+	var diffs []string
+	addDiffs := func(res Result) {
+		filenames := make([]string, 0, len(res.Diffs))
+		for filename := range res.Diffs {
+			filenames = append(filenames, filename)
+		}
+		sort.Strings(filenames)
+		for _, filename := range filenames {
+			diffs = append(diffs, res.Diffs[filename])
+		}
+	}
+	for _, success := range r.Successes {
+		addDiffs(success)
+	}
+	for _, failure := range r.Failures {
+		addDiffs(failure.Result)
+	}
+	return strings.Join(diffs, "")
+}
+
+// HasChanges returns true if this report includes any created, changed or
+// deleted file.
+func (r Report) HasChanges() bool {
+	hasChanges := func(res Result) bool {
+		return len(res.Created) > 0 || len(res.Changed) > 0 || len(res.Deleted) > 0
+	}
+	for _, success := range r.Successes {
+		if hasChanges(success) {
+			return true
+		}
+	}
+	for _, failure := range r.Failures {
+		if hasChanges(failure.Result) {
+			return true
+		}
+	}
+	return false
+}
+
 func (r Report) empty() bool {
 	return r.BootstrapErr == nil &&
 		len(r.Failures) == 0 &&
@@ -232,6 +279,7 @@ func (r *Report) addDirReport(path project.Path, sr dirReport) {
 				other.Created = append(other.Created, sr.created...)
 				other.Changed = append(other.Changed, sr.changed...)
 				other.Deleted = append(other.Deleted, sr.deleted...)
+				other.Diffs = mergeDiffs(other.Diffs, sr.diffs)
 				r.Successes[i] = other
 				return
 			}
@@ -241,6 +289,7 @@ func (r *Report) addDirReport(path project.Path, sr dirReport) {
 			Created: sr.created,
 			Changed: sr.changed,
 			Deleted: sr.deleted,
+			Diffs:   sr.diffs,
 		})
 		return
 	}
@@ -250,6 +299,7 @@ func (r *Report) addDirReport(path project.Path, sr dirReport) {
 			other.Created = append(other.Created, sr.created...)
 			other.Changed = append(other.Changed, sr.changed...)
 			other.Deleted = append(other.Deleted, sr.deleted...)
+			other.Diffs = mergeDiffs(other.Diffs, sr.diffs)
 			r.Failures[i] = other
 			return
 		}
@@ -260,6 +310,7 @@ func (r *Report) addDirReport(path project.Path, sr dirReport) {
 			Created: sr.created,
 			Changed: sr.changed,
 			Deleted: sr.deleted,
+			Diffs:   sr.diffs,
 		},
 		Error: sr.err,
 	})
@@ -275,6 +326,7 @@ type dirReport struct {
 	created []string
 	changed []string
 	deleted []string
+	diffs   map[string]string
 	err     error
 }
 
@@ -290,6 +342,13 @@ func (s *dirReport) addChangedFile(filename string) {
 	s.changed = append(s.changed, filename)
 }
 
+func (s *dirReport) addDiff(filename string, diff string) {
+	if s.diffs == nil {
+		s.diffs = map[string]string{}
+	}
+	s.diffs[filename] = diff
+}
+
 func (s dirReport) isSuccess() bool {
 	return s.err == nil
 }
@@ -301,6 +360,19 @@ func (s dirReport) empty() bool {
 		s.err == nil
 }
 
+func mergeDiffs(d1, d2 map[string]string) map[string]string {
+	if len(d2) == 0 {
+		return d1
+	}
+	if d1 == nil {
+		d1 = map[string]string{}
+	}
+	for filename, diff := range d2 {
+		d1[filename] = diff
+	}
+	return d1
+}
+
 func joinResults[T any](results ...[]T) []T {
 	var all []T
 	for _, r := range results {
@@ -293,7 +293,7 @@ func TestGenerateVendorRequestEvents(t *testing.T) {
 
 	t.Log("generating code")
 
-	report := generate.Do(s.Config(), vendorDir, events)
+	report := generate.Do(s.Config(), vendorDir, events, generate.Options{})
 
 	t.Logf("generation report: %s", report.Full())
 
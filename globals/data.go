@@ -0,0 +1,117 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package globals
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	hhcl "github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/terramate-io/terramate/hcl"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+
+	ctyyaml "github.com/zclconf/go-cty-yaml"
+)
+
+// dataExpr is an expression which evaluates to the decoded content of the
+// file loaded by a globals.data block.
+type dataExpr struct {
+	data     hcl.GlobalsData
+	hostpath string
+}
+
+func newDataExpr(rootdir string, data hcl.GlobalsData) *dataExpr {
+	return &dataExpr{
+		data:     data,
+		hostpath: filepath.Join(rootdir, filepath.FromSlash(data.File)),
+	}
+}
+
+// Value reads and decodes the data file.
+func (d *dataExpr) Value(_ *hhcl.EvalContext) (cty.Value, hhcl.Diagnostics) {
+	content, err := os.ReadFile(d.hostpath)
+	if err != nil {
+		return cty.NilVal, d.diags("failed to read globals.data file %s: %v", d.data.File, err)
+	}
+
+	var val cty.Value
+	switch d.data.Format {
+	case hcl.GlobalsDataJSON:
+		val, err = decodeJSON(content)
+	case hcl.GlobalsDataYAML:
+		val, err = decodeYAML(content)
+	case hcl.GlobalsDataHCL:
+		var diags hhcl.Diagnostics
+		val, diags = decodeHCL(content, d.data.File)
+		if diags.HasErrors() {
+			return cty.NilVal, diags
+		}
+	default:
+		err = fmt.Errorf("unsupported format %q", d.data.Format)
+	}
+	if err != nil {
+		return cty.NilVal, d.diags("failed to decode globals.data file %s: %v", d.data.File, err)
+	}
+	return val, nil
+}
+
+// Variables returns nil as data files can't reference any variables.
+func (d *dataExpr) Variables() []hhcl.Traversal { return nil }
+
+// Range of the data block.
+func (d *dataExpr) Range() hhcl.Range { return d.data.Range.ToHCLRange() }
+
+// StartRange of the data block.
+func (d *dataExpr) StartRange() hhcl.Range { return d.Range() }
+
+func (d *dataExpr) diags(format string, args ...any) hhcl.Diagnostics {
+	subject := d.Range()
+	return hhcl.Diagnostics{
+		&hhcl.Diagnostic{
+			Severity: hhcl.DiagError,
+			Summary:  fmt.Sprintf(format, args...),
+			Subject:  &subject,
+		},
+	}
+}
+
+func decodeJSON(content []byte) (cty.Value, error) {
+	typ, err := ctyjson.ImpliedType(content)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	return ctyjson.Unmarshal(content, typ)
+}
+
+func decodeYAML(content []byte) (cty.Value, error) {
+	typ, err := ctyyaml.Standard.ImpliedType(content)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	return ctyyaml.Standard.Unmarshal(content, typ)
+}
+
+// decodeHCL decodes a file with literal HCL attributes as an object.
+func decodeHCL(content []byte, filename string) (cty.Value, hhcl.Diagnostics) {
+	file, diags := hclsyntax.ParseConfig(content, filename, hhcl.InitialPos)
+	if diags.HasErrors() {
+		return cty.NilVal, diags
+	}
+	attrs, diags := file.Body.JustAttributes()
+	if diags.HasErrors() {
+		return cty.NilVal, diags
+	}
+	values := map[string]cty.Value{}
+	for name, attr := range attrs {
+		val, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() {
+			return cty.NilVal, diags
+		}
+		values[name] = val
+	}
+	return cty.ObjectVal(values), nil
+}
@@ -0,0 +1,121 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package globals
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/terramate-io/terramate/config"
+	"github.com/terramate-io/terramate/hcl/eval"
+	"github.com/terramate-io/terramate/hcl/info"
+	"github.com/terramate-io/terramate/project"
+	"github.com/zclconf/go-cty/cty"
+)
+
+type (
+	// Explanation describes how the value of a global was defined.
+	Explanation struct {
+		// Path of the global.
+		Path eval.ObjectPath
+
+		// Value is the evaluated value of the global or cty.NilVal if the
+		// global was unset.
+		Value cty.Value
+
+		// Definitions of the global, from the most specific configuration dir
+		// to the least specific one. The first definition is the one in
+		// effect and the others are the overridden ones.
+		Definitions []Definition
+	}
+
+	// Definition is a single definition of a global.
+	Definition struct {
+		// Origin is the range of the definition.
+		Origin info.Range
+
+		// ConfigDir is the directory which loaded the definition.
+		ConfigDir project.Path
+
+		// References are the globals referenced by the definition.
+		References []eval.ObjectPath
+	}
+)
+
+// Name returns the name of the global, eg.: global.a.b
+func (e Explanation) Name() string {
+	return pathName(e.Path)
+}
+
+// ExplainForDir evaluates the globals of cfgdir and explains how each one of
+// them is defined, sorted by the global name.
+func ExplainForDir(root *config.Root, cfgdir project.Path, ctx *eval.Context) ([]Explanation, error) {
+	exprs, report := forDir(root, cfgdir, ctx)
+	if err := report.AsError(); err != nil {
+		return nil, err
+	}
+
+	explanations := map[GlobalPathKey]*Explanation{}
+	sorted := exprs.sort()
+	for i := len(sorted) - 1; i >= 0; i-- {
+		exprset := sorted[i]
+		for accessor, expr := range exprset.expressions {
+			if !accessor.isattr {
+				// implicit expression of empty labeled globals blocks.
+				continue
+			}
+			explanation, ok := explanations[accessor]
+			if !ok {
+				explanation = &Explanation{
+					Path:  accessor.Path(),
+					Value: cty.NilVal,
+				}
+				if val, ok := report.Globals.GetKeyPath(accessor.Path()); ok {
+					explanation.Value = ctyValueOf(val)
+				}
+				explanations[accessor] = explanation
+			}
+			explanation.Definitions = append(explanation.Definitions, Definition{
+				Origin:     expr.Origin,
+				ConfigDir:  exprset.origin,
+				References: globalReferences(expr),
+			})
+		}
+	}
+
+	res := make([]Explanation, 0, len(explanations))
+	for _, explanation := range explanations {
+		res = append(res, *explanation)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name() < res[j].Name()
+	})
+	return res, nil
+}
+
+// globalReferences returns the globals referenced by the expression.
+func globalReferences(expr Expr) []eval.ObjectPath {
+	var refs []eval.ObjectPath
+	seen := map[string]bool{}
+	for _, traversal := range expr.Variables() {
+		if traversal.RootName() != "global" || len(traversal) == 1 {
+			continue
+		}
+		ref := traversalPath(traversal[1:])
+		name := pathName(ref)
+		if len(ref) == 0 || seen[name] {
+			continue
+		}
+		seen[name] = true
+		refs = append(refs, ref)
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		return pathName(refs[i]) < pathName(refs[j])
+	})
+	return refs
+}
+
+func pathName(path []string) string {
+	return "global." + strings.Join(path, ".")
+}
@@ -0,0 +1,166 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package globals_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/globals"
+	"github.com/terramate-io/terramate/hcl/eval"
+	"github.com/terramate-io/terramate/project"
+	errtest "github.com/terramate-io/terramate/test/errors"
+	"github.com/terramate-io/terramate/test/sandbox"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestExplainGlobals(t *testing.T) {
+	t.Parallel()
+
+	type definition struct {
+		origin     string
+		configDir  string
+		references []eval.ObjectPath
+	}
+	type explanation struct {
+		name        string
+		value       cty.Value
+		definitions []definition
+	}
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:stacks/stack`,
+		`f:globals.tm:globals {
+  a = "root"
+  b = 1
+  c = "c"
+}
+`,
+		`f:stacks/globals.tm:globals {
+  a = "parent"
+  c = unset
+}
+`,
+		`f:stacks/stack/globals.tm:globals "obj" {
+  a = global.a
+  b = [global.b, global.obj2["x"]]
+}
+
+globals "obj2" {
+  x = 1
+}
+`,
+	})
+
+	st := s.LoadStack(project.NewPath("/stacks/stack"))
+	got, err := globals.ExplainForStack(s.Config(), st)
+	assert.NoError(t, err)
+
+	want := []explanation{
+		{
+			name:  "global.a",
+			value: cty.StringVal("parent"),
+			definitions: []definition{
+				{origin: "/stacks/globals.tm:2,3-15", configDir: "/stacks"},
+				{origin: "/globals.tm:2,3-13", configDir: "/"},
+			},
+		},
+		{
+			name:  "global.b",
+			value: cty.NumberIntVal(1),
+			definitions: []definition{
+				{origin: "/globals.tm:3,3-8", configDir: "/"},
+			},
+		},
+		{
+			name:  "global.c",
+			value: cty.NilVal,
+			definitions: []definition{
+				{origin: "/stacks/globals.tm:3,3-12", configDir: "/stacks"},
+				{origin: "/globals.tm:4,3-10", configDir: "/"},
+			},
+		},
+		{
+			name:  "global.obj.a",
+			value: cty.StringVal("parent"),
+			definitions: []definition{
+				{
+					origin:     "/stacks/stack/globals.tm:2,3-15",
+					configDir:  "/stacks/stack",
+					references: []eval.ObjectPath{{"a"}},
+				},
+			},
+		},
+		{
+			name:  "global.obj.b",
+			value: cty.TupleVal([]cty.Value{cty.NumberIntVal(1), cty.NumberIntVal(1)}),
+			definitions: []definition{
+				{
+					origin:     "/stacks/stack/globals.tm:3,3-35",
+					configDir:  "/stacks/stack",
+					references: []eval.ObjectPath{{"b"}, {"obj2", "x"}},
+				},
+			},
+		},
+		{
+			name:  "global.obj2.x",
+			value: cty.NumberIntVal(1),
+			definitions: []definition{
+				{origin: "/stacks/stack/globals.tm:7,3-8", configDir: "/stacks/stack"},
+			},
+		},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("got %d explanations, want %d: %v", len(got), len(want), got)
+	}
+
+	for i, wantExplanation := range want {
+		gotExplanation := got[i]
+		if gotExplanation.Name() != wantExplanation.name {
+			t.Fatalf("got global %s, want %s", gotExplanation.Name(), wantExplanation.name)
+		}
+		if wantExplanation.value == cty.NilVal {
+			if gotExplanation.Value != cty.NilVal {
+				t.Errorf("%s: want unset value, got %#v", wantExplanation.name, gotExplanation.Value)
+			}
+		} else if !gotExplanation.Value.RawEquals(wantExplanation.value) {
+			t.Errorf("%s: got value %#v, want %#v", wantExplanation.name,
+				gotExplanation.Value, wantExplanation.value)
+		}
+
+		var gotDefs []definition
+		for _, def := range gotExplanation.Definitions {
+			gotDefs = append(gotDefs, definition{
+				origin:     def.Origin.String(),
+				configDir:  def.ConfigDir.String(),
+				references: def.References,
+			})
+		}
+		if diff := cmp.Diff(wantExplanation.definitions, gotDefs,
+			cmp.AllowUnexported(definition{})); diff != "" {
+			t.Errorf("%s: definitions mismatch (-want +got):\n%s", wantExplanation.name, diff)
+		}
+	}
+}
+
+func TestExplainGlobalsFailsOnEvalErrors(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		`s:stack`,
+		`f:stack/globals.tm:globals {
+  a = global.undefined
+}
+`,
+	})
+
+	st := s.LoadStack(project.NewPath("/stack"))
+	_, err := globals.ExplainForStack(s.Config(), st)
+	errtest.Assert(t, err, errors.E(globals.ErrEval))
+}
@@ -25,6 +25,7 @@ import (
 const (
 	ErrEval      errors.Kind = "global eval"
 	ErrRedefined errors.Kind = "global redefined"
+	ErrCycle     errors.Kind = "global dependency cycle"
 )
 
 type (
@@ -40,6 +41,10 @@ type (
 		// be assigned into.
 		LabelPath eval.ObjectPath
 
+		// Merge is the merge strategy of the globals block defining the
+		// expression. It's empty for the default strategy.
+		Merge string
+
 		hhcl.Expression
 	}
 
@@ -74,6 +79,11 @@ func (a GlobalPathKey) name() string {
 // More specific globals (closer or at the current dir) have precedence over
 // less specific globals (closer or at the root dir).
 func ForDir(root *config.Root, cfgdir project.Path, ctx *eval.Context) EvalReport {
+	_, report := forDir(root, cfgdir, ctx)
+	return report
+}
+
+func forDir(root *config.Root, cfgdir project.Path, ctx *eval.Context) (HierarchicalExprs, EvalReport) {
 	logger := log.With().
 		Str("action", "globals.Load()").
 		Str("root", root.HostDir()).
@@ -82,7 +92,7 @@ func ForDir(root *config.Root, cfgdir project.Path, ctx *eval.Context) EvalRepor
 
 	tree, ok := root.Lookup(cfgdir)
 	if !ok {
-		return NewEvalReport()
+		return HierarchicalExprs{}, NewEvalReport()
 	}
 
 	logger.Trace().Msg("loading expressions")
@@ -91,10 +101,18 @@ func ForDir(root *config.Root, cfgdir project.Path, ctx *eval.Context) EvalRepor
 	if err != nil {
 		report := NewEvalReport()
 		report.BootstrapErr = err
-		return report
+		return nil, report
 	}
 
-	return exprs.Eval(ctx)
+	schemas, err := LoadSchemas(tree)
+	if err != nil {
+		report := NewEvalReport()
+		report.BootstrapErr = err
+		return nil, report
+	}
+
+	exprs.SetOverrides(cfgdir, root.GlobalOverrides())
+	return exprs, exprs.EvalWithSchemas(ctx, schemas)
 }
 
 // ExprSet represents a set of globals loaded from a dir.
@@ -102,6 +120,10 @@ func ForDir(root *config.Root, cfgdir project.Path, ctx *eval.Context) EvalRepor
 type ExprSet struct {
 	origin      project.Path
 	expressions map[GlobalPathKey]Expr
+
+	// replaced are the label paths of the labeled globals blocks which
+	// replace the objects defined in the parent directories.
+	replaced [][]string
 }
 
 // HierarchicalExprs contains all loaded global expressions from multiple
@@ -139,6 +161,25 @@ func LoadExprs(tree *config.Tree) (HierarchicalExprs, error) {
 			)
 		}
 
+		strategies := map[string]string{}
+		for _, raw := range block.RawOrigins {
+			strategy, err := hcl.GlobalsMergeStrategy(raw)
+			if err != nil {
+				return nil, err
+			}
+			if strategy == hcl.GlobalsMergeReplace && len(block.Labels) > 0 {
+				exprs.replaced = append(exprs.replaced, block.Labels)
+			}
+			for name := range raw.Attributes {
+				strategies[name] = strategy
+			}
+			for _, subBlock := range raw.Blocks {
+				if subBlock.Type == "map" && len(subBlock.Labels) > 0 {
+					strategies[subBlock.Labels[0]] = strategy
+				}
+			}
+		}
+
 		attrs := block.Attributes.SortedList()
 		if len(block.Labels) > 0 && len(attrs) == 0 {
 			expr := &hclsyntax.ObjectConsExpr{
@@ -154,6 +195,9 @@ func LoadExprs(tree *config.Tree) (HierarchicalExprs, error) {
 		}
 
 		for _, varsBlock := range block.Blocks {
+			if varsBlock.Type != "map" {
+				continue
+			}
 			varName := varsBlock.Labels[0]
 			if _, ok := block.Attributes[varName]; ok {
 				return HierarchicalExprs{}, errors.E(
@@ -171,6 +215,7 @@ func LoadExprs(tree *config.Tree) (HierarchicalExprs, error) {
 			exprs.expressions[key] = Expr{
 				Origin:     varsBlock.RawOrigins[0].Range,
 				LabelPath:  key.Path(),
+				Merge:      strategies[varName],
 				Expression: expr,
 			}
 		}
@@ -185,11 +230,28 @@ func LoadExprs(tree *config.Tree) (HierarchicalExprs, error) {
 				Origin:     attr.Range,
 				ConfigDir:  tree.Dir(),
 				LabelPath:  key.Path(),
+				Merge:      strategies[attr.Name],
 				Expression: attr.Expr,
 			}
 		}
 	}
 
+	for _, data := range tree.Node.GlobalsData {
+		key := NewGlobalAttrPath(data.LabelPath, data.Name)
+		if _, ok := exprs.expressions[key]; ok {
+			return nil, errors.E(
+				ErrRedefined, data.Range,
+				"data label %s conflicts with global.%s", data.Name, key.name())
+		}
+		exprs.expressions[key] = Expr{
+			Origin:     data.Range,
+			ConfigDir:  tree.Dir(),
+			LabelPath:  key.Path(),
+			Merge:      data.Merge,
+			Expression: newDataExpr(tree.RootDir(), data),
+		}
+	}
+
 	globals := HierarchicalExprs{
 		tree.Dir(): exprs,
 	}
@@ -222,7 +284,7 @@ func (dirExprs HierarchicalExprs) SetOverride(
 ) {
 	exprSet, ok := dirExprs[dir]
 	if !ok {
-		exprSet = newExprSet(origin.Path())
+		exprSet = newExprSet(dir)
 		dirExprs[dir] = exprSet
 	}
 	exprSet.expressions[path] = Expr{
@@ -233,6 +295,21 @@ func (dirExprs HierarchicalExprs) SetOverride(
 	}
 }
 
+// SetOverrides sets all the given global overrides at the specified directory.
+// The overrides are set in order, so later overrides of the same global
+// replace the previous ones.
+func (dirExprs HierarchicalExprs) SetOverrides(dir project.Path, overrides []config.GlobalOverride) {
+	for _, override := range overrides {
+		n := len(override.Path)
+		dirExprs.SetOverride(
+			dir,
+			NewGlobalAttrPath(override.Path[0:n-1], override.Path[n-1]),
+			override.Expr,
+			override.Origin,
+		)
+	}
+}
+
 // Returns a sorted loaded exprs, sorting it by config dir path.
 // The loaded expressions are sorted by the config dir path
 // from smaller (root) to more specific (stack). Eg:
@@ -267,277 +344,293 @@ func (dirExprs ExprSet) sort() []GlobalPathKey {
 		res = append(res, globalPath)
 	}
 
-	sort.SliceStable(res, func(i, j int) bool {
-		return len(res[i].Path()) < len(res[j].Path())
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].numPaths != res[j].numPaths {
+			return res[i].numPaths < res[j].numPaths
+		}
+		// the implicit empty objects of labeled blocks come first.
+		if res[i].isattr != res[j].isattr {
+			return !res[i].isattr
+		}
+		return res[i].name() < res[j].name()
 	})
 
 	return res
 }
 
 // Eval evaluates all global expressions and returns an EvalReport.
+//
+// The globals are evaluated in the dependency order given by the globals they
+// reference, so each expression is evaluated only once.
 func (dirExprs HierarchicalExprs) Eval(ctx *eval.Context) EvalReport {
 	logger := log.With().
 		Str("action", "HierarchicalExprs.Eval()").
 		Logger()
 
-	logger.Trace().Msg("Create new evaluation context.")
-
 	report := NewEvalReport()
 	globals := report.Globals
-	pendingExprsErrs := map[GlobalPathKey]*errors.List{}
 
-	sortedLoadedExprs := dirExprs.sort()
-	pendingExprs := map[GlobalPathKey]Expr{}
+	if !ctx.HasNamespace("global") {
+		ctx.SetNamespace("global", map[string]cty.Value{})
+	}
+
+	logger.Trace().Msg("creating globals dependency graph")
 
-	// Here we will override values, but since
-	// we ordered by config dir the more specific global expressions
-	// will override the parent ones.
-	for _, xp := range sortedLoadedExprs {
-		for k, v := range xp.expressions {
-			pendingExprs[k] = v
+	graph := newGlobalsGraph(dirExprs)
+	namespace := newGlobalsNamespace(globals)
+
+	graph.eval(func(node *globalNode, origin project.Path) error {
+		for _, expr := range node.chain {
+			err := evalGlobal(ctx, globals, namespace, node.key, expr, origin)
+			if err != nil {
+				return err
+			}
 		}
-	}
+		return nil
+	})
 
-	// Here we will sort each set of globals from each dir independently
-	// So the final iteration order is parent first then child, and
-	// for each given config dir it is ordered by the length of the global path.
-	// So we guarantee that independent of the expression accessors length we always
-	// process parent expressions first, then the child ones, until reaching the stack.
-	type globalAccessors struct {
-		origin    project.Path
-		accessors []GlobalPathKey
-	}
-	sortedGlobalAccessors := []globalAccessors{}
-	for _, exprset := range sortedLoadedExprs {
-		// for now we are allowing repeated access paths for different
-		// directories, should not affect results since pendingExprs already
-		// has the correct expression anyway.
-		sortedGlobalAccessors = append(sortedGlobalAccessors, globalAccessors{
-			origin:    exprset.origin,
-			accessors: exprset.sort(),
-		})
+	for _, node := range graph.failed() {
+		report.Errors[node.key] = EvalError{
+			Expr: node.expr,
+			Err:  errors.E(ErrEval, node.err),
+		}
 	}
 
-	if !ctx.HasNamespace("global") {
-		ctx.SetNamespace("global", map[string]cty.Value{})
+	for _, node := range graph.pending() {
+		report.Errors[node.key] = EvalError{
+			Expr: node.expr,
+			Err:  errors.E(ErrEval, graph.pendingErr(node)),
+		}
 	}
 
-	for len(pendingExprs) > 0 {
-		amountEvaluated := 0
-
-		logger.Trace().Msg("evaluating pending expressions")
-
-		for _, sortedGlobals := range sortedGlobalAccessors {
+	if len(globals.Keys) > 0 {
+		ctx.SetNamespace("global", globals.AsValueMap())
+	}
 
-		pendingExpression:
-			for _, accessor := range sortedGlobals.accessors {
-				expr, ok := pendingExprs[accessor]
-				if !ok {
-					// Ignoring already evaluated expression
-					continue
-				}
+	return report
+}
 
-				logger := logger.With().
-					Stringer("origin", sortedGlobals.origin).
-					Strs("global", accessor.Path()).
-					Logger()
-
-				logger.Trace().Msg("checking var access inside expression")
-
-				traversal, diags := hhcl.AbsTraversalForExpr(expr.Expression)
-				if !diags.HasErrors() && len(traversal) == 1 && traversal.RootName() == "unset" {
-					if _, ok := globals.GetKeyPath(accessor.Path()); ok {
-						err := globals.DeleteAt(accessor.Path())
-						if err != nil {
-							panic(errors.E(errors.ErrInternal, err))
-						}
-					}
-
-					amountEvaluated++
-					delete(pendingExprs, accessor)
-					delete(pendingExprsErrs, accessor)
-				}
+// evalGlobal evaluates the expression and sets, or merges, the global
+// accordingly to the expression merge strategy.
+func evalGlobal(
+	ctx *eval.Context,
+	globals *eval.Object,
+	namespace *globalsNamespace,
+	accessor GlobalPathKey,
+	expr Expr,
+	origin project.Path,
+) error {
+	logger := log.With().
+		Str("action", "globals.evalGlobal()").
+		Stringer("origin", origin).
+		Strs("global", accessor.Path()).
+		Logger()
 
-				pendingExprsErrs[accessor] = errors.L()
-				for _, namespace := range expr.Variables() {
-					if !ctx.HasNamespace(namespace.RootName()) {
-						pendingExprsErrs[accessor].Append(errors.E(
-							ErrEval,
-							namespace.SourceRange(),
-							"unknown variable namespace: %s", namespace.RootName(),
-						))
-
-						continue
-					}
-
-					if namespace.RootName() != "global" || len(namespace) == 1 {
-						continue
-					}
-
-					var varPaths []string
-
-					for _, ns := range namespace[1:] {
-						switch attr := ns.(type) {
-						case hhcl.TraverseAttr:
-							varPaths = append(varPaths, attr.Name)
-						case hhcl.TraverseSplat:
-							// ignore
-						case hhcl.TraverseIndex:
-							if !attr.Key.Type().Equals(cty.String) {
-								break
-							}
-
-							varPaths = append(varPaths, attr.Key.AsString())
-						default:
-							panic(errors.E(
-								errors.ErrInternal,
-								"unexpected type of traversal - this is a BUG: %T",
-								attr,
-							))
-						}
-					}
-
-					min := func(a, b int) int {
-						if a < b {
-							return a
-						}
-						return b
-					}
-
-					for accessPath := range pendingExprs {
-						found := true
-						accessPathPaths := accessPath.Path()
-						for i := min(len(accessPathPaths), len(varPaths)) - 1; i >= 0; i-- {
-							if accessPathPaths[i] != varPaths[i] {
-								found = false
-								break
-							}
-						}
-						if found {
-							continue pendingExpression
-						}
-					}
-				}
+	if isUnsetExpr(expr) {
+		if _, ok := globals.GetKeyPath(accessor.Path()); ok {
+			err := globals.DeleteAt(accessor.Path())
+			if err != nil {
+				panic(errors.E(errors.ErrInternal, err))
+			}
+			namespace.invalidate(accessor)
+		}
+		return nil
+	}
 
-				// also checks if any part of the accessor is pending.
-				// Example:
-				//   globals a {
-				//       val = tm_try(global.pending, 1)
-				//   }
-				//
-				//   globals a b {
-				//       c = 1
-				//   }
-				//
-				// The first global block would evaluate before but as it has
-				// pending variables, then we need to postpone the second block
-				// as well.
-				if len(accessor.Path()) > 1 {
-					for size := accessor.numPaths; size >= 1; size-- {
-						base := accessor.path[0 : size-1]
-						attr := accessor.path[size-1]
-						v, isPending := pendingExprs[newGlobalPath(base, attr)]
-
-						if isPending &&
-							// is not this global path
-							!isSameObjectPath(v.LabelPath, accessor.Path()) &&
-							// dependent comes from same or higher level
-							strings.HasPrefix(sortedGlobals.origin.String(), v.ConfigDir.String()) {
-							continue pendingExpression
-						}
-
-					}
-				}
+	errs := errors.L()
+	for _, traversal := range expr.Variables() {
+		if !ctx.HasNamespace(traversal.RootName()) {
+			errs.Append(errors.E(
+				ErrEval,
+				traversal.SourceRange(),
+				"unknown variable namespace: %s", traversal.RootName(),
+			))
+		}
+	}
+	if err := errs.AsError(); err != nil {
+		return err
+	}
 
-				if err := pendingExprsErrs[accessor].AsError(); err != nil {
-					continue
-				}
+	// This catches a schema error that cannot be detected at the parser.
+	// When a nested object is defined either by literal or funcalls,
+	// it can't be detected at the parser.
+	oldValue, hasOldValue := globals.GetKeyPath(accessor.Path())
+	if hasOldValue &&
+		accessor.isattr &&
+		oldValue.Info().DefinedAt.Dir().String() == expr.Origin.Path().Dir().String() {
+		return errors.E(hcl.ErrTerramateSchema, expr.Range(),
+			"global.%s attribute redefined: previously defined at %s",
+			accessor.name(), oldValue.Info().DefinedAt.String())
+	}
 
-				// This catches a schema error that cannot be detected at the parser.
-				// When a nested object is defined either by literal or funcalls,
-				// it can't be detected at the parser.
-				oldValue, hasOldValue := globals.GetKeyPath(accessor.Path())
-				if hasOldValue &&
-					accessor.isattr &&
-					oldValue.Info().DefinedAt.Dir().String() == expr.Origin.Path().Dir().String() {
-					pendingExprsErrs[accessor].Append(
-						errors.E(hcl.ErrTerramateSchema, expr.Range(),
-							"global.%s attribute redefined: previously defined at %s",
-							accessor.name(), oldValue.Info().DefinedAt.String()))
-
-					continue
-				}
+	logger.Trace().Msg("evaluating expression")
 
-				// This is to avoid setting a label defined extension on the child
-				// and later overwriting that with an object definition on the parent
+	ctx.SetNamespace("global", namespace.forExpr(expr))
+	val, err := ctx.Eval(expr)
+	if err != nil {
+		errs.Append(undefinedGlobals(globals, expr))
+		errs.Append(errors.E(
+			ErrEval, err, "global.%s (%t)", accessor.rootname(), accessor.isattr))
+		return errs.AsError()
+	}
 
-				logger.Trace().Msg("evaluating expression")
+	if hasOldValue && oldValue.IsObject() && !accessor.isattr {
+		// all the `attr = expr` inside global blocks become an entry
+		// in the globalExprs map but we have the special case that
+		// an empty globals block with labels must implicitly create
+		// the label defined object...
+		// then as it does not define any expression, an implicit
+		// expression for an empty object block is added to the map.
+		// This special entry sets the key accessor.isattr = false
+		// which means this expression doesn't come from an attribute.
+
+		// this `if` happens for the general case, which we must not
+		// set the fake expression when extending an existing object.
+
+		logger.Trace().Msg("ignoring implicitly created empty global")
+		return nil
+	}
 
-				val, err := ctx.Eval(expr)
-				if err != nil {
-					pendingExprsErrs[accessor].Append(errors.E(
-						ErrEval, err, "global.%s (%t)", accessor.rootname(), accessor.isattr))
-					continue
-				}
+	newVal := eval.NewValue(val, eval.Info{
+		DefinedAt: expr.Origin.Path(),
+		Dir:       origin,
+	})
 
-				if hasOldValue && oldValue.IsObject() && !accessor.isattr {
-					// all the `attr = expr` inside global blocks become an entry
-					// in the globalExprs map but we have the special case that
-					// an empty globals block with labels must implicitly create
-					// the label defined object...
-					// then as it does not define any expression, an implicit
-					// expression for an empty object block is added to the map.
-					// This special entry sets the key accessor.isattr = false
-					// which means this expression doesn't come from an attribute.
-
-					// this `if` happens for the general case, which we must not
-					// set the fake expression when extending an existing object.
-
-					logger.Trace().Msg("ignoring implicitly created empty global")
-				} else {
-					logger.Trace().Msg("setting global")
-
-					err := setGlobal(globals, accessor, eval.NewValue(val,
-						eval.Info{
-							DefinedAt: expr.Origin.Path(),
-							Dir:       sortedGlobals.origin,
-						},
-					))
-
-					if err != nil {
-						pendingExprsErrs[accessor].Append(errors.E(err, "setting global"))
-						continue
-					}
-				}
+	switch expr.Merge {
+	case hcl.GlobalsMergeDeep:
+		logger.Trace().Msg("deep merging global")
+		err = globals.MergeOverwrite(accessor.Path(), newVal)
+	case hcl.GlobalsMergeAppendList:
+		logger.Trace().Msg("appending to global")
+		err = globals.MergeAppend(accessor.Path(), newVal)
+	default:
+		logger.Trace().Msg("setting global")
+		err = setGlobal(globals, accessor, newVal)
+	}
+	if err != nil {
+		return errors.E(err, expr.Range(), "setting global")
+	}
+	namespace.invalidate(accessor)
+	return nil
+}
 
-				amountEvaluated++
+// pendingErr returns the reason why the pending global was not evaluated.
+func (g *globalsGraph) pendingErr(node *globalNode) error {
+	if failed := node.firstBlocker(globalFailed); failed != nil {
+		return errors.E(node.expr.Range(),
+			"%s depends on %s which failed to evaluate",
+			node.name(), failed.name())
+	}
 
-				delete(pendingExprs, accessor)
-				delete(pendingExprsErrs, accessor)
+	if cycle, ok := g.cycle(node); ok {
+		names := make([]string, len(cycle))
+		for i, node := range cycle {
+			names[i] = node.name()
+		}
+		return errors.E(ErrCycle, node.expr.Range(), strings.Join(names, " -> "))
+	}
 
-				logger.Trace().Msg("updating globals eval context with evaluated attribute")
+	blocker := node.firstBlocker(globalPending)
+	return errors.E(node.expr.Range(),
+		"%s depends on %s which can't be evaluated",
+		node.name(), blocker.name())
+}
 
-				ctx.SetNamespace("global", globals.AsValueMap())
+// undefinedGlobals returns an error for each global referenced by the
+// expression which is not defined.
+func undefinedGlobals(globals *eval.Object, expr hhcl.Expression) error {
+	errs := errors.L()
+	for _, traversal := range expr.Variables() {
+		if traversal.RootName() != "global" || len(traversal) == 1 {
+			continue
+		}
+		path := traversalPath(traversal[1:])
+		for i := range path {
+			val, ok := globals.GetKeyPath(path[:i+1])
+			if !ok {
+				errs.Append(errors.E(traversal.SourceRange(),
+					"undefined global.%s", strings.Join(path[:i+1], ".")))
+				break
+			}
+			if !val.IsObject() {
+				break
 			}
 		}
+	}
+	return errs.AsError()
+}
 
-		if amountEvaluated == 0 {
-			break
-		}
+// globalsNamespace builds the global namespace for evaluating expressions
+// with only the root globals referenced by them.
+type globalsNamespace struct {
+	globals *eval.Object
+	cache   map[string]cty.Value
+}
+
+func newGlobalsNamespace(globals *eval.Object) *globalsNamespace {
+	return &globalsNamespace{
+		globals: globals,
+		cache:   map[string]cty.Value{},
 	}
+}
 
-	for accessor, expr := range pendingExprs {
-		err := pendingExprsErrs[accessor].AsError()
-		if err == nil {
-			err = errors.E(expr.Range(), "undefined global.%s", accessor.rootname())
+// forExpr returns the global namespace for the given expression.
+func (ns *globalsNamespace) forExpr(expr hhcl.Expression) map[string]cty.Value {
+	vals := map[string]cty.Value{}
+	for _, traversal := range expr.Variables() {
+		if traversal.RootName() != "global" {
+			continue
+		}
+		name, ok := rootGlobalName(traversal)
+		if !ok {
+			return ns.globals.AsValueMap()
 		}
-		report.Errors[accessor] = EvalError{
-			Expr: expr,
-			Err:  errors.E(ErrEval, err),
+		if val, ok := ns.get(name); ok {
+			vals[name] = val
 		}
 	}
+	return vals
+}
 
-	return report
+func (ns *globalsNamespace) get(name string) (cty.Value, bool) {
+	if val, ok := ns.cache[name]; ok {
+		return val, true
+	}
+	v, ok := ns.globals.Keys[name]
+	if !ok {
+		return cty.NilVal, false
+	}
+	var val cty.Value
+	switch vv := v.(type) {
+	case *eval.Object:
+		val = cty.ObjectVal(vv.AsValueMap())
+	case eval.CtyValue:
+		val = vv.Raw()
+	default:
+		panic(errors.E(errors.ErrInternal, "unexpected global value type %T", v))
+	}
+	ns.cache[name] = val
+	return val, true
+}
+
+func (ns *globalsNamespace) invalidate(accessor GlobalPathKey) {
+	delete(ns.cache, accessor.rootname())
+}
+
+// rootGlobalName returns the name of the root global accessed by the traversal.
+func rootGlobalName(traversal hhcl.Traversal) (string, bool) {
+	if len(traversal) == 1 {
+		return "", false
+	}
+	switch step := traversal[1].(type) {
+	case hhcl.TraverseAttr:
+		return step.Name, true
+	case hhcl.TraverseIndex:
+		if step.Key.Type().Equals(cty.String) {
+			return step.Key.AsString(), true
+		}
+	}
+	return "", false
 }
 
 func (dirExprs HierarchicalExprs) merge(other HierarchicalExprs) {
@@ -553,6 +646,32 @@ func (dirExprs HierarchicalExprs) merge(other HierarchicalExprs) {
 	}
 }
 
+// traversalPath returns the object path accessed by the traversal.
+func traversalPath(traversal hhcl.Traversal) []string {
+	var paths []string
+	for _, ns := range traversal {
+		switch attr := ns.(type) {
+		case hhcl.TraverseAttr:
+			paths = append(paths, attr.Name)
+		case hhcl.TraverseSplat:
+			// ignore
+		case hhcl.TraverseIndex:
+			if !attr.Key.Type().Equals(cty.String) {
+				break
+			}
+
+			paths = append(paths, attr.Key.AsString())
+		default:
+			panic(errors.E(
+				errors.ErrInternal,
+				"unexpected type of traversal - this is a BUG: %T",
+				attr,
+			))
+		}
+	}
+	return paths
+}
+
 func isSameObjectPath(a, b eval.ObjectPath) bool {
 	if len(a) != len(b) {
 		return false
@@ -613,3 +732,13 @@ func NewGlobalAttrPath(basepath []string, name string) GlobalPathKey {
 func NewGlobalExtendPath(path []string) GlobalPathKey {
 	return newGlobalPath(path, "")
 }
+
+// IsPathRelated tells if one global path is equal to or a prefix of the other.
+func IsPathRelated(a, b []string) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
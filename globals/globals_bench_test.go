@@ -0,0 +1,82 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package globals_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/config"
+	"github.com/terramate-io/terramate/globals"
+	"github.com/terramate-io/terramate/project"
+	"github.com/terramate-io/terramate/test/sandbox"
+)
+
+func BenchmarkGlobalsIndependent(b *testing.B) {
+	// benchmarks a lot of globals without references between them.
+	benchmarkGlobals(b, func(i int) string {
+		return fmt.Sprintf("g%d = %d", i, i)
+	})
+}
+
+func BenchmarkGlobalsChain(b *testing.B) {
+	// benchmarks a chain of globals where each global references the one
+	// defined after it, which is the worst case for evaluating the globals
+	// in the order they are defined.
+	benchmarkGlobals(b, func(i int) string {
+		if i == benchGlobalsPerDir*len(benchGlobalsDirs)-1 {
+			return fmt.Sprintf("g%d = %d", i, i)
+		}
+		return fmt.Sprintf("g%d = global.g%d + 1", i, i+1)
+	})
+}
+
+func BenchmarkGlobalsFanIn(b *testing.B) {
+	// benchmarks a lot of globals referencing the same labeled global.
+	benchmarkGlobals(b, func(i int) string {
+		if i == 0 {
+			return "obj = { a = 1, b = 2 }"
+		}
+		return fmt.Sprintf("g%d = global.obj.a + %d", i, i)
+	})
+}
+
+const benchGlobalsPerDir = 500
+
+var benchGlobalsDirs = []string{"/", "/s1", "/s1/s2", "/s1/s2/s3"}
+
+func benchmarkGlobals(b *testing.B, global func(i int) string) {
+	b.StopTimer()
+
+	s := sandbox.New(b)
+	s.BuildTree([]string{"s:s1/s2/s3"})
+
+	i := 0
+	for _, dir := range benchGlobalsDirs {
+		var content strings.Builder
+		content.WriteString("globals {\n")
+		for j := 0; j < benchGlobalsPerDir; j++ {
+			content.WriteString("  " + global(i) + "\n")
+			i++
+		}
+		content.WriteString("}\n")
+		s.DirEntry(strings.TrimPrefix(dir, "/")).CreateFile("globals.tm", content.String())
+	}
+
+	root, err := config.LoadRoot(s.RootDir())
+	assert.NoError(b, err)
+
+	st, err := config.LoadStack(root, project.NewPath(benchGlobalsDirs[len(benchGlobalsDirs)-1]))
+	assert.NoError(b, err)
+
+	b.StartTimer()
+	for n := 0; n < b.N; n++ {
+		report := globals.ForStack(root, st)
+		if err := report.AsError(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
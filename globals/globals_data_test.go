@@ -0,0 +1,249 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package globals_test
+
+import (
+	"testing"
+
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/globals"
+	"github.com/terramate-io/terramate/hcl"
+	"github.com/terramate-io/terramate/test/hclwrite"
+	. "github.com/terramate-io/terramate/test/hclwrite/hclutils"
+)
+
+func TestGlobalsData(t *testing.T) {
+	t.Parallel()
+
+	data := func(builders ...hclwrite.BlockBuilder) *hclwrite.Block {
+		return Block("data", builders...)
+	}
+
+	for _, tc := range []testcase{
+		{
+			name: "json file relative to the config dir",
+			layout: []string{
+				"s:stack",
+				`f:stack/accounts.json:{"dev": "111", "prd": "222"}`,
+			},
+			configs: []hclconfig{
+				{path: "/stack", add: Globals(
+					data(Labels("accounts"), Str("file", "accounts.json")),
+				)},
+			},
+			want: map[string]*hclwrite.Block{
+				"/stack": Globals(
+					EvalExpr(t, "accounts", `{
+						dev = "111"
+						prd = "222"
+					}`),
+				),
+			},
+		},
+		{
+			name: "yaml file with absolute project path",
+			layout: []string{
+				"s:stacks/stack",
+				"f:data/cidrs.yml:vpc: 10.0.0.0/16\nsubnets:\n  - 10.0.1.0/24\n  - 10.0.2.0/24\n",
+			},
+			configs: []hclconfig{
+				{path: "/stacks", add: Globals(
+					data(Labels("cidrs"), Str("file", "/data/cidrs.yml")),
+				)},
+			},
+			want: map[string]*hclwrite.Block{
+				"/stacks/stack": Globals(
+					EvalExpr(t, "cidrs", `{
+						vpc     = "10.0.0.0/16"
+						subnets = ["10.0.1.0/24", "10.0.2.0/24"]
+					}`),
+				),
+			},
+		},
+		{
+			name: "hcl file with explicit format",
+			layout: []string{
+				"s:stack",
+				"f:teams.conf:owner = \"platform\"\nsize = 3\n",
+			},
+			configs: []hclconfig{
+				{path: "/", add: Globals(
+					data(
+						Labels("team"),
+						Str("file", "teams.conf"),
+						Str("format", "hcl"),
+					),
+				)},
+			},
+			want: map[string]*hclwrite.Block{
+				"/stack": Globals(
+					EvalExpr(t, "team", `{
+						owner = "platform"
+						size  = 3
+					}`),
+				),
+			},
+		},
+		{
+			name: "data inside labeled globals referenced by other globals",
+			layout: []string{
+				"s:stack",
+				`f:accounts.json:{"dev": "111"}`,
+			},
+			configs: []hclconfig{
+				{path: "/", add: Globals(
+					Labels("aws"),
+					data(Labels("accounts"), Str("file", "accounts.json")),
+				)},
+				{path: "/stack", add: Globals(
+					Expr("account", "global.aws.accounts.dev"),
+				)},
+			},
+			want: map[string]*hclwrite.Block{
+				"/stack": Globals(
+					EvalExpr(t, "aws", `{
+						accounts = {
+							dev = "111"
+						}
+					}`),
+					Str("account", "111"),
+				),
+			},
+		},
+		{
+			name: "data overridden by child global",
+			layout: []string{
+				"s:stack",
+				`f:accounts.json:{"dev": "111"}`,
+			},
+			configs: []hclconfig{
+				{path: "/", add: Globals(
+					data(Labels("accounts"), Str("file", "accounts.json")),
+				)},
+				{path: "/stack", add: Globals(
+					Str("accounts", "overridden"),
+				)},
+			},
+			want: map[string]*hclwrite.Block{
+				"/stack": Globals(Str("accounts", "overridden")),
+			},
+		},
+		{
+			name:   "missing file fails",
+			layout: []string{"s:stack"},
+			configs: []hclconfig{
+				{path: "/stack", add: Globals(
+					data(Labels("accounts"), Str("file", "accounts.json")),
+				)},
+			},
+			wantErr: errors.E(globals.ErrEval),
+		},
+		{
+			name: "invalid file content fails",
+			layout: []string{
+				"s:stack",
+				`f:stack/accounts.json:{"dev": `,
+			},
+			configs: []hclconfig{
+				{path: "/stack", add: Globals(
+					data(Labels("accounts"), Str("file", "accounts.json")),
+				)},
+			},
+			wantErr: errors.E(globals.ErrEval),
+		},
+		{
+			name: "data conflicting with attribute fails",
+			layout: []string{
+				"s:stack",
+				`f:stack/accounts.json:{}`,
+			},
+			configs: []hclconfig{
+				{path: "/stack", add: Globals(
+					Str("accounts", "a"),
+					data(Labels("accounts"), Str("file", "accounts.json")),
+				)},
+			},
+			wantErr: errors.E(globals.ErrRedefined),
+		},
+		{
+			name:   "file outside project fails",
+			layout: []string{"s:stack"},
+			configs: []hclconfig{
+				{path: "/stack", add: Globals(
+					data(Labels("accounts"), Str("file", "../../accounts.json")),
+				)},
+			},
+			wantErr: errors.E(hcl.ErrTerramateSchema),
+		},
+		{
+			name:   "missing file attribute fails",
+			layout: []string{"s:stack"},
+			configs: []hclconfig{
+				{path: "/stack", add: Globals(
+					data(Labels("accounts")),
+				)},
+			},
+			wantErr: errors.E(hcl.ErrTerramateSchema),
+		},
+		{
+			name:   "unknown format fails",
+			layout: []string{"s:stack"},
+			configs: []hclconfig{
+				{path: "/stack", add: Globals(
+					data(Labels("accounts"), Str("file", "accounts.txt")),
+				)},
+			},
+			wantErr: errors.E(hcl.ErrTerramateSchema),
+		},
+		{
+			name:   "invalid format fails",
+			layout: []string{"s:stack"},
+			configs: []hclconfig{
+				{path: "/stack", add: Globals(
+					data(
+						Labels("accounts"),
+						Str("file", "accounts.json"),
+						Str("format", "toml"),
+					),
+				)},
+			},
+			wantErr: errors.E(hcl.ErrTerramateSchema),
+		},
+		{
+			name:   "non-literal file fails",
+			layout: []string{"s:stack"},
+			configs: []hclconfig{
+				{path: "/stack", add: Globals(
+					data(Labels("accounts"), Expr("file", "global.file")),
+				)},
+			},
+			wantErr: errors.E(hcl.ErrTerramateSchema),
+		},
+		{
+			name:   "data redeclared fails",
+			layout: []string{"s:stack"},
+			configs: []hclconfig{
+				{path: "/stack", filename: "a.tm", add: Globals(
+					data(Labels("accounts"), Str("file", "a.json")),
+				)},
+				{path: "/stack", filename: "b.tm", add: Globals(
+					data(Labels("accounts"), Str("file", "b.json")),
+				)},
+			},
+			wantErr: errors.E(hcl.ErrTerramateSchema),
+		},
+		{
+			name:   "data without label fails",
+			layout: []string{"s:stack"},
+			configs: []hclconfig{
+				{path: "/stack", add: Globals(
+					data(Str("file", "accounts.json")),
+				)},
+			},
+			wantErr: errors.E(hcl.ErrTerramateSchema),
+		},
+	} {
+		testGlobals(t, tc)
+	}
+}
@@ -735,6 +735,187 @@ func TestGlobalsMap(t *testing.T) {
 				),
 			},
 		},
+		{
+			name:   "globals.map with condition",
+			layout: []string{"s:stack"},
+			configs: []hclconfig{
+				{
+					path: "/",
+					add: Globals(
+						Number("min", 2),
+						Map(
+							Labels("var"),
+							Expr("for_each", `[1, 2, 3, 4]`),
+							Expr("condition", "element.new >= global.min"),
+							Expr("key", `tm_tostring(element.new)`),
+							Expr("value", "element.new"),
+						),
+					),
+				},
+			},
+			want: map[string]*hclwrite.Block{
+				"/stack": Globals(
+					Number("min", 2),
+					EvalExpr(t, "var", `{
+						"2" = 2
+						"3" = 3
+						"4" = 4
+					}`),
+				),
+			},
+		},
+		{
+			name:   "globals.map with condition filtering all elements",
+			layout: []string{"s:stack"},
+			configs: []hclconfig{
+				{
+					path: "/",
+					add: Globals(
+						Map(
+							Labels("var"),
+							Expr("for_each", `["a", "b"]`),
+							Expr("condition", "false"),
+							Expr("key", "element.new"),
+							Expr("value", "element.new"),
+						),
+					),
+				},
+			},
+			want: map[string]*hclwrite.Block{
+				"/stack": Globals(
+					EvalExpr(t, "var", `{}`),
+				),
+			},
+		},
+		{
+			name:   "globals.map with non-boolean condition fails",
+			layout: []string{"s:stack"},
+			configs: []hclconfig{
+				{
+					path: "/",
+					add: Globals(
+						Map(
+							Labels("var"),
+							Expr("for_each", `["a", "b"]`),
+							Expr("condition", "element.new"),
+							Expr("key", "element.new"),
+							Expr("value", "element.new"),
+						),
+					),
+				},
+			},
+			wantErr: errors.E(globals.ErrEval),
+		},
+		{
+			name:   "globals.map with group",
+			layout: []string{"s:stack"},
+			configs: []hclconfig{
+				{
+					path: "/",
+					add: Globals(
+						Map(
+							Labels("by_team"),
+							Expr("for_each", `[
+								{ name = "a", team = "infra" },
+								{ name = "b", team = "app" },
+								{ name = "c", team = "infra" },
+							]`),
+							Bool("group", true),
+							Expr("key", "element.new.team"),
+							Expr("value", "element.new.name"),
+						),
+					),
+				},
+			},
+			want: map[string]*hclwrite.Block{
+				"/stack": Globals(
+					EvalExpr(t, "by_team", `{
+						app   = ["b"]
+						infra = ["a", "c"]
+					}`),
+				),
+			},
+		},
+		{
+			name:   "globals.map with group and condition",
+			layout: []string{"s:stack"},
+			configs: []hclconfig{
+				{
+					path: "/",
+					add: Globals(
+						Map(
+							Labels("by_team"),
+							Expr("for_each", `[
+								{ name = "a", team = "infra", enabled = true },
+								{ name = "b", team = "app", enabled = false },
+								{ name = "c", team = "infra", enabled = false },
+								{ name = "d", team = "infra", enabled = true },
+							]`),
+							Bool("group", true),
+							Expr("condition", "element.new.enabled"),
+							Expr("key", "element.new.team"),
+							Value(
+								Expr("name", "element.new.name"),
+								Expr("index", "tm_length(tm_try(element.old, []))"),
+							),
+						),
+					),
+				},
+			},
+			want: map[string]*hclwrite.Block{
+				"/stack": Globals(
+					EvalExpr(t, "by_team", `{
+						infra = [
+							{ index = 0, name = "a" },
+							{ index = 1, name = "d" },
+						]
+					}`),
+				),
+			},
+		},
+		{
+			name:   "globals.map with value block containing only nested map",
+			layout: []string{"s:stack"},
+			configs: []hclconfig{
+				{
+					path: "/",
+					add: Globals(
+						Map(
+							Labels("var"),
+							Expr("for_each", `["a", "b"]`),
+							Expr("iterator", "outer"),
+							Expr("key", "outer.new"),
+							Value(
+								Map(
+									Labels("nested"),
+									Expr("for_each", `[1, 2]`),
+									Expr("condition", `outer.new == "a" || element.new == 2`),
+									Expr("key", `"${outer.new}${element.new}"`),
+									Expr("value", "element.new"),
+								),
+							),
+						),
+					),
+				},
+			},
+			want: map[string]*hclwrite.Block{
+				"/stack": Globals(
+					EvalExpr(t, "var", `{
+						a = {
+							nested = {
+								a1 = 1
+								a2 = 2
+							}
+						}
+						b = {
+							nested = {
+								b2 = 2
+							}
+						}
+					}`),
+				),
+			},
+		},
 	} {
 		testGlobals(t, tc)
 	}
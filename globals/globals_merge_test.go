@@ -0,0 +1,276 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package globals_test
+
+import (
+	"testing"
+
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/hcl"
+	"github.com/terramate-io/terramate/hcl/eval"
+	"github.com/terramate-io/terramate/test/hclwrite"
+	. "github.com/terramate-io/terramate/test/hclwrite/hclutils"
+)
+
+func TestGlobalsMergeStrategies(t *testing.T) {
+	t.Parallel()
+
+	merge := func(strategy string) *hclwrite.Block {
+		return Block("merge", Str("strategy", strategy))
+	}
+
+	for _, tc := range []testcase{
+		{
+			name:   "deep-merge objects with parent",
+			layout: []string{"s:stacks/stack"},
+			configs: []hclconfig{
+				{path: "/", add: Globals(
+					Expr("tags", `{
+						owner = "platform"
+						cost  = { center = "123", team = "infra" }
+					}`),
+				)},
+				{path: "/stacks/stack", add: Globals(
+					merge("deep-merge"),
+					Expr("tags", `{
+						app  = "api"
+						cost = { center = "456" }
+					}`),
+				)},
+			},
+			want: map[string]*hclwrite.Block{
+				"/stacks/stack": Globals(
+					EvalExpr(t, "tags", `{
+						app   = "api"
+						owner = "platform"
+						cost  = { center = "456", team = "infra" }
+					}`),
+				),
+			},
+		},
+		{
+			name:   "deep-merge without parent value",
+			layout: []string{"s:stack"},
+			configs: []hclconfig{
+				{path: "/stack", add: Globals(
+					merge("deep-merge"),
+					Expr("tags", `{ app = "api" }`),
+				)},
+			},
+			want: map[string]*hclwrite.Block{
+				"/stack": Globals(
+					EvalExpr(t, "tags", `{ app = "api" }`),
+				),
+			},
+		},
+		{
+			name:   "deep-merge of labeled block attributes",
+			layout: []string{"s:stack"},
+			configs: []hclconfig{
+				{path: "/", add: Globals(
+					Labels("network"),
+					Expr("vpc", `{ cidr = "10.0.0.0/16", zones = ["a"] }`),
+				)},
+				{path: "/stack", add: Globals(
+					Labels("network"),
+					merge("deep-merge"),
+					Expr("vpc", `{ zones = ["b"] }`),
+				)},
+			},
+			want: map[string]*hclwrite.Block{
+				"/stack": Globals(
+					EvalExpr(t, "network", `{
+						vpc = { cidr = "10.0.0.0/16", zones = ["b"] }
+					}`),
+				),
+			},
+		},
+		{
+			name:   "append-list through multiple dirs",
+			layout: []string{"s:stacks/stack"},
+			configs: []hclconfig{
+				{path: "/", add: Globals(
+					Expr("subnets", `["a"]`),
+				)},
+				{path: "/stacks", add: Globals(
+					merge("append-list"),
+					Expr("subnets", `["b"]`),
+				)},
+				{path: "/stacks/stack", add: Globals(
+					merge("append-list"),
+					Expr("subnets", `tm_concat(["c"], global.extra)`),
+					Expr("extra", `["d"]`),
+				)},
+			},
+			want: map[string]*hclwrite.Block{
+				"/stacks/stack": Globals(
+					EvalExpr(t, "subnets", `["a", "b", "c", "d"]`),
+					EvalExpr(t, "extra", `["d"]`),
+				),
+			},
+		},
+		{
+			name:   "append-list after parent replace",
+			layout: []string{"s:stacks/stack"},
+			configs: []hclconfig{
+				{path: "/", add: Globals(
+					Expr("subnets", `["a"]`),
+				)},
+				{path: "/stacks", add: Globals(
+					merge("replace"),
+					Expr("subnets", `["b"]`),
+				)},
+				{path: "/stacks/stack", add: Globals(
+					merge("append-list"),
+					Expr("subnets", `["c"]`),
+				)},
+			},
+			want: map[string]*hclwrite.Block{
+				"/stacks/stack": Globals(
+					EvalExpr(t, "subnets", `["b", "c"]`),
+				),
+			},
+		},
+		{
+			name:   "merged global referenced by other globals",
+			layout: []string{"s:stack"},
+			configs: []hclconfig{
+				{path: "/", add: Globals(
+					Expr("all_tags", `tm_merge(global.tags, { managed = true })`),
+					Expr("tags", `{ owner = "platform" }`),
+				)},
+				{path: "/stack", add: Globals(
+					merge("deep-merge"),
+					Expr("tags", `{ app = global.app }`),
+					Str("app", "api"),
+				)},
+			},
+			want: map[string]*hclwrite.Block{
+				"/stack": Globals(
+					EvalExpr(t, "all_tags", `{
+						app     = "api"
+						owner   = "platform"
+						managed = true
+					}`),
+					EvalExpr(t, "tags", `{
+						app   = "api"
+						owner = "platform"
+					}`),
+					Str("app", "api"),
+				),
+			},
+		},
+		{
+			name:   "labeled block replaces parent object",
+			layout: []string{"s:stacks/stack"},
+			configs: []hclconfig{
+				{path: "/", add: Globals(
+					Expr("tags", `{ owner = "platform" }`),
+				)},
+				{path: "/stacks", add: Globals(
+					Labels("tags"),
+					Str("team", "infra"),
+					Expr("cost", `{ center = "123" }`),
+				)},
+				{path: "/stacks/stack", add: Globals(
+					Labels("tags"),
+					merge("replace"),
+					Str("app", "api"),
+				)},
+			},
+			want: map[string]*hclwrite.Block{
+				"/stacks/stack": Globals(
+					EvalExpr(t, "tags", `{ app = "api" }`),
+				),
+			},
+		},
+		{
+			name:   "labeled block replace keeps other parent globals",
+			layout: []string{"s:stack"},
+			configs: []hclconfig{
+				{path: "/", add: Globals(
+					Labels("network"),
+					Str("cidr", "10.0.0.0/16"),
+					Expr("zones", `["a"]`),
+				)},
+				{path: "/", add: Globals(
+					Str("region", "us-east-1"),
+				)},
+				{path: "/stack", add: Globals(
+					Labels("network", "zones"),
+					merge("replace"),
+				)},
+			},
+			want: map[string]*hclwrite.Block{
+				"/stack": Globals(
+					Str("region", "us-east-1"),
+					EvalExpr(t, "network", `{
+						cidr  = "10.0.0.0/16"
+						zones = {}
+					}`),
+				),
+			},
+		},
+		{
+			name:   "append-list to non-list fails",
+			layout: []string{"s:stack"},
+			configs: []hclconfig{
+				{path: "/", add: Globals(
+					Str("subnets", "a"),
+				)},
+				{path: "/stack", add: Globals(
+					merge("append-list"),
+					Expr("subnets", `["b"]`),
+				)},
+			},
+			wantErr: errors.E(eval.ErrCannotAppend),
+		},
+		{
+			name:   "invalid strategy fails",
+			layout: []string{"s:stack"},
+			configs: []hclconfig{
+				{path: "/stack", add: Globals(
+					merge("shallow"),
+					Str("a", "b"),
+				)},
+			},
+			wantErr: errors.E(hcl.ErrTerramateSchema),
+		},
+		{
+			name:   "missing strategy fails",
+			layout: []string{"s:stack"},
+			configs: []hclconfig{
+				{path: "/stack", add: Globals(
+					Block("merge"),
+				)},
+			},
+			wantErr: errors.E(hcl.ErrTerramateSchema),
+		},
+		{
+			name:   "unrecognized merge attribute fails",
+			layout: []string{"s:stack"},
+			configs: []hclconfig{
+				{path: "/stack", add: Globals(
+					Block("merge",
+						Str("strategy", "replace"),
+						Str("mode", "all"),
+					),
+				)},
+			},
+			wantErr: errors.E(hcl.ErrTerramateSchema),
+		},
+		{
+			name:   "merge block with labels fails",
+			layout: []string{"s:stack"},
+			configs: []hclconfig{
+				{path: "/stack", add: Globals(
+					Block("merge", Labels("tags"), Str("strategy", "replace")),
+				)},
+			},
+			wantErr: errors.E(hcl.ErrTerramateSchema),
+		},
+	} {
+		testGlobals(t, tc)
+	}
+}
@@ -0,0 +1,399 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package globals
+
+import (
+	"container/heap"
+	"sort"
+	"strings"
+
+	hhcl "github.com/hashicorp/hcl/v2"
+	"github.com/terramate-io/terramate/hcl"
+	"github.com/terramate-io/terramate/project"
+)
+
+type (
+	// globalsGraph is the dependency graph of the global expressions.
+	globalsGraph struct {
+		// nodes are sorted by their first evaluation position.
+		nodes []*globalNode
+		byKey map[GlobalPathKey]*globalNode
+	}
+
+	// globalNode is a global expression of the dependency graph.
+	globalNode struct {
+		key  GlobalPathKey
+		expr Expr
+
+		// chain are the expressions evaluated for the global, from the
+		// last one replacing the parent values to the most specific one,
+		// which are merged in order accordingly to their merge strategy.
+		chain []Expr
+
+		// positions are the places in the evaluation order where the global
+		// can be evaluated, one for each config dir defining it, from the
+		// root dir to the most specific one. The origins are their dirs.
+		positions []int
+		origins   []project.Path
+
+		// deps are the globals related to the globals referenced by the
+		// expression and parents are the attributes defining the objects
+		// which contain this global.
+		deps    []*globalNode
+		parents []*globalNode
+
+		depDependents    []*globalNode
+		parentDependents []*globalNode
+		pendingDeps      int
+
+		state globalState
+		err   error
+	}
+
+	globalState int
+
+	// globalsTrie indexes the global nodes by their paths.
+	globalsTrie struct {
+		nodes    []*globalNode
+		children map[string]*globalsTrie
+	}
+
+	scheduledGlobal struct {
+		pos    int
+		origin project.Path
+		node   *globalNode
+	}
+
+	scheduledGlobals []scheduledGlobal
+)
+
+const (
+	globalPending globalState = iota
+	globalDone
+	globalFailed
+)
+
+// newGlobalsGraph creates the dependency graph of the expressions.
+//
+// The evaluation order of independent globals is the same as the order of the
+// expressions: from the root dir to the most specific one and, for each dir,
+// from the shortest to the longest global path. When the same global is
+// defined in multiple dirs, the most specific expression is used but it can
+// be evaluated at the position of any of the dirs defining it.
+func newGlobalsGraph(dirExprs HierarchicalExprs) *globalsGraph {
+	g := &globalsGraph{
+		byKey: map[GlobalPathKey]*globalNode{},
+	}
+	trie := &globalsTrie{}
+
+	type entry struct {
+		key     GlobalPathKey
+		expr    Expr
+		origin  project.Path
+		dropped bool
+	}
+
+	var entries []*entry
+	for _, exprset := range dirExprs.sort() {
+		// WHY: exprsets are sorted from root to the most specific dir, so the
+		// entries are only replaced by the ones from child dirs.
+		for _, labels := range exprset.replaced {
+			for _, e := range entries {
+				if isPathPrefix(labels, e.key.Path()) {
+					e.dropped = true
+				}
+			}
+		}
+		for _, key := range exprset.sort() {
+			entries = append(entries, &entry{
+				key:    key,
+				expr:   exprset.expressions[key],
+				origin: exprset.origin,
+			})
+		}
+	}
+
+	exprs := map[*globalNode][]Expr{}
+	pos := 0
+	for _, e := range entries {
+		if e.dropped {
+			continue
+		}
+		node, ok := g.byKey[e.key]
+		if !ok {
+			node = &globalNode{key: e.key}
+			g.byKey[e.key] = node
+			g.nodes = append(g.nodes, node)
+			trie.insert(e.key.Path(), node)
+		}
+		node.expr = e.expr
+		exprs[node] = append(exprs[node], e.expr)
+		node.positions = append(node.positions, pos)
+		node.origins = append(node.origins, e.origin)
+		pos++
+	}
+
+	for _, node := range g.nodes {
+		nodeExprs := exprs[node]
+		start := len(nodeExprs) - 1
+		for start > 0 && isMergeExpr(nodeExprs[start]) {
+			start--
+		}
+		node.chain = nodeExprs[start:]
+
+		if node.isUnset() {
+			continue
+		}
+
+		deps := map[*globalNode]struct{}{}
+		for _, expr := range node.chain {
+			for _, traversal := range expr.Variables() {
+				if traversal.RootName() != "global" || len(traversal) == 1 {
+					continue
+				}
+				trie.visitRelated(traversalPath(traversal[1:]), func(dep *globalNode) {
+					deps[dep] = struct{}{}
+				})
+			}
+		}
+		for dep := range deps {
+			node.deps = append(node.deps, dep)
+			dep.depDependents = append(dep.depDependents, node)
+		}
+		sortNodes(node.deps)
+		node.pendingDeps = len(node.deps)
+
+		if node.key.numPaths <= 1 {
+			continue
+		}
+		for size := node.key.numPaths; size >= 1; size-- {
+			parent, ok := g.byKey[newGlobalPath(node.key.path[0:size-1], node.key.path[size-1])]
+			if ok && !isSameObjectPath(parent.expr.LabelPath, node.key.Path()) {
+				node.parents = append(node.parents, parent)
+				parent.parentDependents = append(parent.parentDependents, node)
+			}
+		}
+	}
+	return g
+}
+
+// eval calls evalNode for each global in the evaluation order, after all
+// the globals it depends on are evaluated. The globals which are never
+// evaluated are left pending.
+func (g *globalsGraph) eval(evalNode func(node *globalNode, origin project.Path) error) {
+	// WHY: globals are evaluated in rounds over the evaluation positions.
+	// A global which becomes ready after its position was visited in the
+	// current round is only evaluated in the next round.
+	current := &scheduledGlobals{}
+	var next scheduledGlobals
+
+	schedule := func(node *globalNode, after int) {
+		if node.state != globalPending || node.pendingDeps > 0 {
+			return
+		}
+		if i, ok := node.readyAfter(after); ok {
+			heap.Push(current, scheduledGlobal{
+				pos:    node.positions[i],
+				origin: node.origins[i],
+				node:   node,
+			})
+			return
+		}
+		if i, ok := node.readyAfter(-1); ok {
+			next = append(next, scheduledGlobal{
+				pos:    node.positions[i],
+				origin: node.origins[i],
+				node:   node,
+			})
+		}
+	}
+
+	for _, node := range g.nodes {
+		schedule(node, -1)
+	}
+
+	for current.Len() > 0 || len(next) > 0 {
+		if current.Len() == 0 {
+			*current = next
+			next = nil
+			heap.Init(current)
+		}
+
+		scheduled := heap.Pop(current).(scheduledGlobal)
+		node := scheduled.node
+		if node.state != globalPending {
+			continue
+		}
+
+		if err := evalNode(node, scheduled.origin); err != nil {
+			node.state = globalFailed
+			node.err = err
+			continue
+		}
+
+		node.state = globalDone
+		for _, dependent := range node.depDependents {
+			dependent.pendingDeps--
+			schedule(dependent, scheduled.pos)
+		}
+		for _, dependent := range node.parentDependents {
+			schedule(dependent, scheduled.pos)
+		}
+	}
+}
+
+// pending returns the globals which were not evaluated.
+func (g *globalsGraph) pending() []*globalNode {
+	var nodes []*globalNode
+	for _, node := range g.nodes {
+		if node.state == globalPending {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// failed returns the globals which failed to evaluate.
+func (g *globalsGraph) failed() []*globalNode {
+	var nodes []*globalNode
+	for _, node := range g.nodes {
+		if node.state == globalFailed {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// cycle returns the dependency cycle which starts at the given pending node,
+// if any. The first node is repeated at the end of the cycle.
+func (g *globalsGraph) cycle(node *globalNode) ([]*globalNode, bool) {
+	visited := map[*globalNode]bool{}
+	path := []*globalNode{}
+	for cur := node; cur != nil; cur = cur.firstBlocker(globalPending) {
+		if visited[cur] {
+			return append(path, cur), cur == node
+		}
+		visited[cur] = true
+		path = append(path, cur)
+	}
+	return nil, false
+}
+
+// readyAfter returns the index of the first position after the given one
+// where the global can be evaluated. A global must be evaluated only after
+// the attributes defining its parent objects in the same or higher config
+// dirs.
+func (node *globalNode) readyAfter(after int) (int, bool) {
+positions:
+	for i, pos := range node.positions {
+		if pos <= after {
+			continue
+		}
+		for _, parent := range node.parents {
+			if parent.state != globalDone &&
+				strings.HasPrefix(node.origins[i].String(), parent.expr.ConfigDir.String()) {
+				continue positions
+			}
+		}
+		return i, true
+	}
+	return 0, false
+}
+
+// firstBlocker returns the first global in the given state which prevents
+// this global from being evaluated.
+func (node *globalNode) firstBlocker(state globalState) *globalNode {
+	blockers := append([]*globalNode{}, node.deps...)
+	blockers = append(blockers, node.parents...)
+	sortNodes(blockers)
+	for _, blocker := range blockers {
+		if blocker.state == state {
+			return blocker
+		}
+	}
+	return nil
+}
+
+func (node *globalNode) isUnset() bool {
+	return isUnsetExpr(node.expr)
+}
+
+func isUnsetExpr(expr Expr) bool {
+	traversal, diags := hhcl.AbsTraversalForExpr(expr.Expression)
+	return !diags.HasErrors() && len(traversal) == 1 && traversal.RootName() == "unset"
+}
+
+// isMergeExpr tells if the expression is merged with the parent value.
+func isMergeExpr(expr Expr) bool {
+	if isUnsetExpr(expr) {
+		return false
+	}
+	return expr.Merge == hcl.GlobalsMergeDeep || expr.Merge == hcl.GlobalsMergeAppendList
+}
+
+func (node *globalNode) name() string {
+	return "global." + node.key.name()
+}
+
+func sortNodes(nodes []*globalNode) {
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].positions[0] < nodes[j].positions[0]
+	})
+}
+
+func (t *globalsTrie) insert(path []string, node *globalNode) {
+	for _, elem := range path {
+		if t.children == nil {
+			t.children = map[string]*globalsTrie{}
+		}
+		child, ok := t.children[elem]
+		if !ok {
+			child = &globalsTrie{}
+			t.children[elem] = child
+		}
+		t = child
+	}
+	t.nodes = append(t.nodes, node)
+}
+
+// visitRelated calls fn for all the nodes whose path is a prefix of the given
+// path or has the given path as prefix.
+func (t *globalsTrie) visitRelated(path []string, fn func(*globalNode)) {
+	for _, elem := range path {
+		for _, node := range t.nodes {
+			fn(node)
+		}
+		child, ok := t.children[elem]
+		if !ok {
+			return
+		}
+		t = child
+	}
+	t.visitAll(fn)
+}
+
+func (t *globalsTrie) visitAll(fn func(*globalNode)) {
+	for _, node := range t.nodes {
+		fn(node)
+	}
+	for _, child := range t.children {
+		child.visitAll(fn)
+	}
+}
+
+func (s scheduledGlobals) Len() int           { return len(s) }
+func (s scheduledGlobals) Less(i, j int) bool { return s[i].pos < s[j].pos }
+func (s scheduledGlobals) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+func (s *scheduledGlobals) Push(x any) {
+	*s = append(*s, x.(scheduledGlobal))
+}
+
+func (s *scheduledGlobals) Pop() any {
+	old := *s
+	n := len(old)
+	x := old[n-1]
+	*s = old[0 : n-1]
+	return x
+}
@@ -0,0 +1,122 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package globals_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/globals"
+	"github.com/terramate-io/terramate/project"
+	errtest "github.com/terramate-io/terramate/test/errors"
+	"github.com/terramate-io/terramate/test/sandbox"
+)
+
+func TestGlobalsDependencyErrors(t *testing.T) {
+	t.Parallel()
+
+	type testcase struct {
+		name    string
+		globals string
+		// want maps the global names to their expected error message.
+		want     map[string]string
+		wantKind errors.Kind
+	}
+
+	for _, tc := range []testcase{
+		{
+			name: "self reference",
+			globals: `globals {
+			  a = global.a
+			}`,
+			want: map[string]string{
+				"a": "global.a -> global.a",
+			},
+			wantKind: globals.ErrCycle,
+		},
+		{
+			name: "cycle",
+			globals: `globals {
+			  a = global.b
+			  b = global.c
+			  c = global.a
+			  d = global.a
+			}`,
+			want: map[string]string{
+				"a": "global.a -> global.b -> global.c -> global.a",
+				"b": "global.b -> global.c -> global.a -> global.b",
+				"c": "global.c -> global.a -> global.b -> global.c",
+				"d": "global.d depends on global.a which can't be evaluated",
+			},
+			wantKind: globals.ErrCycle,
+		},
+		{
+			name: "cycle through labeled globals",
+			globals: `globals "obj" {
+			  a = global.b
+			}
+			globals {
+			  b = global.obj.a
+			}`,
+			want: map[string]string{
+				"obj.a": "global.obj.a -> global.b -> global.obj.a",
+				"b":     "global.b -> global.obj.a -> global.b",
+			},
+			wantKind: globals.ErrCycle,
+		},
+		{
+			name: "undefined global",
+			globals: `globals {
+			  a = global.undefined
+			  b = global.a
+			}`,
+			want: map[string]string{
+				"a": "undefined global.undefined",
+				"b": "global.b depends on global.a which failed to evaluate",
+			},
+			wantKind: globals.ErrEval,
+		},
+		{
+			name: "undefined nested global",
+			globals: `globals "obj" {
+			  a = 1
+			}
+			globals "other" {
+			  b = global.obj.b
+			}`,
+			want: map[string]string{
+				"other.b": "undefined global.obj.b",
+			},
+			wantKind: globals.ErrEval,
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := sandbox.NoGit(t)
+			s.BuildTree([]string{
+				"s:stack",
+				"f:globals.tm:" + tc.globals,
+			})
+
+			report := globals.ForStack(s.Config(), s.LoadStack(project.NewPath("/stack")))
+			errtest.AssertIsKind(t, report.AsError(), tc.wantKind)
+
+			assert.EqualInts(t, len(tc.want), len(report.Errors), "unexpected errors: %v", report.Errors)
+			for key, evalErr := range report.Errors {
+				name := strings.Join(key.Path(), ".")
+				want, ok := tc.want[name]
+				if !ok {
+					t.Fatalf("unexpected error for global.%s: %v", name, evalErr.Err)
+				}
+				if !strings.Contains(evalErr.Err.Error(), want) {
+					t.Errorf("global.%s error %q does not contain %q", name, evalErr.Err, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package globals_test
+
+import (
+	"testing"
+
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/config"
+	"github.com/terramate-io/terramate/globals"
+	"github.com/terramate-io/terramate/hcl/ast"
+	"github.com/terramate-io/terramate/project"
+	"github.com/terramate-io/terramate/test/sandbox"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestGlobalsOverrides(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	s.BuildTree([]string{
+		"s:stack",
+		`f:globals.tm:globals {
+		  region = "us-east-1"
+		  zone   = "${global.region}a"
+		}
+
+		globals "network" {
+		  cidr = "10.0.0.0/16"
+		  name = "vpc"
+		}`,
+		`f:stack/globals.tm:globals {
+		  region = "sa-east-1"
+		}`,
+	})
+
+	override := func(path []string, expr string) config.GlobalOverride {
+		parsed, err := ast.ParseExpression(expr, "<test>")
+		assert.NoError(t, err)
+		return config.GlobalOverride{Path: path, Expr: parsed}
+	}
+
+	root := s.Config()
+	root.SetGlobalOverrides([]config.GlobalOverride{
+		override([]string{"region"}, `"eu-west-1"`),
+		override([]string{"network", "cidr"}, `"10.1.0.0/16"`),
+		override([]string{"new"}, `tm_upper(global.network.name)`),
+	})
+
+	st := s.LoadStack(project.NewPath("/stack"))
+	report := globals.ForStack(root, st)
+	assert.NoError(t, report.AsError())
+
+	got := report.Globals.AsValueMap()
+	assert.EqualStrings(t, "eu-west-1", got["region"].AsString())
+	assert.EqualStrings(t, "eu-west-1a", got["zone"].AsString())
+	assert.EqualStrings(t, "VPC", got["new"].AsString())
+	assert.IsTrue(t, got["network"].RawEquals(cty.ObjectVal(map[string]cty.Value{
+		"cidr": cty.StringVal("10.1.0.0/16"),
+		"name": cty.StringVal("vpc"),
+	})), "unexpected global.network: %#v", got["network"])
+}
@@ -0,0 +1,255 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package globals
+
+import (
+	"strings"
+
+	hhcl "github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/ext/typeexpr"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/terramate-io/terramate/config"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/hcl"
+	"github.com/terramate-io/terramate/hcl/eval"
+	"github.com/terramate-io/terramate/project"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// ErrSchema indicates that a global doesn't conform to its globals_schema.
+const ErrSchema errors.Kind = "global schema violation"
+
+// Schema is a globals_schema declaration loaded from a configuration dir.
+type Schema struct {
+	hcl.GlobalSchema
+
+	// ConfigDir is the directory which declared the schema.
+	ConfigDir project.Path
+}
+
+// Schemas is a list of globals schema declarations.
+type Schemas []Schema
+
+// LoadSchemas loads all the globals_schema declarations visible from the
+// given tree, from the tree dir up to the root dir.
+// A global can be declared only once in the whole hierarchy.
+func LoadSchemas(tree *config.Tree) (Schemas, error) {
+	var schemas Schemas
+	errs := errors.L()
+	declared := map[string]Schema{}
+	for node := tree; node != nil; node = node.Parent {
+		for _, s := range node.Node.GlobalsSchema {
+			schema := Schema{
+				GlobalSchema: s,
+				ConfigDir:    node.Dir(),
+			}
+			name := strings.Join(s.Path, ".")
+			if other, ok := declared[name]; ok {
+				errs.Append(errors.E(hcl.ErrTerramateSchema, other.Range,
+					"globals_schema for global.%s redeclared: previously declared at %s",
+					name, s.Range.String()))
+				continue
+			}
+			declared[name] = schema
+			schemas = append(schemas, schema)
+		}
+	}
+	if err := errs.AsError(); err != nil {
+		return nil, err
+	}
+	return schemas, nil
+}
+
+// EvalWithSchemas evaluates all global expressions, like [HierarchicalExprs.Eval],
+// and validates the resulting globals against the given schemas.
+func (dirExprs HierarchicalExprs) EvalWithSchemas(ctx *eval.Context, schemas Schemas) EvalReport {
+	dirExprs.SetDefaults(schemas)
+	dirExprs.SetSensitive(schemas)
+
+	report := dirExprs.Eval(ctx)
+	if len(report.Errors) > 0 || len(schemas) == 0 {
+		return report
+	}
+
+	dirExprs.validateSchemas(&report, schemas)
+	ctx.SetNamespace("global", report.Globals.AsValueMap())
+	return report
+}
+
+// SetDefaults sets the schema default values for the globals which are not
+// defined by any expression, so other globals can reference them.
+func (dirExprs HierarchicalExprs) SetDefaults(schemas Schemas) {
+	for _, schema := range schemas {
+		if schema.Default == cty.NilVal {
+			continue
+		}
+		if _, found := dirExprs.lastExprFor(schema.Path); found {
+			continue
+		}
+		exprSet, ok := dirExprs[schema.ConfigDir]
+		if !ok {
+			exprSet = newExprSet(schema.ConfigDir)
+			dirExprs[schema.ConfigDir] = exprSet
+		}
+		defaultVal := schema.Default
+		if schema.Sensitive {
+			defaultVal = defaultVal.Mark(eval.SensitiveMark)
+		}
+		key := schema.key()
+		exprSet.expressions[key] = Expr{
+			Origin:    schema.Range,
+			ConfigDir: schema.ConfigDir,
+			LabelPath: key.Path(),
+			Expression: &hclsyntax.LiteralValueExpr{
+				Val:      defaultVal,
+				SrcRange: schema.Range.ToHCLRange(),
+			},
+		}
+	}
+}
+
+// SetSensitive marks the expressions defining sensitive globals, so the
+// values of the globals referencing them are also sensitive.
+func (dirExprs HierarchicalExprs) SetSensitive(schemas Schemas) {
+	for _, schema := range schemas {
+		if !schema.Sensitive {
+			continue
+		}
+		for _, exprset := range dirExprs {
+			for accessor, expr := range exprset.expressions {
+				if !accessor.isattr || !isPathPrefix(schema.Path, accessor.Path()) {
+					continue
+				}
+				if _, ok := expr.Expression.(*sensitiveExpr); ok {
+					continue
+				}
+				expr.Expression = &sensitiveExpr{Expression: expr.Expression}
+				exprset.expressions[accessor] = expr
+			}
+		}
+	}
+}
+
+// lastExprFor returns the expression which last contributed to the value
+// of the global at the given path, which can be an expression defining the
+// path itself, one of its parent objects or one of its children.
+// The implicit expressions of empty labeled globals blocks are ignored.
+func (dirExprs HierarchicalExprs) lastExprFor(path []string) (Expr, bool) {
+	var (
+		last  Expr
+		found bool
+	)
+	for _, exprset := range dirExprs.sort() {
+		for _, accessor := range exprset.sort() {
+			if accessor.isattr && IsPathRelated(accessor.Path(), path) {
+				last = exprset.expressions[accessor]
+				found = true
+			}
+		}
+	}
+	return last, found
+}
+
+// validateSchemas checks the evaluated globals against the schemas, updating
+// the report with the converted values and any schema violation.
+func (dirExprs HierarchicalExprs) validateSchemas(report *EvalReport, schemas Schemas) {
+	for _, schema := range schemas {
+		key := schema.key()
+		typename := typeexpr.TypeString(schema.Type)
+
+		value, ok := report.Globals.GetKeyPath(schema.Path)
+		if !ok {
+			switch {
+			case schema.Required:
+				report.Errors[key] = EvalError{
+					Expr: Expr{
+						Origin:    schema.Range,
+						ConfigDir: schema.ConfigDir,
+						LabelPath: schema.Path,
+					},
+					Err: errors.E(ErrSchema, schema.Range,
+						"global.%s is required but it is not defined",
+						strings.Join(schema.Path, ".")),
+				}
+			case schema.Default != cty.NilVal:
+				// the default was unset by some expression.
+				err := report.Globals.SetAt(schema.Path, eval.NewValue(schema.Default,
+					eval.Info{
+						DefinedAt: schema.Range.Path(),
+						Dir:       schema.ConfigDir,
+					}))
+				if err != nil {
+					panic(errors.E(errors.ErrInternal, err))
+				}
+			}
+			continue
+		}
+
+		if schema.Type == cty.DynamicPseudoType && !schema.Sensitive {
+			continue
+		}
+
+		converted, err := convert.Convert(ctyValueOf(value), schema.Type)
+		if err != nil {
+			origin := schema.Range
+			expr, found := dirExprs.lastExprFor(schema.Path)
+			if found {
+				origin = expr.Origin
+			}
+			report.Errors[key] = EvalError{
+				Expr: expr,
+				Err: errors.E(ErrSchema, origin,
+					"global.%s must be of type %s: %s",
+					strings.Join(schema.Path, "."), typename, err),
+			}
+			continue
+		}
+
+		if schema.Sensitive {
+			converted = converted.Mark(eval.SensitiveMark)
+		}
+
+		err = report.Globals.SetAt(schema.Path, eval.NewValue(converted, value.Info()))
+		if err != nil {
+			panic(errors.E(errors.ErrInternal, err))
+		}
+	}
+}
+
+func (schema Schema) key() GlobalPathKey {
+	last := len(schema.Path) - 1
+	return NewGlobalAttrPath(schema.Path[:last], schema.Path[last])
+}
+
+func ctyValueOf(value eval.Value) cty.Value {
+	if value.IsObject() {
+		return cty.ObjectVal(value.(*eval.Object).AsValueMap())
+	}
+	return value.(eval.CtyValue).Raw()
+}
+
+// isPathPrefix tells if prefix is equal to or a prefix of path.
+func isPathPrefix(prefix, path []string) bool {
+	return len(prefix) <= len(path) && IsPathRelated(prefix, path)
+}
+
+// sensitiveExpr is an expression whose value is marked as sensitive.
+type sensitiveExpr struct {
+	hhcl.Expression
+}
+
+// Value evaluates the wrapped expression and marks the result as sensitive.
+func (e *sensitiveExpr) Value(ctx *hhcl.EvalContext) (cty.Value, hhcl.Diagnostics) {
+	val, diags := e.Expression.Value(ctx)
+	if diags.HasErrors() {
+		return val, diags
+	}
+	return val.Mark(eval.SensitiveMark), diags
+}
+
+// UnwrapExpression returns the wrapped expression.
+func (e *sensitiveExpr) UnwrapExpression() hhcl.Expression {
+	return e.Expression
+}
@@ -11,11 +11,20 @@ import (
 
 // ForStack loads from the config tree all globals defined for a given stack.
 func ForStack(root *config.Root, stack *config.Stack) EvalReport {
+	return ForDir(root, stack.Dir, newStackEvalCtx(root, stack))
+}
+
+// ExplainForStack explains how each global of the given stack is defined.
+func ExplainForStack(root *config.Root, stack *config.Stack) ([]Explanation, error) {
+	return ExplainForDir(root, stack.Dir, newStackEvalCtx(root, stack))
+}
+
+func newStackEvalCtx(root *config.Root, stack *config.Stack) *eval.Context {
 	ctx := eval.NewContext(
 		stdlib.Functions(stack.HostDir(root)),
 	)
 	runtime := root.Runtime()
 	runtime.Merge(stack.RuntimeValues(root))
 	ctx.SetNamespace("terramate", runtime)
-	return ForDir(root, stack.Dir, ctx)
+	return ctx
 }
@@ -8,9 +8,11 @@ import (
 
 	"github.com/terramate-io/terramate/errors"
 	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
 	"github.com/zclconf/go-cty/cty/function"
 
 	hhcl "github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
 )
 
 // ErrEval indicates a failure during the evaluation process
@@ -84,6 +86,30 @@ func (c *Context) Eval(expr hhcl.Expression) (cty.Value, error) {
 	return val, nil
 }
 
+// EvalTemplate parses src as a template, with the same syntax as the
+// Terraform templatefile function, and renders it given its context.
+// The filename is only used for diagnostics.
+func (c *Context) EvalTemplate(src []byte, filename string) (string, error) {
+	expr, diags := hclsyntax.ParseTemplate(src, filename, hhcl.InitialPos)
+	if diags.HasErrors() {
+		return "", errors.E(ErrEval, diags)
+	}
+	val, err := c.Eval(expr)
+	if err != nil {
+		return "", err
+	}
+	if IsSensitive(val) {
+		return "", errors.E(ErrSensitive, expr.Range(),
+			"template renders sensitive values: use tm_nonsensitive() to explicitly allow them")
+	}
+	strval, err := convert.Convert(val, cty.String)
+	if err != nil || strval.IsNull() {
+		return "", errors.E(ErrEval, expr.Range(),
+			"template must render to a string but got %s", val.Type().FriendlyName())
+	}
+	return strval.AsString(), nil
+}
+
 // PartialEval evaluates only the terramate variable expressions from the list
 // of tokens, leaving all the rest as-is. It returns a modified list of tokens
 // with  no reference to terramate namespaced variables (globals and terramate)
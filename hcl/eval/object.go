@@ -13,6 +13,9 @@ import (
 // ErrCannotExtendObject is the error when an object cannot be extended.
 const ErrCannotExtendObject errors.Kind = "cannot extend object"
 
+// ErrCannotAppend is the error when a value cannot be appended to a list.
+const ErrCannotAppend errors.Kind = "cannot append to list"
+
 type (
 	// Object is an object container for cty.Value values supporting set at
 	// arbitrary accessor paths.
@@ -125,7 +128,7 @@ func (obj *Object) SetFromCtyValues(values map[string]cty.Value, origin Info) *O
 	for k, v := range values {
 		if v.Type().IsObjectType() {
 			subtree := NewObject(origin)
-			subtree.SetFromCtyValues(v.AsValueMap(), origin)
+			subtree.SetFromCtyValues(objectValueMap(v), origin)
 			obj.Set(k, subtree)
 		} else {
 			obj.Set(k, NewCtyValue(v, origin))
@@ -180,7 +183,8 @@ func (obj *Object) MergeFailsIfKeyExists(path ObjectPath, value Value) error {
 	return nil
 }
 
-// MergeOverwrite merges value into obj by overwriting each key.
+// MergeOverwrite deep merges value into obj by overwriting each key.
+// The nested objects are merged recursively and any other value is replaced.
 func (obj *Object) MergeOverwrite(path ObjectPath, value Value) error {
 	target, key, err := computeTargetFrom(obj, path, value.Info())
 	if err != nil {
@@ -188,23 +192,14 @@ func (obj *Object) MergeOverwrite(path ObjectPath, value Value) error {
 	}
 
 	old, ok := target.GetKeyPath([]string{key})
-	if !ok {
+	if !ok || !old.IsObject() || !value.IsObject() {
 		target.Set(key, value)
 		return nil
 	}
 
-	if old.IsObject() != value.IsObject() {
-		target.Set(key, value)
-		return nil
-	}
-	if !old.IsObject() {
-		target.Set(key, value)
-		return nil
-	}
-	valObj := value.(*Object)
 	oldObj := old.(*Object)
-	for k, v := range valObj.Keys {
-		err := oldObj.SetAt([]string{k}, v)
+	for k, v := range value.(*Object).Keys {
+		err := oldObj.MergeOverwrite([]string{k}, v)
 		if err != nil {
 			return err
 		}
@@ -212,7 +207,8 @@ func (obj *Object) MergeOverwrite(path ObjectPath, value Value) error {
 	return nil
 }
 
-// MergeNewKeys merge the keys from value that doesn't exist in obj.
+// MergeNewKeys deep merges the keys from value that doesn't exist in obj.
+// The nested objects are merged recursively.
 func (obj *Object) MergeNewKeys(path ObjectPath, value Value) error {
 	target, key, err := computeTargetFrom(obj, path, value.Info())
 	if err != nil {
@@ -231,20 +227,79 @@ func (obj *Object) MergeNewKeys(path ObjectPath, value Value) error {
 	if !value.IsObject() {
 		return errors.E("cannot overwrite")
 	}
-	valObj := value.(*Object)
+
 	oldObj := old.(*Object)
-	for k, v := range valObj.Keys {
-		_, ok := oldObj.GetKeyPath([]string{k})
-		if !ok {
-			err := oldObj.SetAt([]string{k}, v)
-			if err != nil {
-				return err
-			}
+	for k, v := range value.(*Object).Keys {
+		old, ok := oldObj.GetKeyPath([]string{k})
+		if ok && !(old.IsObject() && v.IsObject()) {
+			continue
+		}
+		err := oldObj.MergeNewKeys([]string{k}, v)
+		if err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
+// MergeAppend appends the elements of the list value to the list in obj.
+// If there's no value at the path, then the value is set.
+func (obj *Object) MergeAppend(path ObjectPath, value Value) error {
+	target, key, err := computeTargetFrom(obj, path, value.Info())
+	if err != nil {
+		return err
+	}
+
+	old, ok := target.GetKeyPath([]string{key})
+	if !ok {
+		target.Set(key, value)
+		return nil
+	}
+
+	oldVal, oldIsCty := old.(CtyValue)
+	newVal, newIsCty := value.(CtyValue)
+	if !oldIsCty || !newIsCty || !isList(oldVal.Raw()) || !isList(newVal.Raw()) {
+		return errors.E(ErrCannotAppend, "cannot append %s to %s at %v",
+			typeName(value), typeName(old), path)
+	}
+
+	oldList, oldMarks := oldVal.Raw().Unmark()
+	newList, newMarks := newVal.Raw().Unmark()
+
+	var elems []cty.Value
+	for _, list := range []cty.Value{oldList, newList} {
+		if list.IsNull() {
+			continue
+		}
+		if !list.IsKnown() {
+			return errors.E(ErrCannotAppend, "cannot append unknown list at %v", path)
+		}
+		elems = append(elems, list.AsValueSlice()...)
+	}
+
+	res := cty.EmptyTupleVal
+	if len(elems) > 0 {
+		res = cty.TupleVal(elems)
+	}
+	target.Set(key, NewCtyValue(res.WithMarks(oldMarks, newMarks), value.Info()))
+	return nil
+}
+
+func isList(val cty.Value) bool {
+	typ := val.Type()
+	return typ.IsListType() || typ.IsTupleType()
+}
+
+func typeName(val Value) string {
+	if val.IsObject() {
+		return "object"
+	}
+	if v, ok := val.(CtyValue); ok {
+		return v.Type().FriendlyName()
+	}
+	return "value"
+}
+
 func computeTargetFrom(obj *Object, path ObjectPath, info Info) (*Object, string, error) {
 	for len(path) > 1 {
 		key := path[0]
@@ -303,8 +358,13 @@ func (obj *Object) AsValueMap() map[string]cty.Value {
 }
 
 // String representation of the object.
+// Sensitive values are redacted.
 func (obj *Object) String() string {
-	return fmt.FormatAttributes(obj.AsValueMap())
+	values := obj.AsValueMap()
+	for k, v := range values {
+		values[k] = Redact(v)
+	}
+	return fmt.FormatAttributes(values)
 }
 
 // NewCtyValue creates a new cty.Value wrapper.
@@ -323,7 +383,7 @@ func NewCtyValue(val cty.Value, origin Info) CtyValue {
 func NewValue(val cty.Value, origin Info) Value {
 	if val.Type().IsObjectType() {
 		obj := NewObject(origin)
-		obj.SetFromCtyValues(val.AsValueMap(), origin)
+		obj.SetFromCtyValues(objectValueMap(val), origin)
 		return obj
 	}
 	return NewCtyValue(val, origin)
@@ -336,7 +396,22 @@ func (v CtyValue) Info() Info { return v.origin }
 func (v CtyValue) IsObject() bool { return false }
 
 // Raw returns the original cty.Value value (unmarked).
+// The sensitive mark is kept, so sensitive values stay sensitive.
 func (v CtyValue) Raw() cty.Value {
-	val, _ := v.Value.Unmark()
+	val, marks := v.Value.Unmark()
+	if _, ok := marks[SensitiveMark]; ok {
+		val = val.Mark(SensitiveMark)
+	}
 	return val
 }
+
+// objectValueMap returns the attributes of the object value. The marks of a
+// marked object are moved to each one of its attributes.
+func objectValueMap(val cty.Value) map[string]cty.Value {
+	val, marks := val.Unmark()
+	values := val.AsValueMap()
+	for k, v := range values {
+		values[k] = v.WithMarks(marks)
+	}
+	return values
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/terramate-io/terramate/errors"
 	"github.com/terramate-io/terramate/hcl/eval"
 	"github.com/terramate-io/terramate/project"
+	"github.com/zclconf/go-cty/cty"
 
 	errtest "github.com/terramate-io/terramate/test/errors"
 )
@@ -158,3 +159,168 @@ func TestCtyObjectSetAt(t *testing.T) {
 		})
 	}
 }
+
+func TestCtyObjectMerge(t *testing.T) {
+	type testcase struct {
+		name    string
+		obj     map[string]cty.Value
+		merge   func(obj *eval.Object, path eval.ObjectPath, val eval.Value) error
+		path    eval.ObjectPath
+		val     cty.Value
+		want    map[string]cty.Value
+		wantErr error
+	}
+
+	origin := eval.Info{
+		DefinedAt: project.NewPath("/file.tm"),
+		Dir:       project.NewPath("/"),
+	}
+
+	overwrite := (*eval.Object).MergeOverwrite
+	newKeys := (*eval.Object).MergeNewKeys
+	appendList := (*eval.Object).MergeAppend
+
+	strs := func(vals ...string) cty.Value {
+		var elems []cty.Value
+		for _, v := range vals {
+			elems = append(elems, cty.StringVal(v))
+		}
+		return cty.TupleVal(elems)
+	}
+
+	for _, tc := range []testcase{
+		{
+			name:  "overwrite sets missing key",
+			obj:   map[string]cty.Value{},
+			merge: overwrite,
+			path:  eval.ObjectPath{"a", "b"},
+			val:   cty.StringVal("v"),
+			want: map[string]cty.Value{
+				"a": cty.ObjectVal(map[string]cty.Value{"b": cty.StringVal("v")}),
+			},
+		},
+		{
+			name: "overwrite deep merges objects",
+			obj: map[string]cty.Value{
+				"a": cty.ObjectVal(map[string]cty.Value{
+					"b": cty.ObjectVal(map[string]cty.Value{
+						"c": cty.StringVal("old"),
+						"d": cty.StringVal("kept"),
+					}),
+				}),
+			},
+			merge: overwrite,
+			path:  eval.ObjectPath{"a"},
+			val: cty.ObjectVal(map[string]cty.Value{
+				"b": cty.ObjectVal(map[string]cty.Value{
+					"c": cty.StringVal("new"),
+				}),
+			}),
+			want: map[string]cty.Value{
+				"a": cty.ObjectVal(map[string]cty.Value{
+					"b": cty.ObjectVal(map[string]cty.Value{
+						"c": cty.StringVal("new"),
+						"d": cty.StringVal("kept"),
+					}),
+				}),
+			},
+		},
+		{
+			name: "overwrite replaces non-objects",
+			obj: map[string]cty.Value{
+				"a": cty.ObjectVal(map[string]cty.Value{
+					"b": cty.StringVal("old"),
+				}),
+			},
+			merge: overwrite,
+			path:  eval.ObjectPath{"a"},
+			val:   strs("new"),
+			want: map[string]cty.Value{
+				"a": strs("new"),
+			},
+		},
+		{
+			name: "new keys deep merges only missing keys",
+			obj: map[string]cty.Value{
+				"a": cty.ObjectVal(map[string]cty.Value{
+					"b": cty.ObjectVal(map[string]cty.Value{
+						"c": cty.StringVal("old"),
+					}),
+				}),
+			},
+			merge: newKeys,
+			path:  eval.ObjectPath{"a"},
+			val: cty.ObjectVal(map[string]cty.Value{
+				"b": cty.ObjectVal(map[string]cty.Value{
+					"c": cty.StringVal("new"),
+					"d": cty.StringVal("added"),
+				}),
+			}),
+			want: map[string]cty.Value{
+				"a": cty.ObjectVal(map[string]cty.Value{
+					"b": cty.ObjectVal(map[string]cty.Value{
+						"c": cty.StringVal("old"),
+						"d": cty.StringVal("added"),
+					}),
+				}),
+			},
+		},
+		{
+			name: "new keys fails to merge object and value",
+			obj: map[string]cty.Value{
+				"a": cty.StringVal("old"),
+			},
+			merge:   newKeys,
+			path:    eval.ObjectPath{"a"},
+			val:     cty.ObjectVal(map[string]cty.Value{}),
+			wantErr: errors.E("failed to merge object and value"),
+		},
+		{
+			name: "append lists",
+			obj: map[string]cty.Value{
+				"a": cty.ListVal([]cty.Value{cty.StringVal("x")}),
+			},
+			merge: appendList,
+			path:  eval.ObjectPath{"a"},
+			val:   strs("y", "z"),
+			want: map[string]cty.Value{
+				"a": strs("x", "y", "z"),
+			},
+		},
+		{
+			name:  "append sets missing key",
+			obj:   map[string]cty.Value{},
+			merge: appendList,
+			path:  eval.ObjectPath{"a"},
+			val:   strs("x"),
+			want: map[string]cty.Value{
+				"a": strs("x"),
+			},
+		},
+		{
+			name: "append to non-list fails",
+			obj: map[string]cty.Value{
+				"a": cty.StringVal("x"),
+			},
+			merge:   appendList,
+			path:    eval.ObjectPath{"a"},
+			val:     strs("y"),
+			wantErr: errors.E(eval.ErrCannotAppend),
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			obj := eval.NewObject(origin).SetFromCtyValues(tc.obj, origin)
+			err := tc.merge(obj, tc.path, eval.NewValue(tc.val, origin))
+			errtest.Assert(t, err, tc.wantErr)
+			if err != nil {
+				return
+			}
+			got := cty.ObjectVal(obj.AsValueMap())
+			want := cty.ObjectVal(tc.want)
+			if !got.RawEquals(want) {
+				t.Fatalf("got %#v but want %#v", got, want)
+			}
+		})
+	}
+}
@@ -81,8 +81,8 @@ func (c *Context) partialEvalTmplWrap(wrap *hclsyntax.TemplateWrapExpr) (hhcl.Ex
 	}
 	if v, ok := newwrap.(*hclsyntax.LiteralValueExpr); ok {
 		// TODO(fix)
-		if v.Val.Type() == cty.String && strings.Contains(v.Val.AsString(), "${") {
-			panic(v.Val.AsString())
+		if str, _ := v.Val.Unmark(); str.Type() == cty.String && strings.Contains(str.AsString(), "${") {
+			panic(str.AsString())
 		}
 		return v, nil
 	}
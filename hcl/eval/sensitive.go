@@ -0,0 +1,104 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package eval
+
+import (
+	hhcl "github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// ErrSensitive indicates that a sensitive value was used where it's not allowed.
+const ErrSensitive errors.Kind = "sensitive value not allowed"
+
+// SensitiveMark is the mark of sensitive values. It's the same mark used by
+// the tm_sensitive() and tm_nonsensitive() functions.
+const SensitiveMark = "sensitive"
+
+// RedactedValue is the placeholder which replaces sensitive values on outputs.
+const RedactedValue = "(sensitive)"
+
+// IsSensitive tells if the value or any value nested in it is sensitive.
+func IsSensitive(val cty.Value) bool {
+	if val.HasMark(SensitiveMark) {
+		return true
+	}
+	val, _ = val.Unmark()
+	if !val.IsKnown() || val.IsNull() || !val.CanIterateElements() {
+		return false
+	}
+	for it := val.ElementIterator(); it.Next(); {
+		_, elem := it.Element()
+		if IsSensitive(elem) {
+			return true
+		}
+	}
+	return false
+}
+
+// Redact returns a copy of the value with all sensitive values replaced by
+// the RedactedValue placeholder. The returned value has no marks.
+func Redact(val cty.Value) cty.Value {
+	if val.HasMark(SensitiveMark) {
+		return cty.StringVal(RedactedValue)
+	}
+	val, _ = val.Unmark()
+	if !val.IsKnown() || val.IsNull() || !IsSensitive(val) {
+		val, _ = val.UnmarkDeep()
+		return val
+	}
+
+	typ := val.Type()
+	switch {
+	case typ.IsObjectType() || typ.IsMapType():
+		values := map[string]cty.Value{}
+		for k, v := range val.AsValueMap() {
+			values[k] = Redact(v)
+		}
+		return cty.ObjectVal(values)
+	case typ.IsListType() || typ.IsSetType() || typ.IsTupleType():
+		values := []cty.Value{}
+		for _, v := range val.AsValueSlice() {
+			values = append(values, Redact(v))
+		}
+		return cty.TupleVal(values)
+	default:
+		panic(errors.E(errors.ErrInternal, "unexpected sensitive value of type %s",
+			typ.FriendlyName()))
+	}
+}
+
+// CheckNotSensitive returns an error if any of the values in the expression
+// is sensitive. The expression is usually the result of a partial evaluation.
+func CheckNotSensitive(expr hhcl.Expression) error {
+	node, ok := expr.(hclsyntax.Node)
+	if !ok {
+		return nil
+	}
+	var err error
+	_ = hclsyntax.VisitAll(node, func(node hclsyntax.Node) hhcl.Diagnostics {
+		if lit, ok := node.(*hclsyntax.LiteralValueExpr); ok && err == nil && IsSensitive(lit.Val) {
+			err = errors.E(ErrSensitive, lit.Range(),
+				"use tm_nonsensitive() to explicitly allow it")
+		}
+		return nil
+	})
+	return err
+}
+
+// RedactExpr replaces all sensitive values in the expression by the
+// RedactedValue placeholder. The expression is changed in place.
+func RedactExpr(expr hhcl.Expression) {
+	node, ok := expr.(hclsyntax.Node)
+	if !ok {
+		return
+	}
+	_ = hclsyntax.VisitAll(node, func(node hclsyntax.Node) hhcl.Diagnostics {
+		if lit, ok := node.(*hclsyntax.LiteralValueExpr); ok && IsSensitive(lit.Val) {
+			lit.Val = Redact(lit.Val)
+		}
+		return nil
+	})
+}
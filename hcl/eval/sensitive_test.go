@@ -0,0 +1,187 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package eval_test
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/hcl/ast"
+	"github.com/terramate-io/terramate/hcl/eval"
+	"github.com/terramate-io/terramate/stdlib"
+	errtest "github.com/terramate-io/terramate/test/errors"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestRedact(t *testing.T) {
+	t.Parallel()
+
+	type testcase struct {
+		name      string
+		val       cty.Value
+		sensitive bool
+		want      cty.Value
+	}
+
+	secret := cty.StringVal("secret").Mark(eval.SensitiveMark)
+	redacted := cty.StringVal(eval.RedactedValue)
+
+	for _, tc := range []testcase{
+		{
+			name: "non-sensitive value is unchanged",
+			val:  cty.StringVal("value"),
+			want: cty.StringVal("value"),
+		},
+		{
+			name: "other marks are removed",
+			val:  cty.StringVal("value").Mark("other"),
+			want: cty.StringVal("value"),
+		},
+		{
+			name:      "sensitive string",
+			val:       secret,
+			sensitive: true,
+			want:      redacted,
+		},
+		{
+			name:      "sensitive number",
+			val:       cty.NumberIntVal(1).Mark(eval.SensitiveMark),
+			sensitive: true,
+			want:      redacted,
+		},
+		{
+			name:      "sensitive object",
+			val:       cty.ObjectVal(map[string]cty.Value{"a": cty.True}).Mark(eval.SensitiveMark),
+			sensitive: true,
+			want:      redacted,
+		},
+		{
+			name: "object with sensitive attribute",
+			val: cty.ObjectVal(map[string]cty.Value{
+				"user":     cty.StringVal("admin"),
+				"password": secret,
+			}),
+			sensitive: true,
+			want: cty.ObjectVal(map[string]cty.Value{
+				"user":     cty.StringVal("admin"),
+				"password": redacted,
+			}),
+		},
+		{
+			name: "list with sensitive element",
+			val: cty.ListVal([]cty.Value{
+				cty.StringVal("a"),
+				secret,
+			}),
+			sensitive: true,
+			want: cty.TupleVal([]cty.Value{
+				cty.StringVal("a"),
+				redacted,
+			}),
+		},
+		{
+			name: "map with nested sensitive element",
+			val: cty.MapVal(map[string]cty.Value{
+				"a": cty.ObjectVal(map[string]cty.Value{
+					"b": cty.NumberIntVal(1).Mark(eval.SensitiveMark),
+				}),
+			}),
+			sensitive: true,
+			want: cty.ObjectVal(map[string]cty.Value{
+				"a": cty.ObjectVal(map[string]cty.Value{
+					"b": redacted,
+				}),
+			}),
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.IsTrue(t, eval.IsSensitive(tc.val) == tc.sensitive,
+				"IsSensitive(%#v) should be %t", tc.val, tc.sensitive)
+
+			got := eval.Redact(tc.val)
+			assert.IsTrue(t, !got.ContainsMarked(), "redacted value must not be marked")
+			assert.IsTrue(t, got.RawEquals(tc.want), "got %#v but want %#v", got, tc.want)
+		})
+	}
+}
+
+func TestSensitiveExprs(t *testing.T) {
+	t.Parallel()
+
+	type testcase struct {
+		name    string
+		expr    string
+		want    string
+		wantErr error
+	}
+
+	for _, tc := range []testcase{
+		{
+			name: "no sensitive values",
+			expr: `[global.user, "${global.user}-x"]`,
+			want: `["admin","admin-x"]`,
+		},
+		{
+			name:    "sensitive global",
+			expr:    `global.password`,
+			want:    `"(sensitive)"`,
+			wantErr: errors.E(eval.ErrSensitive),
+		},
+		{
+			name: "sensitive function result",
+			expr: `{ a = tm_sensitive(global.user) }`,
+			want: `{
+a = "(sensitive)"
+}`,
+			wantErr: errors.E(eval.ErrSensitive),
+		},
+		{
+			name:    "sensitive value inside template",
+			expr:    `"password: ${global.password}"`,
+			want:    `"password: (sensitive)"`,
+			wantErr: errors.E(eval.ErrSensitive),
+		},
+		{
+			name: "tm_nonsensitive allows the value",
+			expr: `tm_nonsensitive(global.password)`,
+			want: `"secret"`,
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := eval.NewContext(stdlib.Functions(t.TempDir()))
+			ctx.SetNamespace("global", map[string]cty.Value{
+				"user":     cty.StringVal("admin"),
+				"password": cty.StringVal("secret").Mark(eval.SensitiveMark),
+			})
+
+			expr, diags := hclsyntax.ParseExpression([]byte(tc.expr), "test.hcl", hcl.InitialPos)
+			if diags.HasErrors() {
+				t.Fatalf(diags.Error())
+			}
+
+			newexpr, err := ctx.PartialEval(expr)
+			if err != nil {
+				errtest.Assert(t, err, tc.wantErr)
+				return
+			}
+
+			errtest.Assert(t, eval.CheckNotSensitive(newexpr), tc.wantErr)
+
+			eval.RedactExpr(newexpr)
+			assert.NoError(t, eval.CheckNotSensitive(newexpr))
+
+			got := string(ast.TokensForExpression(newexpr).Bytes())
+			assert.EqualStrings(t, tc.want, got)
+		})
+	}
+}
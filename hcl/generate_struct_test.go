@@ -0,0 +1,146 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package hcl_test
+
+import (
+	"testing"
+
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/hcl"
+	"github.com/terramate-io/terramate/test"
+
+	. "github.com/terramate-io/terramate/test/hclutils"
+	. "github.com/terramate-io/terramate/test/hclutils/info"
+)
+
+func TestHCLParserGenerateStructBlocks(t *testing.T) {
+	tcases := []testcase{
+		{
+			name: "generate_json and generate_yaml blocks",
+			input: []cfgfile{
+				{
+					filename: "gen.tm",
+					body: `generate_json "file.json" {
+  content = {
+    a = 1
+  }
+}
+generate_yaml "file.yml" {
+  condition = true
+  lets {
+    name = "test"
+  }
+  assert {
+    assertion = true
+    message   = "ok"
+  }
+  content = {
+    name = let.name
+  }
+}
+`,
+				},
+			},
+			want: want{
+				config: hcl.Config{
+					Generate: hcl.GenerateConfig{
+						JSONs: []hcl.GenStructBlock{
+							{
+								Type:  "generate_json",
+								Label: "file.json",
+								Range: Range(
+									"gen.tm",
+									Start(1, 1, 0),
+									End(5, 2, 57),
+								),
+							},
+						},
+						YAMLs: []hcl.GenStructBlock{
+							{
+								Type:  "generate_yaml",
+								Label: "file.yml",
+								Range: Range(
+									"gen.tm",
+									Start(6, 1, 58),
+									End(18, 2, 231),
+								),
+								Asserts: []hcl.AssertConfig{
+									{
+										Range: Range(
+											"gen.tm",
+											Start(11, 3, 137),
+											End(14, 4, 191),
+										),
+										Assertion: test.NewExpr(t, "true"),
+										Message:   test.NewExpr(t, `"ok"`),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "content is required",
+			input: []cfgfile{
+				{
+					filename: "gen.tm",
+					body: `generate_json "file.json" {
+}
+`,
+				},
+			},
+			want: want{
+				errs: []error{
+					errors.E(hcl.ErrTerramateSchema),
+				},
+			},
+		},
+		{
+			name: "single label is required",
+			input: []cfgfile{
+				{
+					filename: "gen.tm",
+					body: `generate_yaml {
+  content = {}
+}
+`,
+				},
+			},
+			want: want{
+				errs: []error{
+					errors.E(hcl.ErrTerramateSchema,
+						Mkrange("gen.tm", Start(1, 15, 14), End(1, 16, 15))),
+				},
+			},
+		},
+		{
+			name: "unrecognized attribute and block",
+			input: []cfgfile{
+				{
+					filename: "gen.tm",
+					body: `generate_yaml "file.yml" {
+  content = {}
+  context = root
+  content {}
+}
+`,
+				},
+			},
+			want: want{
+				errs: []error{
+					errors.E(hcl.ErrTerramateSchema,
+						Mkrange("gen.tm", Start(3, 3, 44), End(3, 10, 51))),
+					errors.E(hcl.ErrTerramateSchema,
+						Mkrange("gen.tm", Start(4, 3, 61), End(4, 10, 68))),
+				},
+			},
+		},
+	}
+
+	for _, tcase := range tcases {
+		testParser(t, tcase)
+	}
+}
@@ -4,6 +4,8 @@
 package hcl
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"path"
@@ -12,6 +14,7 @@ import (
 	"strings"
 
 	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/ext/typeexpr"
 	"github.com/hashicorp/hcl/v2/hclparse"
 	"github.com/hashicorp/hcl/v2/hclsyntax"
 	"github.com/rs/zerolog/log"
@@ -20,8 +23,10 @@ import (
 	"github.com/terramate-io/terramate/hcl/ast"
 	"github.com/terramate-io/terramate/hcl/eval"
 	"github.com/terramate-io/terramate/hcl/info"
+	"github.com/terramate-io/terramate/project"
 	"github.com/terramate-io/terramate/stdlib"
 	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
 )
 
 // Errors returned during the HCL parsing.
@@ -42,21 +47,39 @@ type Config struct {
 	Terramate *Terramate
 	Stack     *Stack
 	Globals   ast.MergedLabelBlocks
-	Vendor    *VendorConfig
-	Asserts   []AssertConfig
-	Generate  GenerateConfig
+	// GlobalsSchema are the declarations of the globals_schema blocks.
+	GlobalsSchema []GlobalSchema
+	// GlobalsData are the data blocks declared inside globals blocks.
+	GlobalsData []GlobalsData
+	Vendor      *VendorConfig
+	Asserts     []AssertConfig
+	Generate    GenerateConfig
+	Inputs      []Input
+	Outputs     []Output
+
+	// Lets are the lets shared by the generate blocks in the directory and
+	// its child directories. It's nil if no lets block is defined.
+	Lets *ast.MergedBlock
 
 	Imported RawConfig
 
 	// absdir is the absolute path to the configuration directory.
 	absdir string
+
+	// digest of the content of all files the config was parsed from.
+	digest string
+
+	// funcCalls are the names of all functions called on the config files.
+	funcCalls []string
 }
 
 // GenerateConfig includes code generation related configurations, like
-// generate_file and generate_hcl.
+// generate_file, generate_hcl, generate_json and generate_yaml.
 type GenerateConfig struct {
 	Files []GenFileBlock
 	HCLs  []GenHCLBlock
+	JSONs []GenStructBlock
+	YAMLs []GenStructBlock
 }
 
 // AssertConfig represents Terramate assert configuration block.
@@ -67,15 +90,131 @@ type AssertConfig struct {
 	Message   hcl.Expression
 }
 
+// GlobalSchema represents a parsed globals_schema block, which declares the
+// type of a global.
+type GlobalSchema struct {
+	// Range is the range of the entire block definition.
+	Range info.Range
+	// Path of the global (the block labels).
+	Path []string
+	// Type constraint of the global value. It is cty.DynamicPseudoType if
+	// any type is accepted.
+	Type cty.Type
+	// Required tells if the global must be defined.
+	Required bool
+	// Default value of the global when it is not defined.
+	// It is cty.NilVal if there is no default value.
+	Default cty.Value
+	// Description of the global.
+	Description string
+	// Sensitive tells if the global value must be redacted on outputs.
+	Sensitive bool
+}
+
+// Supported formats of the files loaded by globals.data blocks.
+const (
+	GlobalsDataJSON = "json"
+	GlobalsDataYAML = "yaml"
+	GlobalsDataHCL  = "hcl"
+)
+
+// GlobalsData represents a parsed data block inside a globals block, which
+// loads the content of a project file into a global.
+type GlobalsData struct {
+	// Range is the range of the entire data block.
+	Range info.Range
+	// LabelPath is the labels of the parent globals block.
+	LabelPath []string
+	// Name of the global attribute (the data block label).
+	Name string
+	// File is the project path of the loaded file.
+	File string
+	// Format of the file. See GlobalsDataJSON, GlobalsDataYAML and GlobalsDataHCL.
+	Format string
+	// Merge is the merge strategy of the parent globals block.
+	Merge string
+}
+
+// Merge strategies of the globals blocks. They define how the globals of a
+// block are merged with the same globals defined in parent directories.
+const (
+	// GlobalsMergeReplace replaces the parent value. For labeled globals
+	// blocks, the whole object defined by the labels is replaced.
+	GlobalsMergeReplace = "replace"
+	// GlobalsMergeDeep deep merges objects with the parent value.
+	GlobalsMergeDeep = "deep-merge"
+	// GlobalsMergeAppendList appends lists to the parent value.
+	GlobalsMergeAppendList = "append-list"
+)
+
+// Input represents a parsed stack input block.
+type Input struct {
+	// Range is the range of the entire block definition.
+	Range info.Range
+	// Name of the input (the block label).
+	Name string
+	// FromStack is the path of the stack whose outputs are read.
+	FromStack string
+	// Value is the expression which computes the input from the outputs of
+	// the FromStack stack.
+	Value hcl.Expression
+	// Mock is the value used when the outputs of FromStack are not available.
+	// It is cty.NilVal if no mock was provided.
+	Mock cty.Value
+}
+
+// Output represents a parsed stack output block.
+type Output struct {
+	// Range is the range of the entire block definition.
+	Range info.Range
+	// Name of the Terraform output shared by the stack (the block label).
+	Name string
+	// Description of the output.
+	Description string
+}
+
 // RunConfig represents Terramate run configuration.
 type RunConfig struct {
 	// CheckGenCode enables generated code is up-to-date check on run.
 	CheckGenCode bool
 
+	// OutputsCommand is the command used to read the outputs of a stack as
+	// JSON when some other stack declares an input from it.
+	OutputsCommand []string
+
 	// Env contains environment definitions for run.
 	Env *RunEnv
 }
 
+// GenerateRootConfig represents Terramate code generation configuration.
+type GenerateRootConfig struct {
+	// Provenance enables the provenance header on the generated files, which
+	// records the origin generate block and a hash of the file content.
+	Provenance bool
+
+	// PostProcess are the post-processors of the generated files whose
+	// names match their Files patterns.
+	PostProcess []PostProcessConfig
+}
+
+// PostProcessFormatHCL is the built-in formatter of HCL code.
+const PostProcessFormatHCL = "hcl"
+
+// PostProcessConfig represents a post-processor of generated files, which
+// is either a built-in formatter or an external command.
+type PostProcessConfig struct {
+	// Files are the glob patterns matched against the base name of the
+	// generated files. Only used by terramate.config.generate.post_process.
+	Files []string
+
+	// Format is the name of the built-in formatter, if any.
+	Format string
+
+	// Command is the external command, if any. It reads the generated code
+	// from stdin and writes the post-processed code to stdout.
+	Command []string
+}
+
 // RunEnv represents Terramate run environment.
 type RunEnv struct {
 	// Attributes is the collection of attribute definitions within the env block.
@@ -105,8 +244,9 @@ type GitConfig struct {
 
 // RootConfig represents the root config block of a Terramate configuration.
 type RootConfig struct {
-	Git *GitConfig
-	Run *RunConfig
+	Git      *GitConfig
+	Run      *RunConfig
+	Generate *GenerateRootConfig
 }
 
 // ManifestDesc represents a parsed manifest description.
@@ -176,6 +316,13 @@ type Stack struct {
 
 	// Watch is a list of files to be watched for changes.
 	Watch []string
+
+	// Disabled tells if the stack is excluded from orchestration and
+	// change detection.
+	Disabled bool
+
+	// Manual tells if the stack only runs when explicitly selected.
+	Manual bool
 }
 
 // GenHCLBlock represents a parsed generate_hcl block.
@@ -190,6 +337,16 @@ type GenHCLBlock struct {
 	Condition *hclsyntax.Attribute
 	// Content block.
 	Content *hclsyntax.Block
+	// Comments are the comment tokens inside the content block, in the
+	// order they appear on the source file.
+	Comments hclsyntax.Tokens
+	// TemplateFile is the project path of the template file rendered as the
+	// content of the block, if any. It can't be used together with Content.
+	TemplateFile string
+	// Context of the generation (stack by default).
+	Context string
+	// PostProcess are the post-processors of the generated file.
+	PostProcess []PostProcessConfig
 	// Asserts represents all assert blocks
 	Asserts []AssertConfig
 }
@@ -206,8 +363,36 @@ type GenFileBlock struct {
 	Condition *hclsyntax.Attribute
 	// Content attribute of the block
 	Content *hclsyntax.Attribute
+	// TemplateFile is the project path of the template file rendered as the
+	// content of the block, if any. It can't be used together with Content.
+	TemplateFile string
 	// Context of the generation (stack by default).
 	Context string
+	// CommentPrefix is the line comment prefix of the generated file format,
+	// if any. It enables the provenance header on the generated file.
+	CommentPrefix string
+	// PostProcess are the post-processors of the generated file.
+	PostProcess []PostProcessConfig
+	// Asserts represents all assert blocks
+	Asserts []AssertConfig
+}
+
+// GenStructBlock represents a parsed generate_json or generate_yaml block.
+type GenStructBlock struct {
+	// Range is the range of the entire block definition.
+	Range info.Range
+	// Type of the block (generate_json or generate_yaml).
+	Type string
+	// Label of the block
+	Label string
+	// Lets is a block of local variables.
+	Lets *ast.MergedBlock
+	// Condition attribute of the block, if any.
+	Condition *hclsyntax.Attribute
+	// Content attribute of the block, which must evaluate to an object.
+	Content *hclsyntax.Attribute
+	// PostProcess are the post-processors of the generated file.
+	PostProcess []PostProcessConfig
 	// Asserts represents all assert blocks
 	Asserts []AssertConfig
 }
@@ -226,6 +411,9 @@ type Evaluator interface {
 	// SetNamespace adds a new namespace, replacing any with the same name.
 	SetNamespace(name string, values map[string]cty.Value)
 
+	// GetNamespace returns the value of the namespace, if it exists.
+	GetNamespace(name string) (cty.Value, bool)
+
 	// DeleteNamespace deletes a namespace.
 	DeleteNamespace(name string)
 }
@@ -247,6 +435,14 @@ type TerramateParser struct {
 	// parsedFiles stores a map of all parsed files
 	parsedFiles map[string]parsedFile
 
+	// importDigests and importFuncCalls are the content digests and function
+	// calls of the imported files.
+	importDigests   []string
+	importFuncCalls map[string]struct{}
+
+	// importSources are the contents of the imported files.
+	importSources map[string][]byte
+
 	strict bool
 	// if true, calling Parse() or MinimalParse() will fail.
 	parsed bool
@@ -296,14 +492,16 @@ func NewTerramateParser(rootdir string, dir string) (*TerramateParser, error) {
 	}
 
 	return &TerramateParser{
-		rootdir:     rootdir,
-		dir:         dir,
-		files:       map[string][]byte{},
-		hclparser:   hclparse.NewParser(),
-		Config:      NewTopLevelRawConfig(),
-		Imported:    NewTopLevelRawConfig(),
-		parsedFiles: make(map[string]parsedFile),
-		evalctx:     eval.NewContext(stdlib.Functions(dir)),
+		rootdir:         rootdir,
+		dir:             dir,
+		files:           map[string][]byte{},
+		hclparser:       hclparse.NewParser(),
+		Config:          NewTopLevelRawConfig(),
+		Imported:        NewTopLevelRawConfig(),
+		parsedFiles:     make(map[string]parsedFile),
+		importFuncCalls: make(map[string]struct{}),
+		importSources:   make(map[string][]byte),
+		evalctx:         eval.NewContext(stdlib.Functions(dir)),
 	}, nil
 }
 
@@ -555,6 +753,16 @@ func (p *TerramateParser) handleImport(importBlock *ast.Block) error {
 	if err != nil {
 		return err
 	}
+	p.importDigests = append(p.importDigests, importParser.digest())
+	for name := range importParser.functionCalls() {
+		p.importFuncCalls[name] = struct{}{}
+	}
+	for name, data := range importParser.files {
+		p.importSources[name] = data
+	}
+	for name, data := range importParser.importSources {
+		p.importSources[name] = data
+	}
 	errs := errors.L()
 	for _, block := range importParser.Config.UnmergedBlocks {
 		if block.Type == "stack" {
@@ -574,6 +782,64 @@ func (p *TerramateParser) handleImport(importBlock *ast.Block) error {
 	return nil
 }
 
+// digest computes the digest of the content of the parsed files and the
+// imported files.
+func (p *TerramateParser) digest() string {
+	h := sha256.New()
+	for _, filename := range p.sortedFilenames() {
+		data := p.files[filename]
+		fmt.Fprintf(h, "%s:%d:", filename, len(data))
+		h.Write(data)
+	}
+	importDigests := append([]string{}, p.importDigests...)
+	sort.Strings(importDigests)
+	for _, digest := range importDigests {
+		fmt.Fprintf(h, "import:%s:", digest)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// contentComments returns the comment tokens inside the given content block.
+func (p *TerramateParser) contentComments(content *hclsyntax.Block) hclsyntax.Tokens {
+	rng := content.Body.Range()
+	src, ok := p.files[rng.Filename]
+	if !ok {
+		src, ok = p.importSources[rng.Filename]
+	}
+	if !ok {
+		return nil
+	}
+
+	tokens, _ := hclsyntax.LexConfig(src, rng.Filename, hcl.InitialPos)
+	var comments hclsyntax.Tokens
+	for _, token := range tokens {
+		if token.Type == hclsyntax.TokenComment &&
+			token.Range.Start.Byte >= rng.Start.Byte &&
+			token.Range.End.Byte <= rng.End.Byte {
+			comments = append(comments, token)
+		}
+	}
+	return comments
+}
+
+// functionCalls returns the names of all functions called on the parsed
+// files and the imported files.
+func (p *TerramateParser) functionCalls() map[string]struct{} {
+	calls := map[string]struct{}{}
+	for name := range p.importFuncCalls {
+		calls[name] = struct{}{}
+	}
+	for _, body := range p.ParsedBodies() {
+		_ = hclsyntax.VisitAll(body, func(node hclsyntax.Node) hcl.Diagnostics {
+			if call, ok := node.(*hclsyntax.FunctionCallExpr); ok {
+				calls[call.Name] = struct{}{}
+			}
+			return nil
+		})
+	}
+	return calls
+}
+
 func (p *TerramateParser) sortedFilenames() []string {
 	filenames := []string{}
 	for fname := range p.files {
@@ -690,6 +956,26 @@ func (p *TerramateParser) parseStack(stackblock *ast.Block) (*Stack, error) {
 		case "watch":
 			errs.Append(assignSet(attr.Name, &stack.Watch, attrVal))
 
+		case "disabled":
+			if attrVal.Type() != cty.Bool {
+				errs.Append(hclAttrErr(attr,
+					"field stack.disabled must be a bool but given %q",
+					attrVal.Type().FriendlyName()),
+				)
+				continue
+			}
+			stack.Disabled = attrVal.True()
+
+		case "manual":
+			if attrVal.Type() != cty.Bool {
+				errs.Append(hclAttrErr(attr,
+					"field stack.manual must be a bool but given %q",
+					attrVal.Type().FriendlyName()),
+				)
+				continue
+			}
+			stack.Manual = attrVal.True()
+
 		default:
 			errs.Append(errors.E(
 				attr.NameRange, "unrecognized attribute stack.%q", attr.Name,
@@ -732,12 +1018,24 @@ func (c Config) HasRunEnv() bool {
 // AbsDir returns the absolute path of the configuration directory.
 func (c Config) AbsDir() string { return c.absdir }
 
+// Digest returns a digest of the content of all the files, including the
+// imported ones, the configuration was parsed from. Configurations parsed
+// from the same files have the same digest.
+func (c Config) Digest() string { return c.digest }
+
+// FunctionCalls returns the sorted names of all the functions called on the
+// files, including the imported ones, the configuration was parsed from.
+func (c Config) FunctionCalls() []string { return c.funcCalls }
+
 // IsEmpty returns true if the config is empty, false otherwise.
 func (c Config) IsEmpty() bool {
 	return c.Stack == nil && c.Terramate == nil &&
 		c.Vendor == nil && len(c.Asserts) == 0 &&
-		len(c.Globals) == 0 &&
-		len(c.Generate.Files) == 0 && len(c.Generate.HCLs) == 0
+		len(c.Globals) == 0 && len(c.GlobalsSchema) == 0 &&
+		c.Lets == nil &&
+		len(c.Inputs) == 0 && len(c.Outputs) == 0 &&
+		len(c.Generate.Files) == 0 && len(c.Generate.HCLs) == 0 &&
+		len(c.Generate.JSONs) == 0 && len(c.Generate.YAMLs) == 0
 }
 
 // HasGlobals tells if the configuration has any globals defined.
@@ -832,11 +1130,33 @@ func parseGenerateHCLBlock(block *ast.Block) (GenHCLBlock, error) {
 		}
 	}
 
-	if content == nil {
+	templateFile, err := parseTemplateFile("generate_hcl", block)
+	errs.Append(err)
+
+	postProcess, err := parseGenBlockPostProcess("generate_hcl", block)
+	errs.Append(err)
+
+	_, hasTemplateFile := block.Body.Attributes["template_file"]
+	if content == nil && !hasTemplateFile {
 		errs.Append(
 			errors.E(ErrTerramateSchema, `"generate_hcl" block requires a content block`, block.Range))
 	}
 
+	if content != nil && hasTemplateFile {
+		errs.Append(errors.E(ErrTerramateSchema, block.Range,
+			"generate_hcl.template_file can't be used together with a content block"))
+	}
+
+	context := "stack"
+	if contextAttr, ok := block.Body.Attributes["context"]; ok {
+		context = hcl.ExprAsKeyword(contextAttr.Expr)
+		if context != "stack" && context != "root" {
+			errs.Append(errors.E(ErrTerramateSchema, contextAttr.Expr.Range(),
+				"generate_hcl.context supported values are \"stack\" and \"root\""+
+					" but given %q", context))
+		}
+	}
+
 	mergedLets := ast.MergedLabelBlocks{}
 	for labelType, mergedBlock := range letsConfig.MergedLabelBlocks {
 		if labelType.Type == "lets" {
@@ -856,12 +1176,15 @@ func parseGenerateHCLBlock(block *ast.Block) (GenHCLBlock, error) {
 	}
 
 	return GenHCLBlock{
-		Range:     block.Range,
-		Label:     block.Labels[0],
-		Lets:      lets,
-		Asserts:   asserts,
-		Content:   content,
-		Condition: block.Body.Attributes["condition"],
+		Range:        block.Range,
+		Label:        block.Labels[0],
+		Lets:         lets,
+		Asserts:      asserts,
+		Content:      content,
+		TemplateFile: templateFile,
+		PostProcess:  postProcess,
+		Condition:    block.Body.Attributes["condition"],
+		Context:      context,
 	}, nil
 }
 
@@ -907,6 +1230,33 @@ func parseGenerateFileBlock(block *ast.Block) (GenFileBlock, error) {
 		}
 	}
 
+	var commentPrefix string
+	if prefixAttr, ok := block.Body.Attributes["comment_prefix"]; ok {
+		value, diags := prefixAttr.Expr.Value(nil)
+		if diags.HasErrors() {
+			errs.Append(errors.E(ErrTerramateSchema, diags,
+				"generate_file.comment_prefix must be a literal string"))
+		} else if value.Type() != cty.String || value.IsNull() || value.AsString() == "" {
+			errs.Append(errors.E(ErrTerramateSchema, prefixAttr.Expr.Range(),
+				"generate_file.comment_prefix must be a non-empty string"))
+		} else {
+			commentPrefix = value.AsString()
+		}
+	}
+
+	templateFile, err := parseTemplateFile("generate_file", block)
+	errs.Append(err)
+
+	postProcess, err := parseGenBlockPostProcess("generate_file", block)
+	errs.Append(err)
+
+	_, hasContent := block.Body.Attributes["content"]
+	_, hasTemplateFile := block.Body.Attributes["template_file"]
+	if hasContent == hasTemplateFile {
+		errs.Append(errors.E(ErrTerramateSchema, block.Range,
+			"generate_file requires either a content or a template_file attribute"))
+	}
+
 	mergedLets := ast.MergedLabelBlocks{}
 	for labelType, mergedBlock := range letsConfig.MergedLabelBlocks {
 		if labelType.Type == "lets" {
@@ -926,136 +1276,702 @@ func parseGenerateFileBlock(block *ast.Block) (GenFileBlock, error) {
 	}
 
 	return GenFileBlock{
-		Range:     block.Range,
-		Label:     block.Labels[0],
-		Lets:      lets,
-		Asserts:   asserts,
-		Content:   block.Body.Attributes["content"],
-		Condition: block.Body.Attributes["condition"],
-		Context:   context,
+		Range:         block.Range,
+		Label:         block.Labels[0],
+		Lets:          lets,
+		Asserts:       asserts,
+		Content:       block.Body.Attributes["content"],
+		TemplateFile:  templateFile,
+		PostProcess:   postProcess,
+		Condition:     block.Body.Attributes["condition"],
+		Context:       context,
+		CommentPrefix: commentPrefix,
 	}, nil
 }
 
-func validateImportBlock(block *ast.Block) error {
-	errs := errors.L()
-	if len(block.Labels) != 0 {
-		errs.Append(errors.E(ErrTerramateSchema, block.LabelRanges(),
-			"import must have no labels but got %v",
-			block.Labels,
-		))
+// parseTemplateFile parses the template_file attribute of the given generate
+// block, returning the project path of the template file or an empty string
+// if the attribute is not defined.
+//
+// Relative paths are relative to the directory of the file defining the block
+// and absolute paths are relative to the project root. The template file
+// must be inside the project.
+func parseTemplateFile(blockType string, block *ast.Block) (string, error) {
+	attr, ok := block.Body.Attributes["template_file"]
+	if !ok {
+		return "", nil
 	}
-	schema := &hcl.BodySchema{
-		Attributes: []hcl.AttributeSchema{
-			{
-				Name:     "source",
-				Required: true,
-			},
-		},
+	value, diags := attr.Expr.Value(nil)
+	if diags.HasErrors() {
+		return "", errors.E(ErrTerramateSchema, diags,
+			"%s.template_file must be a literal string", blockType)
+	}
+	if value.Type() != cty.String || value.IsNull() || value.AsString() == "" {
+		return "", errors.E(ErrTerramateSchema, attr.Expr.Range(),
+			"%s.template_file must be a non-empty string", blockType)
 	}
 
-	_, diags := block.Body.Content(schema)
-	if diags.HasErrors() {
-		errs.Append(errors.E(ErrTerramateSchema, diags))
+	filename, ok := projectFilePath(block, value.AsString())
+	if !ok {
+		return "", errors.E(ErrTerramateSchema, attr.Expr.Range(),
+			"%s.template_file %q is outside the project", blockType, value.AsString())
 	}
-	return errs.AsError()
+	return filename, nil
 }
 
-func validateGenerateHCLBlock(block *ast.Block) error {
+// projectFilePath returns the project path of filename, which is either an
+// absolute project path or relative to the directory of the given block.
+// It returns false if the file is outside the project.
+func projectFilePath(block *ast.Block, filename string) (string, bool) {
+	// The path is handled relative to the root because path.Clean silently
+	// drops ".." elements going above an absolute root.
+	relpath := path.Clean(strings.TrimPrefix(filename, "/"))
+	if !path.IsAbs(filename) {
+		cfgdir := strings.TrimPrefix(block.Range.Path().Dir().String(), "/")
+		relpath = path.Join(cfgdir, filename)
+	}
+	if relpath == ".." || strings.HasPrefix(relpath, "../") {
+		return "", false
+	}
+	return path.Join("/", relpath), true
+}
+
+// parseGlobalsDataBlock parses a data block declared inside a globals block
+// with the given labels.
+func parseGlobalsDataBlock(labels []string, block *ast.Block) (GlobalsData, error) {
 	errs := errors.L()
+	errs.Append(checkNoBlocks(block))
 
-	// Don't seem like we can use hcl.BodySchema to check for any non-empty
-	// label, only specific label values.
-	if len(block.Labels) != 1 {
-		errs.Append(errors.E(ErrTerramateSchema, block.OpenBraceRange,
-			"generate_hcl must have single label instead got %v",
-			block.Labels,
-		))
-	} else if block.Labels[0] == "" {
-		errs.Append(errors.E(ErrTerramateSchema, block.OpenBraceRange,
-			"generate_hcl label can't be empty"))
-	}
-	// Schema check passes if no block is present, so check for amount of blocks
-	if len(block.Body.Blocks) == 0 {
-		errs.Append(errors.E(ErrTerramateSchema, block.Body.Range(),
-			"generate_hcl must have at least one 'content' block"))
+	data := GlobalsData{
+		Range:     block.Range,
+		LabelPath: labels,
 	}
 
-	schema := &hcl.BodySchema{
-		Attributes: []hcl.AttributeSchema{
-			{
-				Name:     "condition",
-				Required: false,
-			},
-		},
-		Blocks: []hcl.BlockHeaderSchema{
-			{
-				Type:       "content",
-				LabelNames: []string{},
-			},
-			{
-				Type:       "lets",
-				LabelNames: []string{},
-			},
-			{
-				Type:       "assert",
-				LabelNames: []string{},
-			},
-		},
+	if len(block.Labels) != 1 || !hclsyntax.ValidIdentifier(block.Labels[0]) {
+		errs.Append(errors.E(ErrTerramateSchema, block.LabelRanges(),
+			"globals.data block requires a single label with a valid identifier"))
+	} else {
+		data.Name = block.Labels[0]
 	}
 
-	_, diags := block.Body.Content(schema)
-	if diags.HasErrors() {
-		errs.Append(errors.E(ErrTerramateSchema, diags))
+	var fileAttr *ast.Attribute
+	for _, attr := range block.Attributes.SortedList() {
+		value, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() || value.Type() != cty.String || value.IsNull() {
+			errs.Append(attrErr(attr, "globals.data.%s must be a literal string", attr.Name))
+			continue
+		}
+		switch attr.Name {
+		case "file":
+			filename, ok := projectFilePath(block, value.AsString())
+			if !ok {
+				errs.Append(attrErr(attr,
+					"globals.data.file %q is outside the project", value.AsString()))
+				continue
+			}
+			attr := attr
+			fileAttr = &attr
+			data.File = filename
+		case "format":
+			switch format := value.AsString(); format {
+			case GlobalsDataJSON, GlobalsDataYAML, GlobalsDataHCL:
+				data.Format = format
+			default:
+				errs.Append(attrErr(attr,
+					"globals.data.format must be one of %q, %q or %q but got %q",
+					GlobalsDataJSON, GlobalsDataYAML, GlobalsDataHCL, format))
+			}
+		default:
+			errs.Append(errors.E(ErrTerramateSchema, attr.NameRange,
+				"unrecognized attribute globals.data.%s", attr.Name))
+		}
 	}
-	err := errs.AsError()
-	if err != nil {
-		return err
+
+	if fileAttr == nil {
+		errs.Append(errors.E(ErrTerramateSchema, block.DefRange(),
+			"globals.data.file is required"))
+	} else if data.File != "" && data.Format == "" {
+		switch path.Ext(data.File) {
+		case ".json":
+			data.Format = GlobalsDataJSON
+		case ".yaml", ".yml":
+			data.Format = GlobalsDataYAML
+		case ".hcl", ".tfvars":
+			data.Format = GlobalsDataHCL
+		default:
+			errs.Append(attrErr(*fileAttr,
+				"unable to detect the format of %q: globals.data.format must be set",
+				data.File))
+		}
 	}
 
-	return errs.AsError()
+	if err := errs.AsError(); err != nil {
+		return GlobalsData{}, err
+	}
+	return data, nil
 }
 
-func validateLets(block *ast.MergedBlock) error {
-	if block.Type != "lets" {
-		return errors.E(block.RawOrigins[0].TypeRange,
-			"unexpected block type %q", block.Type)
-	}
+// GlobalsMergeStrategy returns the merge strategy declared by the merge block
+// of the given globals block, or an empty string if there's none.
+func GlobalsMergeStrategy(block *ast.Block) (string, error) {
+	var mergeBlock *ast.Block
 	errs := errors.L()
 	for _, subBlock := range block.Blocks {
-		for _, raw := range subBlock.RawOrigins {
-			errs.Append(validateMap(raw))
+		if subBlock.Type != "merge" {
+			continue
+		}
+		if mergeBlock != nil {
+			errs.Append(errors.E(ErrTerramateSchema, subBlock.DefRange(),
+				"multiple globals.merge blocks declared"))
+			continue
 		}
+		mergeBlock = subBlock
 	}
-	return errs.AsError()
+	if mergeBlock == nil {
+		return "", errs.AsError()
+	}
+
+	errs.Append(checkNoLabels(mergeBlock))
+	errs.Append(checkNoBlocks(mergeBlock))
+
+	strategy := ""
+	for _, attr := range mergeBlock.Attributes.SortedList() {
+		if attr.Name != "strategy" {
+			errs.Append(errors.E(ErrTerramateSchema, attr.NameRange,
+				"unrecognized attribute globals.merge.%s", attr.Name))
+			continue
+		}
+		value, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() || value.Type() != cty.String || value.IsNull() {
+			errs.Append(attrErr(attr, "globals.merge.strategy must be a literal string"))
+			continue
+		}
+		switch value.AsString() {
+		case GlobalsMergeReplace, GlobalsMergeDeep, GlobalsMergeAppendList:
+			strategy = value.AsString()
+		default:
+			errs.Append(attrErr(attr,
+				"globals.merge.strategy must be one of %q, %q or %q but got %q",
+				GlobalsMergeReplace, GlobalsMergeDeep, GlobalsMergeAppendList,
+				value.AsString()))
+		}
+	}
+	if _, ok := mergeBlock.Attributes["strategy"]; !ok {
+		errs.Append(errors.E(ErrTerramateSchema, mergeBlock.DefRange(),
+			"globals.merge.strategy is required"))
+	}
+
+	if err := errs.AsError(); err != nil {
+		return "", err
+	}
+	return strategy, nil
 }
 
-func validateGenerateFileBlock(block *ast.Block) error {
-	errs := errors.L()
-	if len(block.Labels) != 1 {
-		errs.Append(errors.E(ErrTerramateSchema, block.OpenBraceRange,
-			"generate_file must have single label instead got %v",
-			block.Labels,
-		))
-	} else if block.Labels[0] == "" {
-		errs.Append(errors.E(ErrTerramateSchema, block.OpenBraceRange,
-			"generate_file label can't be empty"))
+// parseGenBlockPostProcess parses the post_process attribute of the given
+// generate block, if any.
+func parseGenBlockPostProcess(blockType string, block *ast.Block) ([]PostProcessConfig, error) {
+	attr, ok := block.Body.Attributes["post_process"]
+	if !ok {
+		return nil, nil
 	}
-	schema := &hcl.BodySchema{
-		Attributes: []hcl.AttributeSchema{
-			{
-				Name:     "content",
-				Required: true,
-			},
-			{
-				Name:     "condition",
+	value, diags := attr.Expr.Value(nil)
+	if diags.HasErrors() {
+		return nil, errors.E(ErrTerramateSchema, diags,
+			"%s.post_process must be a literal list of objects", blockType)
+	}
+	return parsePostProcess(blockType+".post_process", attr.Expr, value, false)
+}
+
+// parsePostProcess parses the list of post-processors defined at cfgpath.
+// The files patterns are only allowed, and then required, if withFiles is
+// true.
+func parsePostProcess(cfgpath string, expr hcl.Expression, value cty.Value, withFiles bool) ([]PostProcessConfig, error) {
+	if value.IsNull() || !(value.Type().IsTupleType() || value.Type().IsListType()) {
+		return nil, errors.E(ErrTerramateSchema, expr.Range(),
+			"%s must be a list of objects but is %s", cfgpath, value.Type().FriendlyName())
+	}
+
+	errs := errors.L()
+	var processors []PostProcessConfig
+	for it := value.ElementIterator(); it.Next(); {
+		_, elem := it.Element()
+		if elem.IsNull() || !(elem.Type().IsObjectType() || elem.Type().IsMapType()) {
+			errs.Append(errors.E(ErrTerramateSchema, expr.Range(),
+				"%s elements must be objects but got %s", cfgpath, elem.Type().FriendlyName()))
+			continue
+		}
+
+		var processor PostProcessConfig
+		values := elem.AsValueMap()
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			val := values[key]
+			switch key {
+			case "command":
+				command, err := ValueAsStringList(val)
+				if err != nil || len(command) == 0 {
+					errs.Append(errors.E(ErrTerramateSchema, expr.Range(),
+						"%s.command must be a non-empty list of strings", cfgpath))
+					continue
+				}
+				processor.Command = command
+			case "format":
+				if val.Type() != cty.String || val.IsNull() || val.AsString() != PostProcessFormatHCL {
+					errs.Append(errors.E(ErrTerramateSchema, expr.Range(),
+						"%s.format supported values are %q", cfgpath, PostProcessFormatHCL))
+					continue
+				}
+				processor.Format = val.AsString()
+			case "files":
+				if !withFiles {
+					errs.Append(errors.E(ErrTerramateSchema, expr.Range(),
+						"unrecognized attribute %s.files", cfgpath))
+					continue
+				}
+				files, err := ValueAsStringList(val)
+				if err != nil || len(files) == 0 {
+					errs.Append(errors.E(ErrTerramateSchema, expr.Range(),
+						"%s.files must be a non-empty list of strings", cfgpath))
+					continue
+				}
+				for _, pattern := range files {
+					if _, err := path.Match(pattern, ""); err != nil {
+						errs.Append(errors.E(ErrTerramateSchema, expr.Range(), err,
+							"%s.files has invalid pattern %q", cfgpath, pattern))
+					}
+				}
+				processor.Files = files
+			default:
+				errs.Append(errors.E(ErrTerramateSchema, expr.Range(),
+					"unrecognized attribute %s.%s", cfgpath, key))
+			}
+		}
+
+		_, hasFormat := values["format"]
+		_, hasCommand := values["command"]
+		if hasFormat == hasCommand {
+			errs.Append(errors.E(ErrTerramateSchema, expr.Range(),
+				"%s requires either a format or a command", cfgpath))
+		}
+		if _, hasFiles := values["files"]; withFiles && !hasFiles {
+			errs.Append(errors.E(ErrTerramateSchema, expr.Range(),
+				"%s requires the files patterns", cfgpath))
+		}
+		processors = append(processors, processor)
+	}
+
+	if err := errs.AsError(); err != nil {
+		return nil, err
+	}
+	return processors, nil
+}
+
+// parseGenerateStructBlock parses a generate_json or generate_yaml block.
+func parseGenerateStructBlock(block *ast.Block) (GenStructBlock, error) {
+	err := validateGenerateStructBlock(block)
+	if err != nil {
+		return GenStructBlock{}, err
+	}
+
+	var asserts []AssertConfig
+
+	letsConfig := NewCustomRawConfig(map[string]mergeHandler{
+		"lets": (*RawConfig).mergeLabeledBlock,
+	})
+
+	errs := errors.L()
+	for _, subBlock := range block.Blocks {
+		switch subBlock.Type {
+		case "lets":
+			errs.AppendWrap(ErrTerramateSchema, letsConfig.mergeBlocks(ast.Blocks{subBlock}))
+		case "assert":
+			assertCfg, err := parseAssertConfig(subBlock)
+			if err != nil {
+				errs.Append(err)
+				continue
+			}
+			asserts = append(asserts, assertCfg)
+		default:
+			// already validated but sanity checks...
+			panic(errors.E(errors.ErrInternal, "unexpected block type %s", subBlock.Type))
+		}
+	}
+
+	var postProcess []PostProcessConfig
+	if attr, ok := block.Body.Attributes["post_process"]; ok && block.Type == "generate_json" {
+		// WHY: the body of generated JSON files is not a complete document
+		// as the header opens the JSON object.
+		errs.Append(errors.E(ErrTerramateSchema, attr.NameRange,
+			"generate_json.post_process is not supported"))
+	} else {
+		postProcess, err = parseGenBlockPostProcess(block.Type, block)
+		errs.Append(err)
+	}
+
+	mergedLets := ast.MergedLabelBlocks{}
+	for labelType, mergedBlock := range letsConfig.MergedLabelBlocks {
+		if labelType.Type == "lets" {
+			mergedLets[labelType] = mergedBlock
+
+			errs.AppendWrap(ErrTerramateSchema, validateLets(mergedBlock))
+		}
+	}
+
+	if err := errs.AsError(); err != nil {
+		return GenStructBlock{}, err
+	}
+
+	lets, ok := mergedLets[ast.NewEmptyLabelBlockType("lets")]
+	if !ok {
+		lets = ast.NewMergedBlock("lets", []string{})
+	}
+
+	return GenStructBlock{
+		Range:       block.Range,
+		Type:        block.Type,
+		Label:       block.Labels[0],
+		Lets:        lets,
+		Asserts:     asserts,
+		Content:     block.Body.Attributes["content"],
+		Condition:   block.Body.Attributes["condition"],
+		PostProcess: postProcess,
+	}, nil
+}
+
+// parseInputBlock parses a stack input block.
+func (p *TerramateParser) parseInputBlock(block *ast.Block) (Input, error) {
+	errs := errors.L()
+	errs.Append(validateSingleLabel(block))
+	errs.Append(checkNoBlocks(block))
+
+	input := Input{
+		Range: block.Range,
+	}
+	if len(block.Labels) == 1 {
+		input.Name = block.Labels[0]
+	}
+
+	for _, attr := range block.Attributes.SortedList() {
+		switch attr.Name {
+		case "from_stack":
+			val, err := p.evalctx.Eval(attr.Expr)
+			if err != nil {
+				errs.Append(errors.E(ErrTerramateSchema, err,
+					"failed to evaluate input.from_stack"))
+				continue
+			}
+			if val.Type() != cty.String {
+				errs.Append(attrErr(attr,
+					"input.from_stack must be a string but given %q",
+					val.Type().FriendlyName()))
+				continue
+			}
+			input.FromStack = val.AsString()
+		case "value":
+			input.Value = attr.Expr
+		case "mock":
+			val, err := p.evalctx.Eval(attr.Expr)
+			if err != nil {
+				errs.Append(errors.E(ErrTerramateSchema, err,
+					"failed to evaluate input.mock"))
+				continue
+			}
+			input.Mock = val
+		default:
+			errs.Append(errors.E(ErrTerramateSchema, attr.NameRange,
+				"unrecognized attribute input.%s", attr.Name))
+		}
+	}
+
+	if input.FromStack == "" {
+		errs.Append(errors.E(ErrTerramateSchema, block.DefRange(),
+			"input.from_stack attribute is required"))
+	}
+	if input.Value == nil {
+		errs.Append(errors.E(ErrTerramateSchema, block.DefRange(),
+			"input.value attribute is required"))
+	}
+
+	if err := errs.AsError(); err != nil {
+		return Input{}, err
+	}
+	return input, nil
+}
+
+// parseOutputBlock parses a stack output block.
+func parseOutputBlock(block *ast.Block) (Output, error) {
+	errs := errors.L()
+	errs.Append(validateSingleLabel(block))
+	errs.Append(checkNoBlocks(block))
+
+	output := Output{
+		Range: block.Range,
+	}
+	if len(block.Labels) == 1 {
+		output.Name = block.Labels[0]
+	}
+
+	for _, attr := range block.Attributes.SortedList() {
+		switch attr.Name {
+		case "description":
+			val, diags := attr.Expr.Value(nil)
+			if diags.HasErrors() {
+				errs.Append(errors.E(ErrTerramateSchema, diags,
+					"failed to evaluate output.description"))
+				continue
+			}
+			if val.Type() != cty.String {
+				errs.Append(attrErr(attr,
+					"output.description must be a string but given %q",
+					val.Type().FriendlyName()))
+				continue
+			}
+			output.Description = val.AsString()
+		default:
+			errs.Append(errors.E(ErrTerramateSchema, attr.NameRange,
+				"unrecognized attribute output.%s", attr.Name))
+		}
+	}
+
+	if err := errs.AsError(); err != nil {
+		return Output{}, err
+	}
+	return output, nil
+}
+
+// parseGlobalSchemaBlock parses a globals_schema block.
+func parseGlobalSchemaBlock(block *ast.Block) (GlobalSchema, error) {
+	errs := errors.L()
+	errs.Append(checkNoBlocks(block))
+
+	schema := GlobalSchema{
+		Range:   block.Range,
+		Path:    block.Labels,
+		Type:    cty.DynamicPseudoType,
+		Default: cty.NilVal,
+	}
+
+	switch {
+	case len(block.Labels) == 0:
+		errs.Append(errors.E(ErrTerramateSchema, block.OpenBraceRange,
+			"globals_schema requires the global path as labels"))
+	case len(block.Labels) > project.MaxGlobalLabels:
+		errs.Append(errors.E(ErrTerramateSchema, block.LabelRanges(),
+			"globals_schema supports at most %d labels", project.MaxGlobalLabels))
+	case !hclsyntax.ValidIdentifier(block.Labels[0]):
+		errs.Append(errors.E(ErrTerramateSchema, block.LabelRanges(),
+			"globals_schema first label must be a valid identifier but got %q",
+			block.Labels[0]))
+	}
+
+	var defaultAttr *ast.Attribute
+	for _, attr := range block.Attributes.SortedList() {
+		switch attr.Name {
+		case "type":
+			typ, diags := typeexpr.TypeConstraint(attr.Expr)
+			if diags.HasErrors() {
+				errs.Append(errors.E(ErrTerramateSchema, diags,
+					"invalid globals_schema.type"))
+				continue
+			}
+			schema.Type = typ
+		case "required":
+			val, diags := attr.Expr.Value(nil)
+			if diags.HasErrors() || val.Type() != cty.Bool || val.IsNull() {
+				errs.Append(attrErr(attr, "globals_schema.required must be a literal boolean"))
+				continue
+			}
+			schema.Required = val.True()
+		case "sensitive":
+			val, diags := attr.Expr.Value(nil)
+			if diags.HasErrors() || val.Type() != cty.Bool || val.IsNull() {
+				errs.Append(attrErr(attr, "globals_schema.sensitive must be a literal boolean"))
+				continue
+			}
+			schema.Sensitive = val.True()
+		case "default":
+			val, diags := attr.Expr.Value(nil)
+			if diags.HasErrors() {
+				errs.Append(errors.E(ErrTerramateSchema, diags,
+					"globals_schema.default must be a literal value"))
+				continue
+			}
+			schema.Default = val
+			attr := attr
+			defaultAttr = &attr
+		case "description":
+			val, diags := attr.Expr.Value(nil)
+			if diags.HasErrors() || val.Type() != cty.String || val.IsNull() {
+				errs.Append(attrErr(attr, "globals_schema.description must be a literal string"))
+				continue
+			}
+			schema.Description = val.AsString()
+		default:
+			errs.Append(errors.E(ErrTerramateSchema, attr.NameRange,
+				"unrecognized attribute globals_schema.%s", attr.Name))
+		}
+	}
+
+	if defaultAttr != nil && schema.Default != cty.NilVal {
+		if schema.Required {
+			errs.Append(attrErr(*defaultAttr,
+				"globals_schema.default can't be used together with required = true"))
+		} else {
+			val, err := convert.Convert(schema.Default, schema.Type)
+			if err != nil {
+				errs.Append(attrErr(*defaultAttr,
+					"globals_schema.default is not a valid %s: %s",
+					typeexpr.TypeString(schema.Type), err))
+			} else {
+				schema.Default = val
+			}
+		}
+	}
+
+	if err := errs.AsError(); err != nil {
+		return GlobalSchema{}, err
+	}
+	return schema, nil
+}
+
+// validateGlobalsSchema checks that each global is declared once.
+func validateGlobalsSchema(schemas []GlobalSchema) error {
+	errs := errors.L()
+	declared := map[string]GlobalSchema{}
+	for _, schema := range schemas {
+		name := strings.Join(schema.Path, ".")
+		if other, ok := declared[name]; ok {
+			errs.Append(errors.E(ErrTerramateSchema, schema.Range,
+				"globals_schema for global.%s redeclared: previously declared at %s",
+				name, other.Range.String()))
+			continue
+		}
+		declared[name] = schema
+	}
+	return errs.AsError()
+}
+
+func validateSingleLabel(block *ast.Block) error {
+	if len(block.Labels) != 1 {
+		return errors.E(ErrTerramateSchema, block.OpenBraceRange,
+			"%s must have single label instead got %v",
+			block.Type, block.Labels,
+		)
+	}
+	if block.Labels[0] == "" {
+		return errors.E(ErrTerramateSchema, block.OpenBraceRange,
+			"%s label can't be empty", block.Type)
+	}
+	return nil
+}
+
+func validateInputsOutputs(isStack bool, inputs []Input, outputs []Output) error {
+	errs := errors.L()
+	if !isStack {
+		for _, input := range inputs {
+			errs.Append(errors.E(ErrTerramateSchema, input.Range,
+				"input blocks are only allowed in stacks"))
+		}
+		for _, output := range outputs {
+			errs.Append(errors.E(ErrTerramateSchema, output.Range,
+				"output blocks are only allowed in stacks"))
+		}
+	}
+
+	inputNames := map[string]struct{}{}
+	for _, input := range inputs {
+		if _, ok := inputNames[input.Name]; ok {
+			errs.Append(errors.E(ErrTerramateSchema, input.Range,
+				"duplicated input %q", input.Name))
+		}
+		inputNames[input.Name] = struct{}{}
+	}
+
+	outputNames := map[string]struct{}{}
+	for _, output := range outputs {
+		if _, ok := outputNames[output.Name]; ok {
+			errs.Append(errors.E(ErrTerramateSchema, output.Range,
+				"duplicated output %q", output.Name))
+		}
+		outputNames[output.Name] = struct{}{}
+	}
+	return errs.AsError()
+}
+
+func validateImportBlock(block *ast.Block) error {
+	errs := errors.L()
+	if len(block.Labels) != 0 {
+		errs.Append(errors.E(ErrTerramateSchema, block.LabelRanges(),
+			"import must have no labels but got %v",
+			block.Labels,
+		))
+	}
+	schema := &hcl.BodySchema{
+		Attributes: []hcl.AttributeSchema{
+			{
+				Name:     "source",
+				Required: true,
+			},
+		},
+	}
+
+	_, diags := block.Body.Content(schema)
+	if diags.HasErrors() {
+		errs.Append(errors.E(ErrTerramateSchema, diags))
+	}
+	return errs.AsError()
+}
+
+func validateGenerateHCLBlock(block *ast.Block) error {
+	errs := errors.L()
+
+	// Don't seem like we can use hcl.BodySchema to check for any non-empty
+	// label, only specific label values.
+	if len(block.Labels) != 1 {
+		errs.Append(errors.E(ErrTerramateSchema, block.OpenBraceRange,
+			"generate_hcl must have single label instead got %v",
+			block.Labels,
+		))
+	} else if block.Labels[0] == "" {
+		errs.Append(errors.E(ErrTerramateSchema, block.OpenBraceRange,
+			"generate_hcl label can't be empty"))
+	}
+	// Schema check passes if no block is present, so check for amount of blocks
+	_, hasTemplateFile := block.Body.Attributes["template_file"]
+	if len(block.Body.Blocks) == 0 && !hasTemplateFile {
+		errs.Append(errors.E(ErrTerramateSchema, block.Body.Range(),
+			"generate_hcl must have at least one 'content' block"))
+	}
+
+	schema := &hcl.BodySchema{
+		Attributes: []hcl.AttributeSchema{
+			{
+				Name:     "condition",
 				Required: false,
 			},
 			{
 				Name:     "context",
 				Required: false,
 			},
+			{
+				Name:     "template_file",
+				Required: false,
+			},
+			{
+				Name:     "post_process",
+				Required: false,
+			},
 		},
 		Blocks: []hcl.BlockHeaderSchema{
+			{
+				Type:       "content",
+				LabelNames: []string{},
+			},
 			{
 				Type:       "lets",
 				LabelNames: []string{},
@@ -1075,6 +1991,127 @@ func validateGenerateFileBlock(block *ast.Block) error {
 	if err != nil {
 		return err
 	}
+
+	return errs.AsError()
+}
+
+func validateLets(block *ast.MergedBlock) error {
+	if block.Type != "lets" {
+		return errors.E(block.RawOrigins[0].TypeRange,
+			"unexpected block type %q", block.Type)
+	}
+	errs := errors.L()
+	for _, subBlock := range block.Blocks {
+		for _, raw := range subBlock.RawOrigins {
+			errs.Append(validateMap(raw))
+		}
+	}
+	return errs.AsError()
+}
+
+func validateGenerateFileBlock(block *ast.Block) error {
+	errs := errors.L()
+	if len(block.Labels) != 1 {
+		errs.Append(errors.E(ErrTerramateSchema, block.OpenBraceRange,
+			"generate_file must have single label instead got %v",
+			block.Labels,
+		))
+	} else if block.Labels[0] == "" {
+		errs.Append(errors.E(ErrTerramateSchema, block.OpenBraceRange,
+			"generate_file label can't be empty"))
+	}
+	schema := &hcl.BodySchema{
+		Attributes: []hcl.AttributeSchema{
+			{
+				Name:     "content",
+				Required: false,
+			},
+			{
+				Name:     "template_file",
+				Required: false,
+			},
+			{
+				Name:     "condition",
+				Required: false,
+			},
+			{
+				Name:     "context",
+				Required: false,
+			},
+			{
+				Name:     "comment_prefix",
+				Required: false,
+			},
+			{
+				Name:     "post_process",
+				Required: false,
+			},
+		},
+		Blocks: []hcl.BlockHeaderSchema{
+			{
+				Type:       "lets",
+				LabelNames: []string{},
+			},
+			{
+				Type:       "assert",
+				LabelNames: []string{},
+			},
+		},
+	}
+
+	_, diags := block.Body.Content(schema)
+	if diags.HasErrors() {
+		errs.Append(errors.E(ErrTerramateSchema, diags))
+	}
+	err := errs.AsError()
+	if err != nil {
+		return err
+	}
+	return errs.AsError()
+}
+
+func validateGenerateStructBlock(block *ast.Block) error {
+	errs := errors.L()
+	if len(block.Labels) != 1 {
+		errs.Append(errors.E(ErrTerramateSchema, block.OpenBraceRange,
+			"%s must have single label instead got %v",
+			block.Type, block.Labels,
+		))
+	} else if block.Labels[0] == "" {
+		errs.Append(errors.E(ErrTerramateSchema, block.OpenBraceRange,
+			"%s label can't be empty", block.Type))
+	}
+	schema := &hcl.BodySchema{
+		Attributes: []hcl.AttributeSchema{
+			{
+				Name:     "content",
+				Required: true,
+			},
+			{
+				Name:     "condition",
+				Required: false,
+			},
+			{
+				Name:     "post_process",
+				Required: false,
+			},
+		},
+		Blocks: []hcl.BlockHeaderSchema{
+			{
+				Type:       "lets",
+				LabelNames: []string{},
+			},
+			{
+				Type:       "assert",
+				LabelNames: []string{},
+			},
+		},
+	}
+
+	_, diags := block.Body.Content(schema)
+	if diags.HasErrors() {
+		errs.Append(errors.E(ErrTerramateSchema, diags))
+	}
 	return errs.AsError()
 }
 
@@ -1386,7 +2423,7 @@ func parseRootConfig(cfg *RootConfig, block *ast.MergedBlock) error {
 		))
 	}
 
-	errs.AppendWrap(ErrTerramateSchema, block.ValidateSubBlocks("git", "run"))
+	errs.AppendWrap(ErrTerramateSchema, block.ValidateSubBlocks("git", "run", "generate"))
 
 	gitBlock, ok := block.Blocks[ast.NewEmptyLabelBlockType("git")]
 	if ok {
@@ -1412,6 +2449,59 @@ func parseRootConfig(cfg *RootConfig, block *ast.MergedBlock) error {
 		errs.Append(parseRunConfig(cfg.Run, runBlock))
 	}
 
+	generateBlock, ok := block.Blocks[ast.NewEmptyLabelBlockType("generate")]
+	if ok {
+		logger.Trace().Msg("Type is 'generate'")
+
+		cfg.Generate = &GenerateRootConfig{}
+
+		logger.Trace().Msg("Parse generate config.")
+
+		errs.Append(parseGenerateRootConfig(cfg.Generate, generateBlock))
+	}
+
+	return errs.AsError()
+}
+
+func parseGenerateRootConfig(cfg *GenerateRootConfig, generateBlock *ast.MergedBlock) error {
+	errs := errors.L()
+
+	errs.AppendWrap(ErrTerramateSchema, generateBlock.ValidateSubBlocks())
+
+	for _, attr := range generateBlock.Attributes.SortedList() {
+		value, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() {
+			errs.Append(errors.E(diags,
+				"failed to evaluate terramate.config.generate.%s attribute", attr.Name,
+			))
+			continue
+		}
+
+		switch attr.Name {
+		case "provenance":
+			if value.Type() != cty.Bool {
+				errs.Append(attrErr(attr,
+					"terramate.config.generate.provenance is not a boolean but %q",
+					value.Type().FriendlyName(),
+				))
+				continue
+			}
+			cfg.Provenance = value.True()
+		case "post_process":
+			postProcess, err := parsePostProcess(
+				"terramate.config.generate.post_process", attr.Expr, value, true)
+			if err != nil {
+				errs.Append(err)
+				continue
+			}
+			cfg.PostProcess = postProcess
+		default:
+			errs.Append(errors.E(ErrTerramateSchema, attr.NameRange,
+				"unrecognized attribute terramate.config.generate.%s", attr.Name,
+			))
+		}
+	}
+
 	return errs.AsError()
 }
 
@@ -1444,6 +2534,19 @@ func parseRunConfig(runCfg *RunConfig, runBlock *ast.MergedBlock) error {
 				continue
 			}
 			runCfg.CheckGenCode = value.True()
+		case "outputs_command":
+			cmd, err := ValueAsStringList(value)
+			if err != nil {
+				errs.Append(attrErr(attr,
+					"terramate.config.run.outputs_command: %v", err))
+				continue
+			}
+			if len(cmd) == 0 {
+				errs.Append(attrErr(attr,
+					"terramate.config.run.outputs_command must not be empty"))
+				continue
+			}
+			runCfg.OutputsCommand = cmd
 		default:
 			errs.Append(errors.E("unrecognized attribute terramate.config.run.env.%s",
 				attr.Name))
@@ -1579,7 +2682,12 @@ func (p *TerramateParser) parseTerramateSchema() (Config, error) {
 
 	config := Config{
 		absdir: p.dir,
+		digest: p.digest(),
+	}
+	for name := range p.functionCalls() {
+		config.funcCalls = append(config.funcCalls, name)
 	}
+	sort.Strings(config.funcCalls)
 
 	errKind := ErrTerramateSchema
 	errs := errors.L()
@@ -1649,6 +2757,9 @@ func (p *TerramateParser) parseTerramateSchema() (Config, error) {
 			genhcl, err := parseGenerateHCLBlock(block)
 			errs.Append(err)
 			if err == nil {
+				if genhcl.Content != nil {
+					genhcl.Comments = p.contentComments(genhcl.Content)
+				}
 				config.Generate.HCLs = append(config.Generate.HCLs, genhcl)
 			}
 
@@ -1660,7 +2771,67 @@ func (p *TerramateParser) parseTerramateSchema() (Config, error) {
 			if err == nil {
 				config.Generate.Files = append(config.Generate.Files, genfile)
 			}
+
+		case "generate_json", "generate_yaml":
+			logger.Trace().Msgf("Found %q block", block.Type)
+
+			genstruct, err := parseGenerateStructBlock(block)
+			errs.Append(err)
+			if err == nil {
+				if block.Type == "generate_json" {
+					config.Generate.JSONs = append(config.Generate.JSONs, genstruct)
+				} else {
+					config.Generate.YAMLs = append(config.Generate.YAMLs, genstruct)
+				}
+			}
+
+		case "input":
+			logger.Trace().Msg("Found \"input\" block")
+
+			input, err := p.parseInputBlock(block)
+			errs.Append(err)
+			if err == nil {
+				config.Inputs = append(config.Inputs, input)
+			}
+
+		case "output":
+			logger.Trace().Msg("Found \"output\" block")
+
+			output, err := parseOutputBlock(block)
+			errs.Append(err)
+			if err == nil {
+				config.Outputs = append(config.Outputs, output)
+			}
+
+		case "globals_schema":
+			logger.Trace().Msg("Found \"globals_schema\" block")
+
+			schema, err := parseGlobalSchemaBlock(block)
+			errs.Append(err)
+			if err == nil {
+				config.GlobalsSchema = append(config.GlobalsSchema, schema)
+			}
+		}
+	}
+
+	errs.Append(validateInputsOutputs(foundstack, config.Inputs, config.Outputs))
+	errs.Append(validateGlobalsSchema(config.GlobalsSchema))
+
+	for labelType, letsBlock := range rawconfig.MergedLabelBlocks {
+		if labelType.Type != "lets" {
+			continue
+		}
+		if len(letsBlock.Labels) > 0 {
+			errs.Append(errors.E(ErrTerramateSchema, letsBlock.RawOrigins[0].LabelRanges(),
+				"lets block does not support labels"))
+			continue
 		}
+		err := letsBlock.ValidateSubBlocks("map")
+		if err == nil {
+			err = validateLets(letsBlock)
+		}
+		errs.AppendWrap(ErrTerramateSchema, err)
+		config.Lets = letsBlock
 	}
 
 	tmBlock, ok := rawconfig.MergedBlocks["terramate"]
@@ -1688,11 +2859,38 @@ func (p *TerramateParser) parseTerramateSchema() (Config, error) {
 	}
 
 	globals := ast.MergedLabelBlocks{}
+	globalsData := map[string]GlobalsData{}
 	for labelType, mergedBlock := range rawconfig.MergedLabelBlocks {
 		if labelType.Type == "globals" {
 			globals[labelType] = mergedBlock
 
 			errs.AppendWrap(ErrTerramateSchema, validateGlobals(mergedBlock))
+
+			for _, raw := range mergedBlock.RawOrigins {
+				strategy, err := GlobalsMergeStrategy(raw)
+				errs.Append(err)
+
+				for _, subBlock := range raw.Blocks {
+					if subBlock.Type != "data" {
+						continue
+					}
+					data, err := parseGlobalsDataBlock(mergedBlock.Labels, subBlock)
+					errs.Append(err)
+					if err != nil {
+						continue
+					}
+					data.Merge = strategy
+					name := strings.Join(append(append([]string{}, data.LabelPath...), data.Name), ".")
+					if other, ok := globalsData[name]; ok {
+						errs.Append(errors.E(ErrTerramateSchema, subBlock.DefRange(),
+							"globals.data for global.%s redeclared: previously declared at %s",
+							name, other.Range.String()))
+						continue
+					}
+					globalsData[name] = data
+					config.GlobalsData = append(config.GlobalsData, data)
+				}
+			}
 		}
 	}
 
@@ -1764,10 +2962,12 @@ func validateGlobals(block *ast.MergedBlock) error {
 		return errors.E(ErrTerramateSchema,
 			block.RawOrigins[0].TypeRange, "unexpected block type %q", block.Type)
 	}
-	errs.Append(block.ValidateSubBlocks("map"))
+	errs.Append(block.ValidateSubBlocks("map", "data", "merge"))
 	for _, raw := range block.RawOrigins {
 		for _, subBlock := range raw.Blocks {
-			errs.Append(validateMap(subBlock))
+			if subBlock.Type == "map" {
+				errs.Append(validateMap(subBlock))
+			}
 		}
 	}
 	return errs.AsError()
@@ -1791,6 +2991,20 @@ func validateMap(block *ast.Block) (err error) {
 	if !ok {
 		return errors.E(block.TypeRange, "map.key is required")
 	}
+	for _, attr := range block.Attributes.SortedList() {
+		switch attr.Name {
+		case "for_each", "iterator", "key", "value", "condition":
+		case "group":
+			val, diags := attr.Expr.Value(nil)
+			if diags.HasErrors() || val.Type() != cty.Bool || val.IsNull() {
+				return errors.E(attr.Expr.Range(),
+					"map.group must be a boolean literal")
+			}
+		default:
+			return errors.E(attr.NameRange,
+				"unrecognized attribute map.%s", attr.Name)
+		}
+	}
 	_, hasValueAttr := block.Attributes["value"]
 	hasValueBlock := false
 	for _, subBlock := range block.Blocks {
@@ -0,0 +1,282 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package hcl_test
+
+import (
+	"testing"
+
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/hcl"
+)
+
+func TestHCLParserConfigGenerate(t *testing.T) {
+	for _, tc := range []testcase{
+		{
+			name: "empty generate",
+			input: []cfgfile{
+				{
+					filename: "cfg.tm",
+					body: `terramate {
+					  config {
+					    generate {
+					    }
+					  }
+					}`,
+				},
+			},
+			want: want{
+				config: hcl.Config{
+					Terramate: &hcl.Terramate{
+						Config: &hcl.RootConfig{
+							Generate: &hcl.GenerateRootConfig{},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "provenance enabled",
+			input: []cfgfile{
+				{
+					filename: "cfg.tm",
+					body: `terramate {
+					  config {
+					    generate {
+					      provenance = true
+					    }
+					  }
+					}`,
+				},
+			},
+			want: want{
+				config: hcl.Config{
+					Terramate: &hcl.Terramate{
+						Config: &hcl.RootConfig{
+							Generate: &hcl.GenerateRootConfig{
+								Provenance: true,
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "provenance is not a boolean",
+			input: []cfgfile{
+				{
+					filename: "cfg.tm",
+					body: `terramate {
+					  config {
+					    generate {
+					      provenance = "yes"
+					    }
+					  }
+					}`,
+				},
+			},
+			want: want{
+				errs: []error{
+					errors.E(hcl.ErrTerramateSchema),
+				},
+			},
+		},
+		{
+			name: "unrecognized attribute on generate",
+			input: []cfgfile{
+				{
+					filename: "cfg.tm",
+					body: `terramate {
+					  config {
+					    generate {
+					      something = true
+					    }
+					  }
+					}`,
+				},
+			},
+			want: want{
+				errs: []error{
+					errors.E(hcl.ErrTerramateSchema),
+				},
+			},
+		},
+		{
+			name: "unrecognized block on generate",
+			input: []cfgfile{
+				{
+					filename: "cfg.tm",
+					body: `terramate {
+					  config {
+					    generate {
+					      something {
+					      }
+					    }
+					  }
+					}`,
+				},
+			},
+			want: want{
+				errs: []error{
+					errors.E(hcl.ErrTerramateSchema),
+				},
+			},
+		},
+		{
+			name: "post_process",
+			input: []cfgfile{
+				{
+					filename: "cfg.tm",
+					body: `terramate {
+					  config {
+					    generate {
+					      post_process = [
+					        {
+					          files  = ["*.tf", "*.hcl"]
+					          format = "hcl"
+					        },
+					        {
+					          files   = ["*.yml"]
+					          command = ["prettier", "--parser", "yaml"]
+					        },
+					      ]
+					    }
+					  }
+					}`,
+				},
+			},
+			want: want{
+				config: hcl.Config{
+					Terramate: &hcl.Terramate{
+						Config: &hcl.RootConfig{
+							Generate: &hcl.GenerateRootConfig{
+								PostProcess: []hcl.PostProcessConfig{
+									{
+										Files:  []string{"*.tf", "*.hcl"},
+										Format: "hcl",
+									},
+									{
+										Files:   []string{"*.yml"},
+										Command: []string{"prettier", "--parser", "yaml"},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "post_process without files",
+			input: []cfgfile{
+				{
+					filename: "cfg.tm",
+					body: `terramate {
+					  config {
+					    generate {
+					      post_process = [{ format = "hcl" }]
+					    }
+					  }
+					}`,
+				},
+			},
+			want: want{
+				errs: []error{
+					errors.E(hcl.ErrTerramateSchema),
+				},
+			},
+		},
+		{
+			name: "post_process with format and command",
+			input: []cfgfile{
+				{
+					filename: "cfg.tm",
+					body: `terramate {
+					  config {
+					    generate {
+					      post_process = [{
+					        files   = ["*.tf"]
+					        format  = "hcl"
+					        command = ["terraform", "fmt", "-"]
+					      }]
+					    }
+					  }
+					}`,
+				},
+			},
+			want: want{
+				errs: []error{
+					errors.E(hcl.ErrTerramateSchema),
+				},
+			},
+		},
+		{
+			name: "post_process with unsupported format",
+			input: []cfgfile{
+				{
+					filename: "cfg.tm",
+					body: `terramate {
+					  config {
+					    generate {
+					      post_process = [{
+					        files  = ["*.json"]
+					        format = "json"
+					      }]
+					    }
+					  }
+					}`,
+				},
+			},
+			want: want{
+				errs: []error{
+					errors.E(hcl.ErrTerramateSchema),
+				},
+			},
+		},
+		{
+			name: "post_process with invalid files pattern",
+			input: []cfgfile{
+				{
+					filename: "cfg.tm",
+					body: `terramate {
+					  config {
+					    generate {
+					      post_process = [{
+					        files  = ["[.tf"]
+					        format = "hcl"
+					      }]
+					    }
+					  }
+					}`,
+				},
+			},
+			want: want{
+				errs: []error{
+					errors.E(hcl.ErrTerramateSchema),
+				},
+			},
+		},
+		{
+			name: "post_process is not a list",
+			input: []cfgfile{
+				{
+					filename: "cfg.tm",
+					body: `terramate {
+					  config {
+					    generate {
+					      post_process = { format = "hcl" }
+					    }
+					  }
+					}`,
+				},
+			},
+			want: want{
+				errs: []error{
+					errors.E(hcl.ErrTerramateSchema),
+				},
+			},
+		},
+	} {
+		testParser(t, tc)
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package hcl_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/hcl"
+	"github.com/terramate-io/terramate/test"
+)
+
+func TestHCLConfigDigestAndFunctionCalls(t *testing.T) {
+	t.Parallel()
+
+	rootdir := t.TempDir()
+	cfgdir := filepath.Join(rootdir, "stack")
+	importdir := filepath.Join(rootdir, "imports")
+
+	test.WriteFile(t, importdir, "globals.tm", `
+		globals {
+			a = tm_upper("a")
+		}
+	`)
+	test.WriteFile(t, cfgdir, "stack.tm", `
+		import {
+			source = "/imports/globals.tm"
+		}
+
+		generate_file "file.txt" {
+			content = tm_join(",", [tm_lower("A"), global.a])
+		}
+	`)
+
+	parse := func() hcl.Config {
+		t.Helper()
+		parser, err := hcl.NewTerramateParser(rootdir, cfgdir)
+		assert.NoError(t, err)
+		assert.NoError(t, parser.AddDir(cfgdir))
+		cfg, err := parser.ParseConfig()
+		assert.NoError(t, err)
+		return cfg
+	}
+
+	cfg := parse()
+	if diff := cmp.Diff(cfg.FunctionCalls(), []string{"tm_join", "tm_lower", "tm_upper"}); diff != "" {
+		t.Fatalf("unexpected function calls: %s", diff)
+	}
+
+	digest := cfg.Digest()
+	assert.IsTrue(t, digest != "", "digest must not be empty")
+	assert.EqualStrings(t, digest, parse().Digest(), "same files must have same digest")
+
+	test.WriteFile(t, importdir, "globals.tm", `
+		globals {
+			a = "a"
+		}
+	`)
+
+	cfg = parse()
+	assert.IsTrue(t, digest != cfg.Digest(), "changing imported file must change the digest")
+	if diff := cmp.Diff(cfg.FunctionCalls(), []string{"tm_join", "tm_lower"}); diff != "" {
+		t.Fatalf("unexpected function calls: %s", diff)
+	}
+}
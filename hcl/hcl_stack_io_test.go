@@ -0,0 +1,197 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package hcl_test
+
+import (
+	"testing"
+
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/hcl"
+	"github.com/terramate-io/terramate/test"
+	. "github.com/terramate-io/terramate/test/hclutils"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestHCLParserStackInputsOutputs(t *testing.T) {
+	for _, tc := range []testcase{
+		{
+			name: "input and output blocks",
+			input: []cfgfile{
+				{
+					filename: "stack.tm",
+					body: `
+						stack {}
+
+						input "vpc_id" {
+							from_stack = "/network"
+							value      = outputs.vpc_id.value
+							mock       = "vpc-${tm_upper("mock")}"
+						}
+
+						input "subnets" {
+							from_stack = "../network"
+							value      = outputs.subnets.value
+						}
+
+						output "db_endpoint" {
+							description = "the database endpoint"
+						}
+
+						output "db_port" {}
+					`,
+				},
+			},
+			want: want{
+				config: hcl.Config{
+					Stack: &hcl.Stack{},
+					Inputs: []hcl.Input{
+						{
+							Name:      "vpc_id",
+							FromStack: "/network",
+							Value:     test.NewExpr(t, "outputs.vpc_id.value"),
+							Mock:      cty.StringVal("vpc-MOCK"),
+						},
+						{
+							Name:      "subnets",
+							FromStack: "../network",
+							Value:     test.NewExpr(t, "outputs.subnets.value"),
+						},
+					},
+					Outputs: []hcl.Output{
+						{
+							Name:        "db_endpoint",
+							Description: "the database endpoint",
+						},
+						{
+							Name: "db_port",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "input and output outside stack fails",
+			input: []cfgfile{
+				{
+					filename: "cfg.tm",
+					body: `
+						input "a" {
+							from_stack = "/network"
+							value      = outputs.a.value
+						}
+
+						output "b" {}
+					`,
+				},
+			},
+			want: want{
+				errs: []error{
+					errors.E(hcl.ErrTerramateSchema,
+						Mkrange("cfg.tm", Start(2, 7, 7), End(5, 8, 93))),
+					errors.E(hcl.ErrTerramateSchema,
+						Mkrange("cfg.tm", Start(7, 7, 101), End(7, 20, 114))),
+				},
+			},
+		},
+		{
+			name: "input missing required attributes",
+			input: []cfgfile{
+				{
+					filename: "stack.tm",
+					body: `
+						stack {}
+						input "a" {
+						}
+					`,
+				},
+			},
+			want: want{
+				errs: []error{
+					errors.E(hcl.ErrTerramateSchema,
+						Mkrange("stack.tm", Start(3, 7, 22), End(3, 16, 31))),
+					errors.E(hcl.ErrTerramateSchema,
+						Mkrange("stack.tm", Start(3, 7, 22), End(3, 16, 31))),
+				},
+			},
+		},
+		{
+			name: "input with unrecognized attribute, block and no label",
+			input: []cfgfile{
+				{
+					filename: "stack.tm",
+					body: `
+						stack {}
+						input {
+							from_stack = "/a"
+							value      = outputs.a.value
+							other      = 1
+							block {}
+						}
+					`,
+				},
+			},
+			want: want{
+				errs: []error{
+					errors.E(hcl.ErrTerramateSchema,
+						Mkrange("stack.tm", Start(3, 13, 28), End(3, 14, 29))),
+					errors.E(hcl.ErrTerramateSchema,
+						Mkrange("stack.tm", Start(7, 8, 120), End(7, 13, 125))),
+					errors.E(hcl.ErrTerramateSchema,
+						Mkrange("stack.tm", Start(6, 8, 98), End(6, 13, 103))),
+				},
+			},
+		},
+		{
+			name: "duplicated inputs and outputs",
+			input: []cfgfile{
+				{
+					filename: "stack.tm",
+					body: `
+						stack {}
+						input "a" {
+							from_stack = "/a"
+							value      = outputs.a.value
+						}
+						input "a" {
+							from_stack = "/b"
+							value      = outputs.a.value
+						}
+						output "o" {}
+						output "o" {}
+					`,
+				},
+			},
+			want: want{
+				errs: []error{
+					errors.E(hcl.ErrTerramateSchema,
+						Mkrange("stack.tm", Start(7, 7, 109), End(10, 8, 189))),
+					errors.E(hcl.ErrTerramateSchema,
+						Mkrange("stack.tm", Start(12, 7, 216), End(12, 20, 229))),
+				},
+			},
+		},
+		{
+			name: "output with invalid description",
+			input: []cfgfile{
+				{
+					filename: "stack.tm",
+					body: `
+						stack {}
+						output "o" {
+							description = 1
+						}
+					`,
+				},
+			},
+			want: want{
+				errs: []error{
+					errors.E(hcl.ErrTerramateSchema,
+						Mkrange("stack.tm", Start(4, 22, 56), End(4, 23, 57))),
+				},
+			},
+		},
+	} {
+		testParser(t, tc)
+	}
+}
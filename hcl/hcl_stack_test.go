@@ -581,6 +581,65 @@ func TestHCLParserStack(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "disabled and manual",
+			input: []cfgfile{
+				{
+					filename: "stack.tm",
+					body: `
+						stack {
+							disabled = true
+							manual = false
+						}
+					`,
+				},
+			},
+			want: want{
+				config: hcl.Config{
+					Stack: &hcl.Stack{
+						Disabled: true,
+					},
+				},
+			},
+		},
+		{
+			name: "disabled must be a bool",
+			input: []cfgfile{
+				{
+					filename: "stack.tm",
+					body: `
+						stack {
+							disabled = "true"
+						}
+					`,
+				},
+			},
+			want: want{
+				errs: []error{
+					errors.E(hcl.ErrTerramateSchema,
+						Mkrange("stack.tm", Start(3, 19, 33), End(3, 25, 39))),
+				},
+			},
+		},
+		{
+			name: "manual must be a bool",
+			input: []cfgfile{
+				{
+					filename: "stack.tm",
+					body: `
+						stack {
+							manual = 1
+						}
+					`,
+				},
+			},
+			want: want{
+				errs: []error{
+					errors.E(hcl.ErrTerramateSchema,
+						Mkrange("stack.tm", Start(3, 17, 31), End(3, 18, 32))),
+				},
+			},
+		},
 	} {
 		testParser(t, tc)
 	}
@@ -661,6 +661,220 @@ func TestHCLParserMultipleErrors(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "generate_hcl with invalid context - fails",
+			input: []cfgfile{
+				{
+					filename: "gen.tm",
+					body: `
+					generate_hcl "test.tf" {
+						context = other
+						content {
+							a = 1
+						}
+					}
+					`,
+				},
+			},
+			want: want{
+				errs: []error{
+					errors.E(hcl.ErrTerramateSchema),
+				},
+			},
+		},
+		{
+			name: "generate_file with non-string comment_prefix - fails",
+			input: []cfgfile{
+				{
+					filename: "gen.tm",
+					body: `
+					generate_file "test.sh" {
+						comment_prefix = 1
+						content = "echo"
+					}
+					`,
+				},
+			},
+			want: want{
+				errs: []error{
+					errors.E(hcl.ErrTerramateSchema),
+				},
+			},
+		},
+		{
+			name: "generate_file with content and template_file - fails",
+			input: []cfgfile{
+				{
+					filename: "gen.tm",
+					body: `
+					generate_file "test.sh" {
+						template_file = "test.sh.tpl"
+						content = "echo"
+					}
+					`,
+				},
+			},
+			want: want{
+				errs: []error{
+					errors.E(hcl.ErrTerramateSchema),
+				},
+			},
+		},
+		{
+			name: "generate_file without content and template_file - fails",
+			input: []cfgfile{
+				{
+					filename: "gen.tm",
+					body: `
+					generate_file "test.sh" {
+						comment_prefix = "#"
+					}
+					`,
+				},
+			},
+			want: want{
+				errs: []error{
+					errors.E(hcl.ErrTerramateSchema),
+				},
+			},
+		},
+		{
+			name: "generate_file with non-string template_file - fails",
+			input: []cfgfile{
+				{
+					filename: "gen.tm",
+					body: `
+					generate_file "test.sh" {
+						template_file = 1
+					}
+					`,
+				},
+			},
+			want: want{
+				errs: []error{
+					errors.E(hcl.ErrTerramateSchema),
+				},
+			},
+		},
+		{
+			name: "generate_file with template_file outside project - fails",
+			input: []cfgfile{
+				{
+					filename: "gen.tm",
+					body: `
+					generate_file "test.sh" {
+						template_file = "../test.sh.tpl"
+					}
+					`,
+				},
+			},
+			want: want{
+				errs: []error{
+					errors.E(hcl.ErrTerramateSchema),
+				},
+			},
+		},
+		{
+			name: "generate_hcl with content block and template_file - fails",
+			input: []cfgfile{
+				{
+					filename: "gen.tm",
+					body: `
+					generate_hcl "test.tf" {
+						template_file = "test.tf.tpl"
+						content {
+							a = 1
+						}
+					}
+					`,
+				},
+			},
+			want: want{
+				errs: []error{
+					errors.E(hcl.ErrTerramateSchema),
+				},
+			},
+		},
+		{
+			name: "generate_hcl with template_file outside project - fails",
+			input: []cfgfile{
+				{
+					filename: "gen.tm",
+					body: `
+					generate_hcl "test.tf" {
+						template_file = "/../test.tf.tpl"
+					}
+					`,
+				},
+			},
+			want: want{
+				errs: []error{
+					errors.E(hcl.ErrTerramateSchema),
+				},
+			},
+		},
+		{
+			name: "generate_hcl with files on post_process - fails",
+			input: []cfgfile{
+				{
+					filename: "gen.tm",
+					body: `
+					generate_hcl "test.tf" {
+						post_process = [{
+							files  = ["*.tf"]
+							format = "hcl"
+						}]
+						content {
+							a = 1
+						}
+					}
+					`,
+				},
+			},
+			want: want{
+				errs: []error{
+					errors.E(hcl.ErrTerramateSchema),
+				},
+			},
+		},
+		{
+			name: "generate_file with non-literal post_process - fails",
+			input: []cfgfile{
+				{
+					filename: "gen.tm",
+					body: `
+					generate_file "test.sh" {
+						post_process = global.processors
+						content      = "echo hi"
+					}
+					`,
+				},
+			},
+			want: want{
+				errs: []error{
+					errors.E(hcl.ErrTerramateSchema),
+				},
+			},
+		},
+		{
+			name: "generate_json with post_process - fails",
+			input: []cfgfile{
+				{
+					filename: "gen.tm",
+					body: `
+					generate_json "test.json" {
+						post_process = [{ command = ["cat"] }]
+						content      = {}
+					}
+					`,
+				},
+			},
+			want: want{
+				errs: []error{
+					errors.E(hcl.ErrTerramateSchema),
+				},
+			},
+		},
 		{
 			name: "generate_hcl with lets with unexpected child blocks - fails",
 			input: []cfgfile{
@@ -102,6 +102,14 @@ func PrintConfig(w io.Writer, cfg Config) error {
 		if stack.ID != "" {
 			stackBody.SetAttributeValue("id", cty.StringVal(stack.ID))
 		}
+
+		if stack.Disabled {
+			stackBody.SetAttributeValue("disabled", cty.True)
+		}
+
+		if stack.Manual {
+			stackBody.SetAttributeValue("manual", cty.True)
+		}
 	}
 
 	logger.Debug().Msg("write to output")
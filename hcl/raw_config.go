@@ -38,14 +38,20 @@ type mergeHandler func(r *RawConfig, block *ast.Block) error
 // Terramate top-level attributes and blocks.
 func NewTopLevelRawConfig() RawConfig {
 	return NewCustomRawConfig(map[string]mergeHandler{
-		"terramate":     (*RawConfig).mergeBlock,
-		"globals":       (*RawConfig).mergeLabeledBlock,
-		"stack":         (*RawConfig).addBlock,
-		"vendor":        (*RawConfig).addBlock,
-		"generate_file": (*RawConfig).addBlock,
-		"generate_hcl":  (*RawConfig).addBlock,
-		"assert":        (*RawConfig).addBlock,
-		"import":        func(r *RawConfig, b *ast.Block) error { return nil },
+		"terramate":      (*RawConfig).mergeBlock,
+		"globals":        (*RawConfig).mergeLabeledBlock,
+		"lets":           (*RawConfig).mergeLabeledBlock,
+		"stack":          (*RawConfig).addBlock,
+		"vendor":         (*RawConfig).addBlock,
+		"generate_file":  (*RawConfig).addBlock,
+		"generate_hcl":   (*RawConfig).addBlock,
+		"generate_json":  (*RawConfig).addBlock,
+		"generate_yaml":  (*RawConfig).addBlock,
+		"assert":         (*RawConfig).addBlock,
+		"input":          (*RawConfig).addBlock,
+		"output":         (*RawConfig).addBlock,
+		"globals_schema": (*RawConfig).addBlock,
+		"import":         func(r *RawConfig, b *ast.Block) error { return nil },
 	})
 }
 
@@ -7,6 +7,7 @@ package lets
 import (
 	hhcl "github.com/hashicorp/hcl/v2"
 	"github.com/rs/zerolog/log"
+	"github.com/terramate-io/terramate/config"
 	"github.com/terramate-io/terramate/errors"
 	"github.com/terramate-io/terramate/hcl/ast"
 	"github.com/terramate-io/terramate/hcl/eval"
@@ -46,14 +47,43 @@ type (
 	Map map[string]Value
 )
 
-// Load loads all the lets from the hcl blocks.
-func Load(letblock *ast.MergedBlock, ctx *eval.Context) error {
+// Load loads all the lets from the hcl blocks, together with the given
+// scoped lets, and evaluates them. The lets of the block take precedence
+// over the scoped lets with the same name.
+func Load(letblock *ast.MergedBlock, scoped Exprs, ctx *eval.Context) error {
 	exprs, err := loadExprs(letblock)
 	if err != nil {
 		return err
 	}
 
-	return exprs.Eval(ctx)
+	allExprs := make(Exprs)
+	copyexprs(allExprs, scoped)
+	copyexprs(allExprs, exprs)
+	return allExprs.Eval(ctx)
+}
+
+// LoadScoped loads the lets blocks defined in the tree dir and in all its
+// parent dirs. The lets of more specific dirs take precedence over the lets
+// with the same name defined in parent dirs.
+func LoadScoped(tree *config.Tree) (Exprs, error) {
+	var trees []*config.Tree
+	for ; tree != nil; tree = tree.Parent {
+		trees = append(trees, tree)
+	}
+
+	exprs := make(Exprs)
+	for i := len(trees) - 1; i >= 0; i-- {
+		letblock := trees[i].Node.Lets
+		if letblock == nil {
+			continue
+		}
+		dirExprs, err := loadExprs(letblock)
+		if err != nil {
+			return nil, errors.E(err, "loading lets from %s", trees[i].Dir())
+		}
+		copyexprs(exprs, dirExprs)
+	}
+	return exprs, nil
 }
 
 // Eval evaluates all lets expressions and returns an EvalReport..
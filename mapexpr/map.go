@@ -29,6 +29,12 @@ type Attributes struct {
 	Key        hhcl.Expression
 	ValueAttr  hhcl.Expression
 	ValueBlock *ast.MergedBlock
+
+	// Condition filters the iterated elements. It's nil if not defined.
+	Condition hhcl.Expression
+
+	// Group tells if the values of the same key are collected into a list.
+	Group bool
 }
 
 // NewMapExpr creates a new MapExpr instance.
@@ -68,6 +74,23 @@ func NewMapExpr(block *ast.MergedBlock) (*MapExpr, error) {
 		iterator = iteratorTraversal.RootName()
 	}
 
+	var condition hhcl.Expression
+	if cond, ok := block.Attributes["condition"]; ok {
+		condition = &ast.CloneExpression{
+			Expression: cond.Expr.(hclsyntax.Expression),
+		}
+	}
+
+	group := false
+	if groupAttr, ok := block.Attributes["group"]; ok {
+		// already validated as a boolean literal by the parser.
+		val, diags := groupAttr.Expr.Value(nil)
+		if diags.HasErrors() {
+			return nil, errors.E(diags)
+		}
+		group = val.True()
+	}
+
 	var valueExpr hhcl.Expression
 	if valueBlock == nil {
 		// already validated, if no value block then a value attr must exist.
@@ -87,6 +110,8 @@ func NewMapExpr(block *ast.MergedBlock) (*MapExpr, error) {
 			ValueAttr:  valueExpr,
 			ValueBlock: valueBlock,
 			Iterator:   iterator,
+			Condition:  condition,
+			Group:      group,
 		},
 	}, nil
 }
@@ -133,14 +158,39 @@ func (m *MapExpr) Value(ctx *hhcl.EvalContext) (cty.Value, hhcl.Diagnostics) {
 	objmap := map[string]cty.Value{}
 	evaluator := eval.NewContextFrom(ctx)
 
+	// the marks of for_each (eg.: sensitive) are kept on each element.
+	foreach, foreachMarks := foreach.Unmark()
+
 	var mapErr error
 	foreach.ForEachElement(func(key, newElement cty.Value) (stop bool) {
 		iteratorMap := map[string]cty.Value{
-			"new": newElement,
+			"new": newElement.WithMarks(foreachMarks),
 		}
 
 		evaluator.SetNamespace(m.Attrs.Iterator, iteratorMap)
 
+		if m.Attrs.Condition != nil {
+			condVal, err := evaluator.Eval(m.Attrs.Condition)
+			if err != nil {
+				mapErr = errors.E(err, "failed to evaluate the map.condition")
+				return true
+			}
+			// the condition only filters elements, so its marks are dropped.
+			condVal, _ = condVal.Unmark()
+			if condVal.Type() != cty.Bool {
+				mapErr = errors.E("map condition is not a boolean but %s",
+					condVal.Type().FriendlyName())
+				return true
+			}
+			if condVal.IsNull() {
+				mapErr = errors.E("map condition is null")
+				return true
+			}
+			if condVal.False() {
+				return false
+			}
+		}
+
 		keyVal, err := evaluator.Eval(m.Attrs.Key)
 		if err != nil {
 			mapErr = errors.E(err, "failed to evaluate the map.key")
@@ -152,6 +202,9 @@ func (m *MapExpr) Value(ctx *hhcl.EvalContext) (cty.Value, hhcl.Diagnostics) {
 			return true
 		}
 
+		// object keys can't be marked, so the key marks go to the value.
+		keyVal, keyMarks := keyVal.Unmark()
+
 		oldElement, ok := objmap[keyVal.AsString()]
 		if ok {
 			iteratorMap["old"] = oldElement
@@ -171,23 +224,23 @@ func (m *MapExpr) Value(ctx *hhcl.EvalContext) (cty.Value, hhcl.Diagnostics) {
 				}
 
 				valueMap[attr.Name] = attrVal
+			}
 
-				for _, subBlock := range m.Attrs.ValueBlock.Blocks {
-					childEvaluator := evaluator.Copy()
-					// only `map` block allowed inside `value` block.
-					subMap, err := NewMapExpr(subBlock)
-					if err != nil {
-						mapErr = errors.E(err, "evaluating nested %q map block", subBlock.Labels[0])
-						return true
-					}
-					val, diags := subMap.Value(childEvaluator.Unwrap())
-					if diags.HasErrors() {
-						mapErr = errors.E(diags, "evaluating nested %q map block", subBlock.Labels[0])
-						return true
-					}
-
-					valueMap[subBlock.Labels[0]] = val
+			for _, subBlock := range m.Attrs.ValueBlock.Blocks {
+				childEvaluator := evaluator.Copy()
+				// only `map` block allowed inside `value` block.
+				subMap, err := NewMapExpr(subBlock)
+				if err != nil {
+					mapErr = errors.E(err, "evaluating nested %q map block", subBlock.Labels[0])
+					return true
 				}
+				val, diags := subMap.Value(childEvaluator.Unwrap())
+				if diags.HasErrors() {
+					mapErr = errors.E(diags, "evaluating nested %q map block", subBlock.Labels[0])
+					return true
+				}
+
+				valueMap[subBlock.Labels[0]] = val
 			}
 
 			valVal = cty.ObjectVal(valueMap)
@@ -199,7 +252,19 @@ func (m *MapExpr) Value(ctx *hhcl.EvalContext) (cty.Value, hhcl.Diagnostics) {
 			}
 		}
 
-		objmap[keyVal.AsString()] = valVal
+		if m.Attrs.Group {
+			// the old element is the list of values grouped so far.
+			values := []cty.Value{}
+			if ok {
+				values = oldElement.AsValueSlice()
+			}
+			objmap[keyVal.AsString()] = cty.TupleVal(
+				append(values, valVal.WithMarks(keyMarks)),
+			)
+			return false
+		}
+
+		objmap[keyVal.AsString()] = valVal.WithMarks(keyMarks)
 		return false
 	})
 
@@ -233,6 +298,9 @@ func (m *MapExpr) Variables() []hhcl.Traversal {
 
 	appendVars(m.Attrs.ForEach.Variables())
 	appendVars(m.Attrs.Key.Variables())
+	if m.Attrs.Condition != nil {
+		appendVars(m.Attrs.Condition.Variables())
+	}
 	if m.Attrs.ValueAttr != nil {
 		appendVars(m.Attrs.ValueAttr.Variables())
 	}
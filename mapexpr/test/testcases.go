@@ -100,6 +100,53 @@ func SchemaErrorTestcases() []Testcase {
 				mapBlock(),
 			),
 		},
+		{
+			Name: "map with unrecognized attribute",
+			Block: mapBlock(
+				labels("var"),
+				expr("for_each", "[]"),
+				expr("key", "element.new"),
+				expr("value", "element.new"),
+				expr("filter", "true"),
+			),
+		},
+		{
+			Name: "map with non-boolean group",
+			Block: mapBlock(
+				labels("var"),
+				expr("for_each", "[]"),
+				expr("key", "element.new"),
+				expr("value", "element.new"),
+				str("group", "true"),
+			),
+		},
+		{
+			Name: "map with non-literal group",
+			Block: mapBlock(
+				labels("var"),
+				expr("for_each", "[]"),
+				expr("key", "element.new"),
+				expr("value", "element.new"),
+				expr("group", "global.group"),
+			),
+		},
+		{
+			Name: "nested map with unrecognized attribute",
+			Block: mapBlock(
+				labels("var"),
+				expr("for_each", "[]"),
+				expr("key", "element.new"),
+				value(
+					mapBlock(
+						labels("nested"),
+						expr("for_each", "[]"),
+						expr("key", "element.new"),
+						expr("value", "element.new"),
+						expr("filter", "true"),
+					),
+				),
+			),
+		},
 		{
 			Name: "nested map with conflicting map labels",
 			Block: mapBlock(
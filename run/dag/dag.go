@@ -208,6 +208,18 @@ func (d *DAG) AncestorsOf(id ID) []ID {
 	return d.dag[id]
 }
 
+// DescendantsOf returns the sorted list of descendant node ids of the given id.
+func (d *DAG) DescendantsOf(id ID) []ID {
+	descendants := idList{}
+	for nodeid, ancestors := range d.dag {
+		if idList(ancestors).contains(id) {
+			descendants = append(descendants, nodeid)
+		}
+	}
+	sort.Sort(descendants)
+	return descendants
+}
+
 // HasCycle returns true if the DAG has a cycle.
 func (d *DAG) HasCycle(id ID) bool {
 	if !d.validated {
@@ -299,6 +299,19 @@ func TestValidatedDAG(t *testing.T) {
 	}
 }
 
+func TestDAGDescendantsOf(t *testing.T) {
+	d := dag.New()
+	assert.NoError(t, d.AddNode("A", nil, []dag.ID{"C", "B"}, nil))
+	assert.NoError(t, d.AddNode("B", nil, nil, nil))
+	assert.NoError(t, d.AddNode("C", nil, nil, []dag.ID{"B"}))
+	assert.NoError(t, d.AddNode("D", nil, nil, nil))
+
+	assertOrder(t, []dag.ID{"B", "C"}, d.DescendantsOf("A"))
+	assertOrder(t, []dag.ID{"C"}, d.DescendantsOf("B"))
+	assertOrder(t, []dag.ID{}, d.DescendantsOf("C"))
+	assertOrder(t, []dag.ID{}, d.DescendantsOf("D"))
+}
+
 func assertOrder(t *testing.T, want, got []dag.ID) {
 	t.Helper()
 	assert.EqualInts(t, len(want), len(got), "length mismatch")
@@ -37,7 +37,20 @@ type EnvVars []string
 // LoadEnv will load environment variables to be exported when running any command
 // inside the given stack. The order of the env vars is guaranteed to be the same
 // and is ordered lexicographically.
+//
+// The stack inputs are available in the "input" namespace with their mocked
+// values, use [Outputs] to compute the actual inputs.
 func LoadEnv(root *config.Root, st *config.Stack) (EnvVars, error) {
+	return loadEnv(root, st, st.InputMocks(), false)
+}
+
+// LoadRedactedEnv is like [LoadEnv] but the sensitive values are redacted,
+// so the environment variables can be safely shown to the user.
+func LoadRedactedEnv(root *config.Root, st *config.Stack) (EnvVars, error) {
+	return loadEnv(root, st, st.InputMocks(), true)
+}
+
+func loadEnv(root *config.Root, st *config.Stack, inputs map[string]cty.Value, redact bool) (EnvVars, error) {
 	logger := log.With().
 		Str("action", "run.Env()").
 		Str("root", root.HostDir()).
@@ -63,6 +76,7 @@ func LoadEnv(root *config.Root, st *config.Stack) (EnvVars, error) {
 	runtime.Merge(st.RuntimeValues(root))
 	evalctx.SetNamespace("terramate", runtime)
 	evalctx.SetNamespace("global", globalsReport.Globals.AsValueMap())
+	evalctx.SetNamespace("input", inputs)
 	evalctx.SetEnv(os.Environ())
 
 	envVars := EnvVars{}
@@ -91,6 +105,10 @@ func LoadEnv(root *config.Root, st *config.Stack) (EnvVars, error) {
 				val.Type().FriendlyName(),
 			)
 		}
+		if redact && eval.IsSensitive(val) {
+			val = cty.StringVal(eval.RedactedValue)
+		}
+		val, _ = val.UnmarkDeep()
 		envVars = append(envVars, attr.Name+"="+val.AsString())
 
 		logger.Trace().Msg("env var loaded")
@@ -54,7 +54,14 @@ func Exec(
 	stackEnvs := map[project.Path]EnvVars{}
 
 	logger.Trace().Msg("loading stacks run environment variables")
+	outputs := NewOutputs(root)
 	for _, elem := range stacks {
+		if len(elem.Stack.Inputs) > 0 {
+			// the env of stacks with inputs depends on the outputs of
+			// stacks that may run before, then it's loaded just before
+			// executing the stack.
+			continue
+		}
 		env, err := LoadEnv(root, elem.Stack)
 		errs.Append(err)
 		stackEnvs[elem.Dir()] = env
@@ -87,6 +94,20 @@ func Exec(
 			Stringer("stack", stack).
 			Logger()
 
+		if len(stack.Inputs) > 0 {
+			env, err := loadStackEnvWithInputs(root, stack.Stack, outputs)
+			if err != nil {
+				after(stack.Stack, errors.E(err, ErrFailed))
+				errs.Append(errors.E(stack, err, "loading run environment"))
+				if continueOnError {
+					continue
+				}
+				cancelStacks(stacks[i+1:])
+				return errs.AsError()
+			}
+			stackEnvs[stack.Dir()] = env
+		}
+
 		cmd := exec.Command(cmd[0], cmd[1:]...)
 		cmd.Dir = stack.HostDir(root)
 		cmd.Env = append(os.Environ(), stackEnvs[stack.Dir()]...)
@@ -150,6 +171,9 @@ func Exec(
 			}
 		}
 
+		// the execution may have changed the stack outputs.
+		outputs.Invalidate(stack.Dir())
+
 		if interruptions > 0 {
 			logger.Info().Msg("interrupting execution of further stacks")
 
@@ -161,6 +185,17 @@ func Exec(
 	return errs.AsError()
 }
 
+func loadStackEnvWithInputs(root *config.Root, st *config.Stack, outputs *Outputs) (EnvVars, error) {
+	if !root.Tree().Node.HasRunEnv() {
+		return nil, nil
+	}
+	inputs, err := outputs.Inputs(st)
+	if err != nil {
+		return nil, err
+	}
+	return loadEnv(root, st, inputs, false)
+}
+
 func startCmdRunner(cmds <-chan *exec.Cmd) <-chan error {
 	errs := make(chan error)
 	go func() {
@@ -0,0 +1,203 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package run
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"strings"
+
+	hhcl "github.com/hashicorp/hcl/v2"
+	"github.com/rs/zerolog/log"
+	"github.com/terramate-io/terramate/config"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/hcl/eval"
+	"github.com/terramate-io/terramate/project"
+	"github.com/terramate-io/terramate/stdlib"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+const (
+	// ErrReadOutputs indicates that the outputs of a stack could not be read.
+	ErrReadOutputs errors.Kind = "reading stack outputs"
+
+	// ErrInput indicates that a stack input could not be computed.
+	ErrInput errors.Kind = "computing stack input"
+)
+
+// DefaultOutputsCommand is the command used to read the outputs of a stack
+// when terramate.config.run.outputs_command is not set.
+var DefaultOutputsCommand = []string{"terraform", "output", "-json"}
+
+// Outputs reads the outputs of stacks, caching them until the stack
+// is invalidated (eg.: after it was executed).
+type Outputs struct {
+	root  *config.Root
+	cmd   []string
+	cache map[project.Path]map[string]cty.Value
+}
+
+// NewOutputs creates a new outputs reader for the given root.
+func NewOutputs(root *config.Root) *Outputs {
+	cmd := DefaultOutputsCommand
+	if rootcfg := root.Tree().Node.Terramate; rootcfg != nil &&
+		rootcfg.Config != nil &&
+		rootcfg.Config.Run != nil &&
+		len(rootcfg.Config.Run.OutputsCommand) > 0 {
+		cmd = rootcfg.Config.Run.OutputsCommand
+	}
+	return &Outputs{
+		root:  root,
+		cmd:   cmd,
+		cache: map[project.Path]map[string]cty.Value{},
+	}
+}
+
+// Invalidate discards the cached outputs of the stack at dir.
+func (o *Outputs) Invalidate(dir project.Path) {
+	delete(o.cache, dir)
+}
+
+// Read the outputs of the given stack. Only the outputs declared by the
+// stack output blocks are returned.
+func (o *Outputs) Read(st *config.Stack) (map[string]cty.Value, error) {
+	if outputs, ok := o.cache[st.Dir]; ok {
+		return outputs, nil
+	}
+
+	logger := log.With().
+		Str("action", "run.Outputs.Read()").
+		Stringer("stack", st).
+		Str("cmd", strings.Join(o.cmd, " ")).
+		Logger()
+
+	logger.Trace().Msg("reading stack outputs")
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(o.cmd[0], o.cmd[1:]...)
+	cmd.Dir = st.HostDir(o.root)
+	cmd.Env = os.Environ()
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, errors.E(ErrReadOutputs, err,
+			"running %s at stack %s: %s", cmd, st.Dir, stderr.String())
+	}
+
+	var rawOutputs map[string]struct {
+		Value json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &rawOutputs); err != nil {
+		return nil, errors.E(ErrReadOutputs, err,
+			"parsing outputs of stack %s", st.Dir)
+	}
+
+	outputs := map[string]cty.Value{}
+	for _, name := range st.Outputs {
+		raw, ok := rawOutputs[name]
+		if !ok || raw.Value == nil {
+			logger.Debug().
+				Str("output", name).
+				Msg("declared output not found")
+			continue
+		}
+		typ, err := ctyjson.ImpliedType(raw.Value)
+		if err != nil {
+			return nil, errors.E(ErrReadOutputs, err,
+				"parsing output %q of stack %s", name, st.Dir)
+		}
+		val, err := ctyjson.Unmarshal(raw.Value, typ)
+		if err != nil {
+			return nil, errors.E(ErrReadOutputs, err,
+				"parsing output %q of stack %s", name, st.Dir)
+		}
+		outputs[name] = val
+	}
+
+	o.cache[st.Dir] = outputs
+	return outputs, nil
+}
+
+// Inputs computes the inputs of the given stack from the outputs of the
+// stacks it depends on. If the outputs are not available, the input mock is
+// used instead, if defined. Errors evaluating the input value are always
+// returned, even if the input has a mock.
+func (o *Outputs) Inputs(st *config.Stack) (map[string]cty.Value, error) {
+	errs := errors.L()
+	inputs := map[string]cty.Value{}
+	for _, input := range st.Inputs {
+		logger := log.With().
+			Str("action", "run.Outputs.Inputs()").
+			Stringer("stack", st).
+			Str("input", input.Name).
+			Logger()
+
+		val, err := o.evalInput(st, input)
+		if err != nil {
+			if input.Mock == cty.NilVal || !errors.IsKind(err, ErrReadOutputs) {
+				errs.Append(err)
+				continue
+			}
+			logger.Debug().Err(err).Msg("using input mock")
+			val = input.Mock
+		}
+		inputs[input.Name] = val
+	}
+	if err := errs.AsError(); err != nil {
+		return nil, err
+	}
+	return inputs, nil
+}
+
+func (o *Outputs) evalInput(st *config.Stack, input config.Input) (cty.Value, error) {
+	fromStack, err := config.LoadStack(o.root, input.FromStack)
+	if err != nil {
+		return cty.NilVal, errors.E(ErrInput, input.Range, err,
+			"loading input.from_stack %s", input.FromStack)
+	}
+	outputs, err := o.Read(fromStack)
+	if err != nil {
+		return cty.NilVal, errors.E(ErrInput, input.Range, err)
+	}
+	if name, ok := missingOutput(fromStack, outputs, input.Value); ok {
+		return cty.NilVal, errors.E(ErrInput, input.Range, errors.E(ErrReadOutputs,
+			"output %q of stack %s is not available", name, fromStack.Dir))
+	}
+	evalctx := eval.NewContext(stdlib.Functions(st.HostDir(o.root)))
+	evalctx.SetNamespace("outputs", outputs)
+	val, err := evalctx.Eval(input.Value)
+	if err != nil {
+		return cty.NilVal, errors.E(ErrInput, input.Range, err,
+			"evaluating input %q", input.Name)
+	}
+	return val, nil
+}
+
+// missingOutput returns the name of an output referenced by the expression
+// which is declared by the stack but is missing from its outputs, which
+// happens when the stack was not applied since the output was added.
+func missingOutput(st *config.Stack, outputs map[string]cty.Value, expr hhcl.Expression) (string, bool) {
+	for _, traversal := range expr.Variables() {
+		if traversal.RootName() != "outputs" || len(traversal) < 2 {
+			continue
+		}
+		attr, ok := traversal[1].(hhcl.TraverseAttr)
+		if !ok {
+			continue
+		}
+		if _, ok := outputs[attr.Name]; ok {
+			continue
+		}
+		for _, name := range st.Outputs {
+			if name == attr.Name {
+				return name, true
+			}
+		}
+	}
+	return "", false
+}
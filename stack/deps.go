@@ -0,0 +1,224 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package stack
+
+import (
+	"sort"
+
+	"github.com/rs/zerolog/log"
+	"github.com/terramate-io/terramate/config"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/project"
+	"github.com/terramate-io/terramate/run"
+	"github.com/terramate-io/terramate/run/dag"
+)
+
+// Dependency kinds.
+const (
+	// DepAfter is the dependency created by stack.after and stack.before.
+	DepAfter = "after"
+
+	// DepWants is the dependency created by stack.wants and stack.wanted_by.
+	DepWants = "wants"
+)
+
+// ErrDepsStackNotFound indicates the stack queried for dependencies does not exist.
+const ErrDepsStackNotFound errors.Kind = "stack not found"
+
+// Dependency is a stack related to another stack by ordering or selection.
+type Dependency struct {
+	// Stack is the dependency stack.
+	Stack *config.Stack
+
+	// Kinds is the sorted list of dependency kinds ([DepAfter] and/or [DepWants]).
+	Kinds []string
+
+	// Direct tells if the dependency is declared directly by the stacks
+	// involved or if it was reached transitively.
+	Direct bool
+}
+
+// Dependencies returns the stacks the stack at dir depends on, which are the
+// stacks it must run after and the stacks it wants. If transitive is true
+// then the dependencies of the dependencies are also returned.
+// The returned list is sorted by the stack directory.
+func (m *Manager) Dependencies(dir project.Path, transitive bool) ([]Dependency, error) {
+	return m.deps(dir, transitive, false)
+}
+
+// ReverseDependencies returns the stacks that depend on the stack at dir,
+// which are the stacks that must run after it and the stacks that want it.
+// If transitive is true then the reverse dependencies of the reverse
+// dependencies are also returned.
+// The returned list is sorted by the stack directory.
+func (m *Manager) ReverseDependencies(dir project.Path, transitive bool) ([]Dependency, error) {
+	return m.deps(dir, transitive, true)
+}
+
+func (m *Manager) deps(dir project.Path, transitive, reverse bool) ([]Dependency, error) {
+	logger := log.With().
+		Str("action", "manager.deps()").
+		Stringer("stack", dir).
+		Bool("transitive", transitive).
+		Bool("reverse", reverse).
+		Logger()
+
+	allstacks, err := config.LoadAllStacks(m.root.Tree())
+	if err != nil {
+		return nil, errors.E(err, "loading all stacks")
+	}
+
+	var target *config.SortableStack
+	for _, s := range allstacks {
+		if s.Dir() == dir {
+			target = s
+			break
+		}
+	}
+	if target == nil {
+		return nil, errors.E(ErrDepsStackNotFound, "no stack at %s", dir)
+	}
+
+	sort.Sort(allstacks)
+
+	logger.Trace().Msg("building order DAG")
+
+	orderDAG, err := buildDAG(m.root, allstacks,
+		"before", func(s config.Stack) []string { return s.Before },
+		"after", func(s config.Stack) []string { return s.After },
+	)
+	if err != nil {
+		return nil, errors.E(err, "building order DAG")
+	}
+
+	logger.Trace().Msg("building wants DAG")
+
+	wantsDAG, err := buildWantsDAG(m.root, allstacks)
+	if err != nil {
+		return nil, errors.E(err, "building wants DAG")
+	}
+
+	depset := map[dag.ID]*Dependency{}
+	addDep := func(s *config.Stack, kind string, direct bool) {
+		id := dag.ID(s.Dir.String())
+		dep, ok := depset[id]
+		if !ok {
+			dep = &Dependency{Stack: s}
+			depset[id] = dep
+		}
+		dep.Kinds = append(dep.Kinds, kind)
+		dep.Direct = dep.Direct || direct
+	}
+
+	directOf := func(d *dag.DAG) []dag.ID {
+		if reverse {
+			return d.DescendantsOf(dag.ID(dir.String()))
+		}
+		return d.AncestorsOf(dag.ID(dir.String()))
+	}
+
+	collect := func(d *dag.DAG, kind string) {
+		edges := d.AncestorsOf
+		if reverse {
+			edges = d.DescendantsOf
+		}
+
+		visited := dag.Visited{dag.ID(dir.String()): {}}
+		// copy the edges so appending to pending never touches the DAG.
+		pending := append([]dag.ID(nil), directOf(d)...)
+		direct := map[dag.ID]bool{}
+		for _, id := range pending {
+			direct[id] = true
+		}
+
+		for len(pending) > 0 {
+			id := pending[0]
+			pending = pending[1:]
+			if _, ok := visited[id]; ok {
+				continue
+			}
+			visited[id] = struct{}{}
+
+			node, err := d.Node(id)
+			if err != nil {
+				// stacks referenced but not loaded are ignored by BuildDAG.
+				continue
+			}
+
+			addDep(node.(*config.Stack), kind, direct[id])
+
+			if transitive {
+				pending = append(pending, edges(id)...)
+			}
+		}
+	}
+
+	collect(orderDAG, DepAfter)
+
+	if transitive && !reverse {
+		// the transitive wants of a stack are exactly the stacks selected
+		// together with it by the run stack selection.
+		wanted, err := m.AddWantedOf(config.List[*config.SortableStack]{target})
+		if err != nil {
+			return nil, err
+		}
+		direct := map[dag.ID]bool{}
+		for _, id := range directOf(wantsDAG) {
+			direct[id] = true
+		}
+		for _, s := range wanted {
+			if s.Dir() == dir {
+				continue
+			}
+			addDep(s.Stack, DepWants, direct[dag.ID(s.Dir().String())])
+		}
+	} else {
+		collect(wantsDAG, DepWants)
+	}
+
+	deps := make([]Dependency, 0, len(depset))
+	for _, dep := range depset {
+		sort.Strings(dep.Kinds)
+		deps = append(deps, *dep)
+	}
+	sort.Slice(deps, func(i, j int) bool {
+		return deps[i].Stack.Dir.String() < deps[j].Stack.Dir.String()
+	})
+	return deps, nil
+}
+
+func buildWantsDAG(root *config.Root, stacks config.List[*config.SortableStack]) (*dag.DAG, error) {
+	return buildDAG(root, stacks,
+		"wanted_by", func(s config.Stack) []string { return s.WantedBy },
+		"wants", func(s config.Stack) []string { return s.Wants },
+	)
+}
+
+func buildDAG(
+	root *config.Root,
+	stacks config.List[*config.SortableStack],
+	descendantsName string,
+	getDescendants func(config.Stack) []string,
+	ancestorsName string,
+	getAncestors func(config.Stack) []string,
+) (*dag.DAG, error) {
+	d := dag.New()
+	visited := dag.Visited{}
+	for _, elem := range stacks {
+		err := run.BuildDAG(
+			d,
+			root,
+			elem.Stack,
+			descendantsName,
+			getDescendants,
+			ancestorsName,
+			getAncestors,
+			visited,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
@@ -0,0 +1,148 @@
+// Copyright 2023 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package stack_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/project"
+	"github.com/terramate-io/terramate/stack"
+	"github.com/terramate-io/terramate/test/sandbox"
+)
+
+type depResult struct {
+	Dir    string
+	Kinds  []string
+	Direct bool
+}
+
+func TestStackDependencies(t *testing.T) {
+	t.Parallel()
+
+	type testcase struct {
+		name       string
+		stack      string
+		transitive bool
+		reverse    bool
+		want       []depResult
+	}
+
+	layout := []string{
+		`s:network`,
+		`s:database:after=["/network"]`,
+		`s:app:after=["/database"];wants=["/monitoring"]`,
+		`s:monitoring:wants=["/logging"]`,
+		`s:logging`,
+		`s:frontend:before=["/app"]`,
+		`s:unrelated`,
+	}
+
+	for _, tc := range []testcase{
+		{
+			name:  "direct dependencies",
+			stack: "/app",
+			want: []depResult{
+				{Dir: "/database", Kinds: []string{"after"}, Direct: true},
+				{Dir: "/frontend", Kinds: []string{"after"}, Direct: true},
+				{Dir: "/monitoring", Kinds: []string{"wants"}, Direct: true},
+			},
+		},
+		{
+			name:       "transitive dependencies",
+			stack:      "/app",
+			transitive: true,
+			want: []depResult{
+				{Dir: "/database", Kinds: []string{"after"}, Direct: true},
+				{Dir: "/frontend", Kinds: []string{"after"}, Direct: true},
+				{Dir: "/logging", Kinds: []string{"wants"}},
+				{Dir: "/monitoring", Kinds: []string{"wants"}, Direct: true},
+				{Dir: "/network", Kinds: []string{"after"}},
+			},
+		},
+		{
+			name:    "direct reverse dependencies",
+			stack:   "/network",
+			reverse: true,
+			want: []depResult{
+				{Dir: "/database", Kinds: []string{"after"}, Direct: true},
+			},
+		},
+		{
+			name:       "transitive reverse dependencies",
+			stack:      "/network",
+			reverse:    true,
+			transitive: true,
+			want: []depResult{
+				{Dir: "/app", Kinds: []string{"after"}},
+				{Dir: "/database", Kinds: []string{"after"}, Direct: true},
+			},
+		},
+		{
+			name:    "reverse dependencies of wanted stack",
+			stack:   "/monitoring",
+			reverse: true,
+			want: []depResult{
+				{Dir: "/app", Kinds: []string{"wants"}, Direct: true},
+			},
+		},
+		{
+			name:       "transitive reverse dependencies of wanted stack",
+			stack:      "/logging",
+			reverse:    true,
+			transitive: true,
+			want: []depResult{
+				{Dir: "/app", Kinds: []string{"wants"}},
+				{Dir: "/monitoring", Kinds: []string{"wants"}, Direct: true},
+			},
+		},
+		{
+			name:       "no dependencies",
+			stack:      "/unrelated",
+			transitive: true,
+			want:       []depResult{},
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := sandbox.NoGit(t)
+			s.BuildTree(layout)
+
+			mgr := stack.NewManager(s.Config(), "")
+			getDeps := mgr.Dependencies
+			if tc.reverse {
+				getDeps = mgr.ReverseDependencies
+			}
+			deps, err := getDeps(project.NewPath(tc.stack), tc.transitive)
+			assert.NoError(t, err)
+
+			got := []depResult{}
+			for _, dep := range deps {
+				got = append(got, depResult{
+					Dir:    dep.Stack.Dir.String(),
+					Kinds:  dep.Kinds,
+					Direct: dep.Direct,
+				})
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Fatalf("-(want) +(got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestStackDependenciesStackNotFound(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.NoGit(t)
+	s.BuildTree([]string{`d:not-a-stack`})
+
+	mgr := stack.NewManager(s.Config(), "")
+	_, err := mgr.Dependencies(project.NewPath("/not-a-stack"), false)
+	assert.IsError(t, err, errors.E(stack.ErrDepsStackNotFound))
+}
@@ -25,6 +25,7 @@ func NewEvalCtx(root *config.Root, stack *config.Stack, globals *eval.Object) *E
 	}
 	evalwrapper.SetMetadata(stack)
 	evalwrapper.SetGlobals(globals)
+	evalwrapper.SetInputs(stack)
 	return evalwrapper
 }
 
@@ -39,3 +40,10 @@ func (e *EvalCtx) SetMetadata(st *config.Stack) {
 	runtime.Merge(st.RuntimeValues(e.root))
 	e.SetNamespace("terramate", runtime)
 }
+
+// SetInputs sets the mocked values of the stack inputs on the "input"
+// namespace. The actual inputs depend on the outputs of other stacks, which
+// are only available at run time, so generation always uses the mocks.
+func (e *EvalCtx) SetInputs(st *config.Stack) {
+	e.SetNamespace("input", st.InputMocks())
+}
@@ -17,7 +17,6 @@ import (
 	"github.com/terramate-io/terramate/errors"
 	"github.com/terramate-io/terramate/git"
 	"github.com/terramate-io/terramate/project"
-	"github.com/terramate-io/terramate/run"
 	"github.com/terramate-io/terramate/run/dag"
 	"github.com/terramate-io/terramate/stack/trigger"
 	"github.com/terramate-io/terramate/tf"
@@ -187,6 +186,7 @@ func (m *Manager) ListChanged() (*Report, error) {
 				return nil, errors.E(errListChanged, err)
 			}
 
+			s.IsChanged = true
 			stackSet[s.Dir] = Entry{
 				Stack:  s,
 				Reason: "stack has been triggered by: " + projpath.String(),
@@ -229,6 +229,7 @@ func (m *Manager) ListChanged() (*Report, error) {
 			return nil, errors.E(errListChanged, err)
 		}
 
+		s.IsChanged = true
 		stackSet[s.Dir] = Entry{
 			Stack:  s,
 			Reason: "stack has unmerged changes",
@@ -272,6 +273,40 @@ rangeStacks:
 			continue rangeStacks
 		}
 
+		if changed, ok := m.hasChangedTemplateFiles(stack, changedFiles); ok {
+			logger.Debug().
+				Stringer("stack", stack).
+				Stringer("templatefile", changed).
+				Msg("changed.")
+
+			stack.IsChanged = true
+			stackSet[stack.Dir] = Entry{
+				Stack: stack,
+				Reason: fmt.Sprintf(
+					"stack changed because template file %q changed",
+					changed,
+				),
+			}
+			continue rangeStacks
+		}
+
+		if changed, ok := m.hasChangedGlobalsDataFiles(stack, changedFiles); ok {
+			logger.Debug().
+				Stringer("stack", stack).
+				Stringer("datafile", changed).
+				Msg("changed.")
+
+			stack.IsChanged = true
+			stackSet[stack.Dir] = Entry{
+				Stack: stack,
+				Reason: fmt.Sprintf(
+					"stack changed because globals data file %q changed",
+					changed,
+				),
+			}
+			continue rangeStacks
+		}
+
 		logger.Debug().
 			Stringer("stack", stack).
 			Msg("Apply function to stack.")
@@ -342,6 +377,12 @@ rangeStacks:
 
 	changedStacks := make([]Entry, 0, len(stackSet))
 	for _, stack := range stackSet {
+		if stack.Stack.Disabled {
+			logger.Debug().
+				Stringer("stack", stack.Stack).
+				Msg("ignoring changes of disabled stack")
+			continue
+		}
 		changedStacks = append(changedStacks, stack)
 	}
 
@@ -361,33 +402,18 @@ func (m *Manager) AddWantedOf(scopeStacks config.List[*config.SortableStack]) (c
 		Str("action", "manager.AddWantedOf").
 		Logger()
 
-	wantsDag := dag.New()
 	allstacks, err := config.LoadAllStacks(m.root.Tree())
 	if err != nil {
 		return nil, errors.E(err, "loading all stacks")
 	}
 
-	visited := dag.Visited{}
 	sort.Sort(allstacks)
-	for _, elem := range allstacks {
-		logger.Trace().
-			Stringer("stack", elem.Dir()).
-			Msg("Building dag")
-
-		err := run.BuildDAG(
-			wantsDag,
-			m.root,
-			elem.Stack,
-			"wanted_by",
-			func(s config.Stack) []string { return s.WantedBy },
-			"wants",
-			func(s config.Stack) []string { return s.Wants },
-			visited,
-		)
 
-		if err != nil {
-			return nil, errors.E(err, "building wants DAG")
-		}
+	logger.Trace().Msg("Building wants DAG.")
+
+	wantsDag, err := buildWantsDAG(m.root, allstacks)
+	if err != nil {
+		return nil, errors.E(err, "building wants DAG")
 	}
 
 	logger.Trace().Msg("Validating DAG.")
@@ -407,7 +433,7 @@ func (m *Manager) AddWantedOf(scopeStacks config.List[*config.SortableStack]) (c
 	}
 
 	var selectedStacks config.List[*config.SortableStack]
-	visited = dag.Visited{}
+	visited := dag.Visited{}
 	addStack := func(s *config.Stack) {
 		if _, ok := visited[dag.ID(s.Dir.String())]; ok {
 			return
@@ -640,6 +666,68 @@ func hasChangedWatchedFiles(stack *config.Stack, changedFiles []string) (project
 	return project.Path{}, false
 }
 
+// hasChangedTemplateFiles checks if any of the template files used by the
+// generate blocks of the stack changed. The generate blocks of a stack are the
+// ones defined from the stack directory up to the project root.
+func (m *Manager) hasChangedTemplateFiles(stack *config.Stack, changedFiles []string) (project.Path, bool) {
+	var templateFiles []string
+	cfgdir := stack.Dir
+	for {
+		cfg, ok := m.root.Lookup(cfgdir)
+		if ok {
+			for _, block := range cfg.Node.Generate.Files {
+				if block.TemplateFile != "" && block.Context == "stack" {
+					templateFiles = append(templateFiles, block.TemplateFile)
+				}
+			}
+			for _, block := range cfg.Node.Generate.HCLs {
+				if block.TemplateFile != "" && block.Context == "stack" {
+					templateFiles = append(templateFiles, block.TemplateFile)
+				}
+			}
+		}
+		parent := cfgdir.Dir()
+		if parent == cfgdir {
+			break
+		}
+		cfgdir = parent
+	}
+
+	for _, templateFile := range templateFiles {
+		for _, file := range changedFiles {
+			if file == templateFile[1:] { // project paths
+				return project.NewPath(templateFile), true
+			}
+		}
+	}
+	return project.Path{}, false
+}
+
+// hasChangedGlobalsDataFiles checks if any of the files loaded by the
+// globals.data blocks of the stack changed. The globals of a stack are the
+// ones defined from the stack directory up to the project root.
+func (m *Manager) hasChangedGlobalsDataFiles(stack *config.Stack, changedFiles []string) (project.Path, bool) {
+	cfgdir := stack.Dir
+	for {
+		cfg, ok := m.root.Lookup(cfgdir)
+		if ok {
+			for _, data := range cfg.Node.GlobalsData {
+				for _, file := range changedFiles {
+					if file == data.File[1:] { // project paths
+						return project.NewPath(data.File), true
+					}
+				}
+			}
+		}
+		parent := cfgdir.Dir()
+		if parent == cfgdir {
+			break
+		}
+		cfgdir = parent
+	}
+	return project.Path{}, false
+}
+
 func checkRepoIsClean(g *git.Git) (RepoChecks, error) {
 	logger := log.With().
 		Str("action", "checkRepoIsClean()").
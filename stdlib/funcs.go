@@ -8,6 +8,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sync"
 
 	resyntax "regexp/syntax"
 
@@ -25,7 +26,12 @@ import (
 	"github.com/zclconf/go-cty/cty/function"
 )
 
-var regexCache map[string]*regexp.Regexp
+var (
+	// regexCache is safe for concurrent use since functions may be called
+	// concurrently, eg.: when generating code for multiple stacks.
+	regexCacheMu sync.RWMutex
+	regexCache   map[string]*regexp.Regexp
+)
 
 func init() {
 	regexCache = map[string]*regexp.Regexp{}
@@ -98,7 +104,9 @@ func Regex() function.Function {
 				return cty.DynamicVal, nil
 			}
 
+			regexCacheMu.RLock()
 			re, ok := regexCache[args[0].AsString()]
+			regexCacheMu.RUnlock()
 			if !ok {
 				panic("should be in the cache")
 			}
@@ -122,7 +130,9 @@ func Regex() function.Function {
 // Returns an error if parsing fails or if the pattern uses a mixture of
 // named and unnamed capture groups, which is not permitted.
 func regexPatternResultType(pattern string) (cty.Type, error) {
+	regexCacheMu.RLock()
 	re, ok := regexCache[pattern]
+	regexCacheMu.RUnlock()
 	if !ok {
 		var rawErr error
 		re, rawErr = regexp.Compile(pattern)
@@ -135,7 +145,9 @@ func regexPatternResultType(pattern string) (cty.Type, error) {
 			return cty.NilType, fmt.Errorf("error parsing pattern: %s", err)
 		}
 
+		regexCacheMu.Lock()
 		regexCache[pattern] = re
+		regexCacheMu.Unlock()
 	}
 
 	allNames := re.SubexpNames()[1:]
@@ -17,6 +17,7 @@ import (
 	"github.com/terramate-io/terramate/hcl/ast"
 	"github.com/terramate-io/terramate/hcl/info"
 	"github.com/terramate-io/terramate/project"
+	"github.com/zclconf/go-cty/cty"
 )
 
 // ParseTerramateConfig parses the Terramate configuration found
@@ -67,6 +68,42 @@ func AssertTerramateConfig(t *testing.T, got, want hcl.Config) {
 	AssertDiff(t, got.Vendor, want.Vendor, "terramate vendor")
 	assertGenHCLBlocks(t, got.Generate.HCLs, want.Generate.HCLs)
 	assertGenFileBlocks(t, got.Generate.Files, want.Generate.Files)
+	assertGenStructBlocks(t, got.Generate.JSONs, want.Generate.JSONs)
+	assertGenStructBlocks(t, got.Generate.YAMLs, want.Generate.YAMLs)
+	assertInputBlocks(t, got.Inputs, want.Inputs)
+	assertOutputBlocks(t, got.Outputs, want.Outputs)
+}
+
+func assertInputBlocks(t *testing.T, got, want []hcl.Input) {
+	t.Helper()
+
+	assert.EqualInts(t, len(want), len(got), "input blocks length mismatch")
+
+	for i, w := range want {
+		g := got[i]
+		assert.EqualStrings(t, w.Name, g.Name, "input[%d] name mismatch", i)
+		assert.EqualStrings(t, w.FromStack, g.FromStack, "input[%d] from_stack mismatch", i)
+		assert.EqualStrings(t, exprAsStr(t, w.Value), exprAsStr(t, g.Value),
+			"input[%d] value mismatch", i)
+		if w.Mock == cty.NilVal || g.Mock == cty.NilVal {
+			assert.IsTrue(t, w.Mock == g.Mock, "input[%d] mock mismatch", i)
+		} else {
+			assert.IsTrue(t, w.Mock.RawEquals(g.Mock),
+				"input[%d] mock mismatch: want %s got %s", i, w.Mock.GoString(), g.Mock.GoString())
+		}
+	}
+}
+
+func assertOutputBlocks(t *testing.T, got, want []hcl.Output) {
+	t.Helper()
+
+	assert.EqualInts(t, len(want), len(got), "output blocks length mismatch")
+
+	for i, w := range want {
+		g := got[i]
+		assert.EqualStrings(t, w.Name, g.Name, "output[%d] name mismatch", i)
+		assert.EqualStrings(t, w.Description, g.Description, "output[%d] description mismatch", i)
+	}
 }
 
 // AssertDiff will compare the two values and fail if they are not the same
@@ -239,6 +276,7 @@ func assertTerramateConfigBlock(t *testing.T, got, want *hcl.RootConfig) {
 	}
 
 	assertTerramateRunBlock(t, got.Run, want.Run)
+	AssertDiff(t, got.Generate, want.Generate, "terramate config generate")
 }
 
 func assertGenHCLBlocks(t *testing.T, got, want []hcl.GenHCLBlock) {
@@ -252,6 +290,7 @@ func assertGenHCLBlocks(t *testing.T, got, want []hcl.GenHCLBlock) {
 		AssertEqualRanges(t, gotBlock.Range, wantBlock.Range, "genhcl range differs")
 		assert.EqualStrings(t, wantBlock.Label, gotBlock.Label, "genhcl label differs")
 		assertAssertsBlock(t, gotBlock.Asserts, wantBlock.Asserts, "genhcl asserts")
+		AssertDiff(t, gotBlock.PostProcess, wantBlock.PostProcess, "genhcl post_process")
 	}
 }
 
@@ -266,6 +305,22 @@ func assertGenFileBlocks(t *testing.T, got, want []hcl.GenFileBlock) {
 		AssertEqualRanges(t, gotBlock.Range, wantBlock.Range, "genfile range differs")
 		assert.EqualStrings(t, wantBlock.Label, gotBlock.Label, "genfile label differs")
 		assertAssertsBlock(t, gotBlock.Asserts, wantBlock.Asserts, "genfile asserts")
+		AssertDiff(t, gotBlock.PostProcess, wantBlock.PostProcess, "genfile post_process")
+	}
+}
+
+func assertGenStructBlocks(t *testing.T, got, want []hcl.GenStructBlock) {
+	t.Helper()
+
+	assert.EqualInts(t, len(want), len(got), "generate_json/generate_yaml blocks differ in len")
+
+	for i, gotBlock := range got {
+		wantBlock := want[i]
+		AssertEqualRanges(t, gotBlock.Range, wantBlock.Range, "block range differs")
+		assert.EqualStrings(t, wantBlock.Type, gotBlock.Type, "block type differs")
+		assert.EqualStrings(t, wantBlock.Label, gotBlock.Label, "block label differs")
+		assertAssertsBlock(t, gotBlock.Asserts, wantBlock.Asserts, "block asserts")
+		AssertDiff(t, gotBlock.PostProcess, wantBlock.PostProcess, "block post_process")
 	}
 }
 
@@ -330,6 +385,8 @@ func assertStackBlock(t *testing.T, got, want *hcl.Stack) {
 		return
 	}
 
+	assert.IsTrue(t, got.Disabled == want.Disabled, "stack disabled mismatch")
+	assert.IsTrue(t, got.Manual == want.Manual, "stack manual mismatch")
 	assert.EqualInts(t, len(got.After), len(want.After), "After length mismatch")
 
 	for i, w := range want.After {
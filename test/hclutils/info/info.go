@@ -49,6 +49,13 @@ func FixRangesOnConfig(dir string, cfg hcl.Config) {
 
 		fixRangeOnAsserts(dir, cfg.Generate.HCLs[i].Asserts)
 	}
+	for _, blocks := range [][]hcl.GenStructBlock{cfg.Generate.JSONs, cfg.Generate.YAMLs} {
+		for i := range blocks {
+			blocks[i].Range = FixRange(dir, blocks[i].Range)
+
+			fixRangeOnAsserts(dir, blocks[i].Asserts)
+		}
+	}
 }
 
 // FixRange fix the given range.
@@ -195,7 +195,7 @@ func (s S) GenerateWith(root *config.Root, vendorDir project.Path) generate.Repo
 	t := s.t
 	t.Helper()
 
-	report := generate.Do(root, vendorDir, nil)
+	report := generate.Do(root, vendorDir, nil, generate.Options{})
 	for _, failure := range report.Failures {
 		t.Errorf("Generate unexpected failure: %v", failure)
 	}
@@ -626,10 +626,16 @@ func buildTree(t testing.TB, root *config.Root, layout []string) {
 				cfg.Stack.WantedBy = parseListSpec(t, name, value)
 			case "watch":
 				cfg.Stack.Watch = parseListSpec(t, name, value)
+			case "name":
+				cfg.Stack.Name = value
 			case "description":
 				cfg.Stack.Description = value
 			case "tags":
 				cfg.Stack.Tags = parseListSpec(t, name, value)
+			case "disabled":
+				cfg.Stack.Disabled = value == "true"
+			case "manual":
+				cfg.Stack.Manual = value == "true"
 			default:
 				t.Fatalf("attribute " + parts[0] + " not supported.")
 			}